@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type apiKeyHandler struct {
+	svc apikey.APIKeyService
+}
+
+func NewAPIKey(mux *gin.Engine, s apikey.APIKeyService, a *Auth) {
+	kh := &apiKeyHandler{
+		svc: s,
+	}
+
+	keysMux := mux.Group("/apikeys", a.Authenticate, a.Authorize(PermAPIKeysManage))
+	keysMux.GET("/", kh.GetAll)
+	keysMux.GET("/:id", kh.GetById)
+	keysMux.POST("/", kh.Create)
+	keysMux.DELETE("/:id", kh.Revoke)
+	keysMux.POST("/:id/rotate", kh.Rotate)
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// rotateRequest sets the seconds the old key keeps working, a day by
+// default.
+type rotateRequest struct {
+	GracePeriod *int `json:"grace_period" binding:"omitempty,gte=0,lte=604800"`
+}
+
+// apiKeyResponse hides the key hash.
+type apiKeyResponse struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(k domain.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		Id:         k.Id,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func (kh *apiKeyHandler) GetAll(ctx *gin.Context) {
+	ks, err := kh.svc.All()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	res := make([]apiKeyResponse, 0, len(ks))
+	for _, k := range ks {
+		res = append(res, newAPIKeyResponse(k))
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(res))
+}
+
+func (kh *apiKeyHandler) GetById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	k, err := kh.svc.GetById(id)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			apikey.ErrNotFound.Error(),
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(newAPIKeyResponse(k)))
+}
+
+func (kh *apiKeyHandler) Create(ctx *gin.Context) {
+	var r apiKeyRequest
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	k, plain, err := kh.svc.Create(r.Name, r.Scopes, r.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrInvalidData):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				apikey.ErrInvalidData.Error(),
+			))
+		case errors.Is(err, apikey.ErrInvalidScope):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				err.Error(),
+			))
+		default:
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				apikey.ErrCreation.Error(),
+			))
+		}
+		return
+	}
+
+	securityEvent(ctx, "apikey_created", fmt.Sprintf("id=%d scopes=[%s]", k.Id, strings.Join(k.Scopes, " ")))
+
+	// the plain key is only shown once
+	res := newAPIKeyResponse(k)
+	res.Key = plain
+
+	ctx.JSON(http.StatusCreated, web.Response(res))
+}
+
+func (kh *apiKeyHandler) Revoke(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = kh.svc.Revoke(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				apikey.ErrNotFound.Error(),
+			))
+		case errors.Is(err, storage.ErrWriteFile):
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				"unable to revoke api key",
+			))
+		}
+		return
+	}
+
+	securityEvent(ctx, "apikey_revoked", fmt.Sprintf("id=%d", id))
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// Rotate replaces the key with a new one, returned in plain text once. The
+// old key expires after the grace period.
+func (kh *apiKeyHandler) Rotate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r rotateRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	grace := apikey.DefaultGracePeriod
+	if r.GracePeriod != nil {
+		grace = time.Duration(*r.GracePeriod) * time.Second
+	}
+
+	k, plain, err := kh.svc.Rotate(id, grace)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				apikey.ErrNotFound.Error(),
+			))
+		case errors.Is(err, apikey.ErrInvalidData):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				apikey.ErrInvalidData.Error(),
+			))
+		case errors.Is(err, apikey.ErrRevoked), errors.Is(err, apikey.ErrExpired):
+			ctx.JSON(http.StatusConflict, web.ErrResponse(
+				http.StatusConflict,
+				"conflict",
+				err.Error(),
+			))
+		default:
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				apikey.ErrCreation.Error(),
+			))
+		}
+		return
+	}
+
+	securityEvent(ctx, "apikey_rotated", fmt.Sprintf("id=%d new_id=%d grace=%s", id, k.Id, grace))
+
+	res := newAPIKeyResponse(k)
+	res.Key = plain
+
+	ctx.JSON(http.StatusCreated, web.Response(res))
+}
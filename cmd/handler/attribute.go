@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/attribute"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type attributeHandler struct {
+	svc attribute.AttributeService
+}
+
+func NewAttribute(mux *gin.Engine, s attribute.AttributeService, a *Auth) {
+	ah := &attributeHandler{
+		svc: s,
+	}
+
+	// definitions are read with the products they describe
+	attributesMux := mux.Group("/attributes", a.Authenticate)
+	attributesMux.GET("/", a.Authorize(PermProductsRead), ah.GetAll)
+	attributesMux.GET("/:name", a.Authorize(PermProductsRead), ah.Get)
+
+	attributesMux.POST("/", a.Authorize(PermAttributesWrite), ah.Create)
+	attributesMux.DELETE("/:name", a.Authorize(PermAttributesWrite), ah.Delete)
+}
+
+func (ah *attributeHandler) GetAll(ctx *gin.Context) {
+	as, err := ah.svc.All()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(as))
+}
+
+func (ah *attributeHandler) Get(ctx *gin.Context) {
+	a, err := ah.svc.Get(ctx.Param("name"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			attribute.ErrNotFound.Error(),
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(a))
+}
+
+func (ah *attributeHandler) Create(ctx *gin.Context) {
+	var a domain.Attribute
+
+	err := ctx.ShouldBindJSON(&a)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			attribute.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	err = ah.svc.Create(a)
+	if err != nil {
+		switch {
+		case errors.Is(err, attribute.ErrInvalidData):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				attribute.ErrInvalidData.Error(),
+			))
+		case errors.Is(err, attribute.ErrDuplicatedName):
+			ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
+				http.StatusUnprocessableEntity,
+				"unprocessable entity",
+				attribute.ErrDuplicatedName.Error(),
+			))
+		case errors.Is(err, storage.ErrWriteFile):
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				"unable to create attribute",
+			))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+func (ah *attributeHandler) Delete(ctx *gin.Context) {
+	err := ah.svc.Delete(ctx.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, attribute.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				attribute.ErrNotFound.Error(),
+			))
+		case errors.Is(err, storage.ErrWriteFile):
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				"unable to delete attribute",
+			))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
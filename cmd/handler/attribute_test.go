@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/attribute"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+// stubAttributes holds no attribute definitions.
+type stubAttributes struct {
+	attribute.AttributeService
+}
+
+func (stubAttributes) All() ([]domain.Attribute, error) {
+	return []domain.Attribute{}, nil
+}
+
+func TestAttributePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	NewAttribute(mux, stubAttributes{}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK},
+		{"viewer read", http.MethodGet, bearer("viewer"), http.StatusOK},
+		{"invalid token", http.MethodGet, "Bearer invalid", http.StatusUnauthorized},
+		{"viewer write", http.MethodPost, bearer("viewer"), http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/attributes/", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", test.token)
+			}
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code)
+		})
+	}
+}
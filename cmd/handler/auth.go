@@ -0,0 +1,408 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/lockout"
+	"gituhb.com/juajosserand/goweb/pkg/rbac"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const (
+	SubjectKey = "subject"
+	ClaimsKey  = "claims"
+	RolesKey   = "roles"
+	APIKeyKey  = "api_key"
+
+	// PermissionsKey holds permissions granted directly, e.g. by api key
+	// scopes, on top of the ones granted by roles.
+	PermissionsKey = "permissions"
+
+	// RoleAnonymous is given to requests without credentials.
+	RoleAnonymous = "anonymous"
+)
+
+const (
+	PermProductsRead    = "products:read"
+	PermProductsCreate  = "products:create"
+	PermProductsUpdate  = "products:update"
+	PermProductsPrice   = "products:price"
+	PermProductsDelete  = "products:delete"
+	PermAttributesWrite = "attributes:write"
+	PermSuppliersWrite  = "suppliers:write"
+	PermOrdersWrite     = "orders:write"
+	PermAPIKeysManage   = "apikeys:manage"
+	PermWebhooksManage  = "webhooks:manage"
+)
+
+// scopePermissions maps api key scopes to the permissions they grant.
+var scopePermissions = map[string][]string{
+	apikey.ScopeProductsRead: {
+		PermProductsRead,
+	},
+	apikey.ScopeProductsWrite: {
+		PermProductsRead,
+		PermProductsCreate,
+		PermProductsUpdate,
+		PermProductsPrice,
+		PermProductsDelete,
+		PermAttributesWrite,
+		PermSuppliersWrite,
+	},
+	apikey.ScopeOrdersWrite: {
+		PermOrdersWrite,
+	},
+}
+
+// APIKeyAuthenticator checks plain text api keys.
+type APIKeyAuthenticator interface {
+	Authenticate(string) (domain.APIKey, error)
+	HasActive() (bool, error)
+}
+
+var (
+	ErrMissingToken     = errors.New("missing bearer token")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNoCredentials    = errors.New("no credentials configured")
+	ErrLockedOut        = errors.New("too many failed authentication attempts")
+)
+
+const (
+	defaultLockoutAttempts = 5
+	defaultLockoutWindow   = 15 * time.Minute
+	defaultLockoutDuration = 15 * time.Minute
+)
+
+// DefaultPolicy is used when no policy file is configured.
+func DefaultPolicy() *rbac.Policy {
+	return &rbac.Policy{
+		Roles: map[string][]string{
+			RoleAnonymous: {PermProductsRead},
+			"viewer":      {PermProductsRead},
+			"editor": {
+				PermProductsRead,
+				PermProductsCreate,
+				PermProductsUpdate,
+				PermAttributesWrite,
+				PermSuppliersWrite,
+				PermOrdersWrite,
+			},
+			"admin": {rbac.Wildcard},
+		},
+	}
+}
+
+// Limiter decides whether an authenticated request may go on, aborting it
+// otherwise. Grpc calls are counted against the http route they serve, by
+// method and path, and get the error of the exceeded limit.
+type Limiter interface {
+	Limit(*gin.Context) bool
+	LimitCall(ip string, key *domain.APIKey, method string, path string) error
+}
+
+type Auth struct {
+	verifier *jwt.Verifier
+	policy   *rbac.Policy
+	keys     APIKeyAuthenticator
+	lockout  *lockout.Tracker
+	limiter  Limiter
+	idem     *Idempotency
+}
+
+type AuthOption func(*Auth)
+
+// Lockout locks a client ip out for duration after the given failed
+// attempts within window.
+func Lockout(attempts int, window time.Duration, duration time.Duration) AuthOption {
+	return func(a *Auth) {
+		a.lockout = lockout.New(attempts, window, duration)
+	}
+}
+
+// RateLimiter limits requests once the client is known.
+func RateLimiter(l Limiter) AuthOption {
+	return func(a *Auth) {
+		a.limiter = l
+	}
+}
+
+// Idempotent replays retried POST requests once the client is known.
+func Idempotent(i *Idempotency) AuthOption {
+	return func(a *Auth) {
+		a.idem = i
+	}
+}
+
+// NewAuth builds the auth middleware. Api keys are only accepted when an
+// authenticator is given.
+func NewAuth(v *jwt.Verifier, p *rbac.Policy, k APIKeyAuthenticator, ops ...AuthOption) *Auth {
+	a := &Auth{
+		verifier: v,
+		policy:   p,
+		keys:     k,
+		lockout:  lockout.New(defaultLockoutAttempts, defaultLockoutWindow, defaultLockoutDuration),
+	}
+
+	for _, op := range ops {
+		op(a)
+	}
+
+	return a
+}
+
+// Check returns ErrNoCredentials when neither a token key nor an active api
+// key is configured, so that nothing but anonymous requests could succeed.
+func (a *Auth) Check() error {
+	if a.verifier.HasKeys() {
+		return nil
+	}
+
+	if a.keys != nil {
+		ok, err := a.keys.HasActive()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+	}
+
+	return ErrNoCredentials
+}
+
+// Authenticate verifies the api key or bearer token, when present, and
+// exposes the subject, claims, roles and permissions on the context.
+// Requests without credentials get the anonymous role.
+func (a *Auth) Authenticate(ctx *gin.Context) {
+	if remaining, locked := a.lockout.Locked(ctx.ClientIP()); locked {
+		securityEvent(ctx, "locked_out", ErrLockedOut.Error())
+		tooManyRequests(ctx, remaining, ErrLockedOut)
+		return
+	}
+
+	if key := ctx.GetHeader("X-API-Key"); key != "" {
+		a.authenticateKey(ctx, key)
+		return
+	}
+
+	if ctx.GetHeader("Authorization") == "" {
+		ctx.Set(RolesKey, []string{RoleAnonymous})
+		a.next(ctx)
+		return
+	}
+
+	token, ok := bearerToken(ctx)
+	if !ok {
+		a.fail(ctx, ErrMissingToken)
+		return
+	}
+
+	claims, err := a.verifier.Verify(token)
+	if err != nil {
+		a.fail(ctx, err)
+		return
+	}
+
+	a.lockout.Reset(ctx.ClientIP())
+
+	roles := claims.Strings("roles")
+	if role, ok := claims.Extra["role"].(string); ok {
+		roles = append(roles, role)
+	}
+
+	ctx.Set(SubjectKey, claims.Subject)
+	ctx.Set(ClaimsKey, claims)
+	ctx.Set(RolesKey, roles)
+
+	a.next(ctx)
+}
+
+func (a *Auth) authenticateKey(ctx *gin.Context, key string) {
+	if a.keys == nil {
+		a.fail(ctx, apikey.ErrInvalidKey)
+		return
+	}
+
+	k, err := a.keys.Authenticate(key)
+	if err != nil {
+		a.fail(ctx, err)
+		return
+	}
+
+	a.lockout.Reset(ctx.ClientIP())
+
+	var permissions []string
+	for _, scope := range k.Scopes {
+		permissions = append(permissions, scopePermissions[scope]...)
+	}
+
+	ctx.Set(SubjectKey, fmt.Sprintf("apikey:%d", k.Id))
+	ctx.Set(APIKeyKey, k)
+	ctx.Set(RolesKey, []string{})
+	ctx.Set(PermissionsKey, permissions)
+
+	a.next(ctx)
+}
+
+// next runs the limiter and the idempotency check, if any, before the rest
+// of the chain.
+func (a *Auth) next(ctx *gin.Context) {
+	if a.limiter != nil && !a.limiter.Limit(ctx) {
+		return
+	}
+
+	if a.idem != nil {
+		a.idem.Handle(ctx)
+		return
+	}
+
+	ctx.Next()
+}
+
+// fail records a failed attempt of the client ip and rejects the request.
+func (a *Auth) fail(ctx *gin.Context, err error) {
+	securityEvent(ctx, "auth_failed", err.Error())
+
+	if a.lockout.Fail(ctx.ClientIP()) {
+		securityEvent(ctx, "lockout", ErrLockedOut.Error())
+	}
+
+	unauthorized(ctx, err)
+}
+
+// Identified rejects anonymous requests, for routes that need credentials
+// even when the anonymous role holds their permission, and then runs the
+// request validation deferred by Validation.
+func (a *Auth) Identified(ctx *gin.Context) {
+	if Subject(ctx) == "" {
+		unauthorized(ctx, ErrMissingToken)
+		return
+	}
+
+	if !checked(ctx) {
+		return
+	}
+
+	ctx.Next()
+}
+
+// Authorize rejects requests whose roles do not grant the permission, and
+// then runs the request validation deferred by Validation.
+func (a *Auth) Authorize(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !a.Allowed(ctx, permission) {
+			a.Deny(ctx, permission)
+			return
+		}
+
+		if !checked(ctx) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Allowed reports whether the request roles or permissions grant the
+// permission.
+func (a *Auth) Allowed(ctx *gin.Context, permission string) bool {
+	for _, p := range ctx.GetStringSlice(PermissionsKey) {
+		if p == permission {
+			return true
+		}
+	}
+
+	return a.policy.Allowed(Roles(ctx), permission)
+}
+
+// Deny aborts the request, with 401 for anonymous requests and 403 with the
+// denial reason otherwise.
+func (a *Auth) Deny(ctx *gin.Context, permission string) {
+	if _, ok := ctx.Get(SubjectKey); !ok {
+		unauthorized(ctx, ErrMissingToken)
+		return
+	}
+
+	reason := fmt.Sprintf("%s: %s not granted to roles [%s]",
+		ErrPermissionDenied.Error(),
+		permission,
+		strings.Join(Roles(ctx), " "),
+	)
+
+	if k, ok := ctx.Value(APIKeyKey).(domain.APIKey); ok {
+		reason = fmt.Sprintf("%s: %s not granted to api key scopes [%s]",
+			ErrPermissionDenied.Error(),
+			permission,
+			strings.Join(k.Scopes, " "),
+		)
+	}
+
+	if granting := a.policy.Granting(permission); len(granting) > 0 {
+		reason += fmt.Sprintf(", requires one of [%s]", strings.Join(granting, " "))
+	}
+
+	securityEvent(ctx, "permission_denied", reason)
+
+	ctx.AbortWithStatusJSON(http.StatusForbidden, web.ErrResponse(
+		http.StatusForbidden,
+		"forbidden",
+		reason,
+	))
+}
+
+// Subject returns the authenticated subject, if any.
+func Subject(ctx *gin.Context) string {
+	return ctx.GetString(SubjectKey)
+}
+
+// Roles returns the roles of the request.
+func Roles(ctx *gin.Context) []string {
+	return ctx.GetStringSlice(RolesKey)
+}
+
+// securityEvent logs an authentication or authorization event. Credentials
+// are never logged.
+func securityEvent(ctx *gin.Context, event string, detail string) {
+	logSecurityEvent(event, ctx.ClientIP(), Subject(ctx), ctx.Request.Method, ctx.Request.URL.Path, detail)
+}
+
+func logSecurityEvent(event, ip, subject, method, path, detail string) {
+	log.Printf("security: event=%s ip=%s subject=%q method=%s path=%s detail=%q",
+		event,
+		ip,
+		subject,
+		method,
+		path,
+		detail,
+	)
+}
+
+func bearerToken(ctx *gin.Context) (string, bool) {
+	h := ctx.GetHeader("Authorization")
+
+	scheme, token, found := strings.Cut(h, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(ctx *gin.Context, err error) {
+	ctx.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, err.Error()))
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, web.ErrResponse(
+		http.StatusUnauthorized,
+		"Unauthorized",
+		err.Error(),
+	))
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const BulkPath = "/products/bulk"
+
+const (
+	BulkAtomic     = "atomic"
+	BulkBestEffort = "best_effort"
+)
+
+// bulkRequest is a batch of product writes. Atomic batches, the default,
+// apply none of the operations when any fails, best effort ones apply the
+// others.
+type bulkRequest struct {
+	Mode       string          `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []bulkOperation `json:"operations" binding:"required,min=1,max=5000,dive"`
+}
+
+// bulkOperation creates a product, or updates or deletes the product with
+// the id. Product is the body of the single create or update route.
+type bulkOperation struct {
+	Op      string          `json:"op" binding:"required,oneof=create update delete"`
+	Id      int             `json:"id" binding:"omitempty,gt=0"`
+	Product json.RawMessage `json:"product"`
+}
+
+// operation validates the item like its single route does.
+func (o bulkOperation) operation() (producti.Operation, error) {
+	op := producti.Operation{Type: o.Op}
+	op.Product.Id = o.Id
+
+	if o.Op != producti.OpCreate && o.Id == 0 {
+		return op, producti.ErrInvalidId
+	}
+
+	if o.Op == producti.OpDelete {
+		return op, nil
+	}
+
+	var r request
+
+	err := json.Unmarshal(o.Product, &r)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(&r)
+	}
+	if err != nil {
+		return op, producti.ErrInvalidData
+	}
+
+	op.Product.Name = r.Name
+	op.Product.Quantity = r.Quantity
+	op.Product.CodeValue = r.CodeValue
+	op.Product.IsPublished = r.IsPublished
+	op.Product.Expiration = r.Expiration
+	op.Product.Price = r.Price
+	op.Product.Attributes = r.Attributes
+
+	return op, nil
+}
+
+type bulkResponse struct {
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Results   []bulkResult `json:"results"`
+}
+
+// bulkResult is the outcome of an operation, in the order of the request,
+// with the status its single route would reply.
+type bulkResult struct {
+	Op     string `json:"op"`
+	Id     int    `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// bulkPermissions are the permissions of the single route of each
+// operation.
+var bulkPermissions = map[string]string{
+	producti.OpCreate: PermProductsCreate,
+	producti.OpUpdate: PermProductsUpdate,
+	producti.OpDelete: PermProductsDelete,
+}
+
+// Bulk applies a batch of product writes with a single storage write. Each
+// operation needs the permission of its single route, and price changes
+// products:price.
+func (ph *product) Bulk(ctx *gin.Context) {
+	var r bulkRequest
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	mode := producti.Atomic
+	if r.Mode == BulkBestEffort {
+		mode = producti.BestEffort
+	}
+
+	results := make([]producti.Result, len(r.Operations))
+	ops := make([]producti.Operation, 0, len(r.Operations))
+	indexes := make([]int, 0, len(r.Operations))
+
+	for i, o := range r.Operations {
+		if permission := bulkPermissions[o.Op]; !ph.auth.Allowed(ctx, permission) {
+			ph.auth.Deny(ctx, permission)
+			return
+		}
+
+		op, err := o.operation()
+		if err != nil {
+			results[i] = producti.Result{Type: o.Op, Id: o.Id, Err: err}
+			continue
+		}
+
+		if op.Type == producti.OpUpdate && !ph.priceChangeAllowed(ctx, op.Product.Id, op.Product.Price) {
+			ph.auth.Deny(ctx, PermProductsPrice)
+			return
+		}
+
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	// atomic batches with invalid items are not sent to the service
+	if mode == producti.Atomic && len(ops) < len(r.Operations) {
+		for _, i := range indexes {
+			results[i] = producti.Result{Type: r.Operations[i].Op, Id: r.Operations[i].Id, Err: producti.ErrBulkAborted}
+		}
+		ops = nil
+	}
+
+	if len(ops) > 0 {
+		applied, err := ph.svc.Bulk(ops, mode)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				"internal server error",
+			))
+			return
+		}
+
+		for j, res := range applied {
+			results[indexes[j]] = res
+		}
+	}
+
+	res := bulkResponse{
+		Results: make([]bulkResult, len(results)),
+	}
+
+	for i, result := range results {
+		op := r.Operations[i].Op
+
+		res.Results[i] = bulkResult{
+			Op:     op,
+			Id:     result.Id,
+			Status: bulkStatus(op, result.Err),
+		}
+
+		if result.Err != nil {
+			res.Results[i].Error = result.Err.Error()
+			res.Failed++
+		} else {
+			res.Succeeded++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(res))
+}
+
+// bulkStatus returns the status the single route of the operation replies
+// with the error.
+func bulkStatus(op string, err error) int {
+	switch {
+	case err == nil && op == producti.OpCreate:
+		return http.StatusCreated
+	case err == nil:
+		return http.StatusNoContent
+	case errors.Is(err, producti.ErrBulkAborted):
+		return http.StatusFailedDependency
+	case errors.Is(err, producti.ErrInvalidData),
+		errors.Is(err, producti.ErrInvalidAttributes),
+		errors.Is(err, producti.ErrInvalidId),
+		errors.Is(err, producti.ErrUnknownOperation):
+		return http.StatusBadRequest
+	case errors.Is(err, producti.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, producti.ErrDuplicatedCodeValue):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
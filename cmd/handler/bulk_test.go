@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// bulkMux serves the product routes over a copy of two products.
+func bulkMux(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Product{
+		{Id: 1, Name: "First", Quantity: 5, CodeValue: "FIRST", Expiration: "20/01/2030", Price: 10},
+		{Id: 2, Name: "Second", Quantity: 5, CodeValue: "SECOND", Expiration: "20/01/2030", Price: 20},
+	}))
+	t.Setenv("PRODUCTS_FILENAME", path)
+
+	repo, err := producti.NewRepository()
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewProduct(mux, producti.NewService(repo), NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	return mux, path
+}
+
+func bulk(t *testing.T, mux *gin.Engine, role string, body map[string]any) (int, bulkResponse) {
+	t.Helper()
+
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, BulkPath, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer(role))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	var env struct {
+		Data bulkResponse `json:"data"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &env)
+
+	return res.Code, env.Data
+}
+
+func storedProducts(t *testing.T, path string) []domain.Product {
+	t.Helper()
+
+	var ps []domain.Product
+	require.NoError(t, storage.ReadFile(path, &ps))
+
+	return ps
+}
+
+func TestBulk(t *testing.T) {
+	product := func(code string) map[string]any {
+		return map[string]any{
+			"name":       "Product " + code,
+			"quantity":   3,
+			"code_value": code,
+			"expiration": "20/01/2030",
+			"price":      10,
+		}
+	}
+
+	operations := []map[string]any{
+		{"op": "create", "product": product("THIRD")},
+		{"op": "update", "id": 1, "product": product("FIRST")},
+		{"op": "create", "product": product("SECOND")},
+		{"op": "delete", "id": 9},
+	}
+
+	t.Run("atomic", func(t *testing.T) {
+		mux, path := bulkMux(t)
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		status, res := bulk(t, mux, "admin", map[string]any{"operations": operations})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 0, res.Succeeded)
+		assert.Equal(t, 4, res.Failed)
+
+		statuses := []int{}
+		for _, r := range res.Results {
+			statuses = append(statuses, r.Status)
+		}
+		assert.Equal(t, []int{
+			http.StatusFailedDependency,
+			http.StatusFailedDependency,
+			http.StatusUnprocessableEntity,
+			http.StatusNotFound,
+		}, statuses)
+
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("atomic with invalid items", func(t *testing.T) {
+		mux, _ := bulkMux(t)
+
+		status, res := bulk(t, mux, "admin", map[string]any{"operations": []map[string]any{
+			{"op": "create", "product": product("THIRD")},
+			{"op": "create", "product": map[string]any{"name": "No code"}},
+		}})
+		assert.Equal(t, http.StatusOK, status)
+		if assert.Len(t, res.Results, 2) {
+			assert.Equal(t, http.StatusFailedDependency, res.Results[0].Status)
+			assert.Equal(t, http.StatusBadRequest, res.Results[1].Status)
+			assert.Equal(t, producti.ErrInvalidData.Error(), res.Results[1].Error)
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		mux, path := bulkMux(t)
+
+		status, res := bulk(t, mux, "admin", map[string]any{"mode": BulkBestEffort, "operations": operations})
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, bulkResult{Op: "create", Id: 3, Status: http.StatusCreated}, res.Results[0])
+		assert.Equal(t, bulkResult{Op: "update", Id: 1, Status: http.StatusNoContent}, res.Results[1])
+		assert.Equal(t, producti.ErrDuplicatedCodeValue.Error(), res.Results[2].Error)
+
+		ps := storedProducts(t, path)
+		if assert.Len(t, ps, 3) {
+			assert.Equal(t, 3, ps[0].Quantity)
+			assert.Equal(t, "THIRD", ps[2].CodeValue)
+		}
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		mux, path := bulkMux(t)
+
+		// editors may not delete
+		status, _ := bulk(t, mux, "editor", map[string]any{"operations": []map[string]any{
+			{"op": "create", "product": product("THIRD")},
+			{"op": "delete", "id": 2},
+		}})
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = bulk(t, mux, "viewer", map[string]any{"operations": []map[string]any{
+			{"op": "create", "product": product("THIRD")},
+		}})
+		assert.Equal(t, http.StatusForbidden, status)
+
+		assert.Len(t, storedProducts(t, path), 2)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		mux, _ := bulkMux(t)
+
+		status, _ := bulk(t, mux, "admin", map[string]any{"operations": []map[string]any{}})
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = bulk(t, mux, "admin", map[string]any{"operations": []map[string]any{{"op": "merge"}}})
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/pkg/events"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const EventsPath = "/products/events"
+
+const (
+	defaultHeartbeat = 15 * time.Second
+
+	// eventReset tells clients resuming after dropped events to reload the
+	// products.
+	eventReset = "reset"
+)
+
+type eventsHandler struct {
+	bus       *events.Bus
+	duration  time.Duration
+	heartbeat time.Duration
+}
+
+type EventsOption func(*eventsHandler)
+
+// StreamDuration ends each stream after d, clients reconnect with the
+// Last-Event-ID header. Streams last until the client goes away by default.
+func StreamDuration(d time.Duration) EventsOption {
+	return func(eh *eventsHandler) {
+		if d > 0 {
+			eh.duration = d
+		}
+	}
+}
+
+// Heartbeat sends a comment every d while no event is published, so that
+// proxies keep idle streams open.
+func Heartbeat(d time.Duration) EventsOption {
+	return func(eh *eventsHandler) {
+		if d > 0 {
+			eh.heartbeat = d
+		}
+	}
+}
+
+// NewEvents streams the changes published on bus as server-sent events,
+// with the permission to read products.
+func NewEvents(mux *gin.Engine, bus *events.Bus, a *Auth, ops ...EventsOption) {
+	eh := &eventsHandler{
+		bus:       bus,
+		heartbeat: defaultHeartbeat,
+	}
+
+	for _, op := range ops {
+		op(eh)
+	}
+
+	mux.GET(EventsPath, a.Authenticate, a.Authorize(PermProductsRead), eh.Stream)
+}
+
+type connKey struct{}
+
+// StreamConn keeps the connection of the requests in their context, so that
+// Streams can clear its write deadline. It is the ConnContext of the http
+// server.
+func StreamConn(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// Streams serves the requests with h, clearing the write timeout of the
+// http server for the event streams, which would otherwise be cut by it.
+// The server must use StreamConn. The deadline is set again by the server
+// for the next request of the connection.
+func Streams(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, ok := r.Context().Value(connKey{}).(net.Conn); ok && r.URL.Path == EventsPath {
+			err := c.SetWriteDeadline(time.Time{})
+			if err != nil {
+				log.Println(fmt.Errorf("[handler.Streams] error: %w", err))
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Stream writes the events after the Last-Event-ID header, then the new
+// ones until the client goes away or the stream duration ends.
+func (eh *eventsHandler) Stream(ctx *gin.Context) {
+	var lastId uint64
+
+	if v := ctx.GetHeader("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				"invalid last event id",
+			))
+			return
+		}
+		lastId = id
+	}
+
+	sub, err := eh.bus.Subscribe(lastId)
+	defer sub.Close()
+
+	h := ctx.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", time.Second.Milliseconds())
+
+	if errors.Is(err, events.ErrGap) {
+		fmt.Fprintf(ctx.Writer, "event: %s\ndata: {}\n\n", eventReset)
+	}
+
+	for _, e := range sub.Missed {
+		writeEvent(ctx, e)
+	}
+	ctx.Writer.Flush()
+
+	// a nil channel never ends the stream
+	var end <-chan time.Time
+	if eh.duration > 0 {
+		timer := time.NewTimer(eh.duration)
+		defer timer.Stop()
+		end = timer.C
+	}
+
+	heartbeat := time.NewTicker(eh.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-end:
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(ctx.Writer, ": heartbeat\n\n")
+		case e, ok := <-sub.C:
+			// dropped for lagging, the client resumes from its last id
+			if !ok {
+				return
+			}
+			writeEvent(ctx, e)
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func writeEvent(ctx *gin.Context, e events.Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, data)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/pkg/events"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+func eventsMux(bus *events.Bus) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewEvents(mux, bus, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil), StreamDuration(50*time.Millisecond))
+
+	return mux
+}
+
+func TestEvents(t *testing.T) {
+	bus := events.NewBus(2)
+	bus.Publish("product.created", map[string]int{"id": 1})
+	bus.Publish("product.updated", map[string]int{"id": 1})
+	bus.Publish("product.updated", map[string]int{"id": 1})
+	bus.Publish("product.deleted", map[string]int{"id": 1})
+
+	mux := eventsMux(bus)
+
+	tests := []struct {
+		name        string
+		lastEventId string
+		status      int
+		body        string
+	}{
+		{
+			name:   "new events only",
+			status: http.StatusOK,
+			body:   "retry: 1000\n\n",
+		},
+		{
+			name:        "resumes",
+			lastEventId: "3",
+			status:      http.StatusOK,
+			body:        "retry: 1000\n\nid: 4\nevent: product.deleted\ndata: {\"id\":1}\n\n",
+		},
+		{
+			name:        "dropped events",
+			lastEventId: "1",
+			status:      http.StatusOK,
+			body: "retry: 1000\n\nevent: reset\ndata: {}\n\n" +
+				"id: 3\nevent: product.updated\ndata: {\"id\":1}\n\n" +
+				"id: 4\nevent: product.deleted\ndata: {\"id\":1}\n\n",
+		},
+		{
+			name:        "invalid last event id",
+			lastEventId: "x",
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, EventsPath, nil)
+			if test.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", test.lastEventId)
+			}
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code, res.Body.String())
+			if test.status != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "text/event-stream", res.Header().Get("Content-Type"))
+			assert.Equal(t, test.body, res.Body.String())
+		})
+	}
+}
+
+func TestEventsOutliveWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	NewEvents(mux, events.NewBus(2), NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil), Heartbeat(20*time.Millisecond))
+
+	srv := httptest.NewUnstartedServer(Streams(mux))
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Config.ConnContext = StreamConn
+	srv.Start()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+EventsPath, nil)
+	require.NoError(t, err)
+
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	// heartbeats keep coming well past the write timeout
+	lines := bufio.NewScanner(res.Body)
+	deadline := time.Now().Add(300 * time.Millisecond)
+
+	for time.Now().Before(deadline) {
+		require.True(t, lines.Scan(), "stream cut: %v", lines.Err())
+	}
+}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// Content types of the product exports.
+const (
+	MIMECSV    = "text/csv"
+	MIMENDJSON = "application/x-ndjson"
+	MIMEXML    = binding.MIMEXML
+)
+
+// exportFlushRows is the number of rows written between flushes.
+const exportFlushRows = 100
+
+// exportTypes are negotiated besides json, which stays the default, with
+// the extension of their file.
+var exportTypes = map[string]string{
+	MIMECSV:    "csv",
+	MIMENDJSON: "ndjson",
+	MIMEXML:    "xml",
+}
+
+// exportEncoder writes the products of an export one at a time. Close
+// ends the export.
+type exportEncoder interface {
+	Encode(domain.Product) error
+	Flush() error
+	Close() error
+}
+
+// export writes the products in the format asked for in the Accept header,
+// and reports false when json is negotiated. Exports have the shape of the
+// catalog files in every version, and are streamed row by row.
+func (ph *product) export(ctx *gin.Context, ps []domain.Product) bool {
+	ctx.Header("Vary", "Accept")
+
+	contentType := exportType(ctx.GetHeader("Accept"))
+
+	ext, ok := exportTypes[contentType]
+	if !ok {
+		return false
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, ext))
+	ctx.Status(http.StatusOK)
+
+	err := writeExport(ctx, newExportEncoder(contentType, ctx.Writer), ps)
+	if err != nil {
+		// the status is already sent, the client gets a truncated file
+		log.Println(fmt.Errorf("[handler.export] error: %w", err))
+	}
+
+	return true
+}
+
+// exportType returns the export type of the Accept header, or "" for json.
+// An export is only negotiated when it is named, and no other media type is
+// preferred to it, e.g. by its q-value or by coming first. Browsers, which
+// accept application/xml after html, get json then.
+func exportType(accept string) string {
+	var (
+		best string
+		q    = 0.0
+	)
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		weight := 1.0
+		if v, ok := params["q"]; ok {
+			weight, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		if weight > q {
+			best, q = mediaType, weight
+		}
+	}
+
+	if _, ok := exportTypes[best]; !ok {
+		return ""
+	}
+
+	return best
+}
+
+func writeExport(ctx *gin.Context, enc exportEncoder, ps []domain.Product) error {
+	for i, p := range ps {
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+
+		if (i+1)%exportFlushRows == 0 {
+			if err := enc.Flush(); err != nil {
+				return err
+			}
+			ctx.Writer.Flush()
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	ctx.Writer.Flush()
+
+	return nil
+}
+
+func newExportEncoder(contentType string, w io.Writer) exportEncoder {
+	switch contentType {
+	case MIMECSV:
+		return &csvExport{enc: storage.NewCSVEncoder(w)}
+	case MIMEXML:
+		return &xmlExport{w: w, enc: xml.NewEncoder(w)}
+	default:
+		return &ndjsonExport{NDJSONEncoder: storage.NewNDJSONEncoder(w)}
+	}
+}
+
+// csvExport starts with the header row, so that exports can be imported
+// back.
+type csvExport struct {
+	enc    *storage.CSVEncoder
+	header bool
+}
+
+func (e *csvExport) Encode(p domain.Product) error {
+	if !e.header {
+		e.header = true
+		if err := e.enc.WriteHeader(); err != nil {
+			return err
+		}
+	}
+
+	return e.enc.Encode(p)
+}
+
+func (e *csvExport) Flush() error {
+	return e.enc.Flush()
+}
+
+func (e *csvExport) Close() error {
+	if !e.header {
+		e.header = true
+		if err := e.enc.WriteHeader(); err != nil {
+			return err
+		}
+	}
+
+	return e.enc.Flush()
+}
+
+// ndjsonExport writes a product per line, like the ndjson files.
+type ndjsonExport struct {
+	*storage.NDJSONEncoder
+}
+
+func (e *ndjsonExport) Close() error {
+	return e.Flush()
+}
+
+// xmlExport writes a products document, opened with the first product.
+type xmlExport struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+var xmlProducts = xml.StartElement{Name: xml.Name{Local: "products"}}
+
+func (e *xmlExport) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	if _, err := io.WriteString(e.w, xml.Header); err != nil {
+		return err
+	}
+
+	return e.enc.EncodeToken(xmlProducts)
+}
+
+func (e *xmlExport) Encode(p domain.Product) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	return e.enc.Encode(newXMLProduct(p))
+}
+
+func (e *xmlExport) Flush() error {
+	return e.enc.Flush()
+}
+
+func (e *xmlExport) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+
+	if err := e.enc.EncodeToken(xmlProducts.End()); err != nil {
+		return err
+	}
+
+	return e.enc.Flush()
+}
+
+// xmlProduct is the xml element of a product. Attributes are listed by
+// name, with the json of values other than strings.
+type xmlProduct struct {
+	XMLName     xml.Name       `xml:"product"`
+	Id          int            `xml:"id"`
+	Name        string         `xml:"name"`
+	Quantity    int            `xml:"quantity"`
+	CodeValue   string         `xml:"code_value"`
+	IsPublished bool           `xml:"is_published"`
+	Expiration  string         `xml:"expiration"`
+	Price       float64        `xml:"price"`
+	Variants    []xmlVariant   `xml:"variants>variant,omitempty"`
+	Attributes  []xmlAttribute `xml:"attributes>attribute,omitempty"`
+	Media       []xmlMedia     `xml:"media>item,omitempty"`
+
+	ReorderPoint    int `xml:"reorder_point,omitempty"`
+	ReorderQuantity int `xml:"reorder_quantity,omitempty"`
+}
+
+type xmlVariant struct {
+	CodeValue string  `xml:"code_value"`
+	Price     float64 `xml:"price"`
+	Quantity  int     `xml:"quantity"`
+	Size      string  `xml:"size,omitempty"`
+	Color     string  `xml:"color,omitempty"`
+	Pack      int     `xml:"pack,omitempty"`
+}
+
+type xmlAttribute struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlMedia struct {
+	Id           string `xml:"id"`
+	Filename     string `xml:"filename"`
+	ContentType  string `xml:"content_type"`
+	Size         int64  `xml:"size"`
+	URL          string `xml:"url"`
+	ThumbnailURL string `xml:"thumbnail_url,omitempty"`
+}
+
+func newXMLProduct(p domain.Product) xmlProduct {
+	x := xmlProduct{
+		Id:              p.Id,
+		Name:            p.Name,
+		Quantity:        p.Quantity,
+		CodeValue:       p.CodeValue,
+		IsPublished:     p.IsPublished,
+		Expiration:      p.Expiration,
+		Price:           p.Price,
+		ReorderPoint:    p.ReorderPoint,
+		ReorderQuantity: p.ReorderQuantity,
+	}
+
+	for _, v := range p.Variants {
+		x.Variants = append(x.Variants, xmlVariant{
+			CodeValue: v.CodeValue,
+			Price:     v.Price,
+			Quantity:  v.Quantity,
+			Size:      v.Attributes.Size,
+			Color:     v.Attributes.Color,
+			Pack:      v.Attributes.Pack,
+		})
+	}
+
+	for name, value := range p.Attributes {
+		s, ok := value.(string)
+		if !ok {
+			b, _ := json.Marshal(value)
+			s = string(b)
+		}
+		x.Attributes = append(x.Attributes, xmlAttribute{Name: name, Value: s})
+	}
+	// maps have no order
+	sort.Slice(x.Attributes, func(i, j int) bool {
+		return x.Attributes[i].Name < x.Attributes[j].Name
+	})
+
+	for _, m := range p.Media {
+		x.Media = append(x.Media, xmlMedia(m))
+	}
+
+	return x
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+func TestExport(t *testing.T) {
+	mux, path := bulkMux(t)
+	want := storedProducts(t, path)
+
+	get := func(path string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		res := get("/products/", "text/csv")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, MIMECSV, res.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="products.csv"`, res.Header().Get("Content-Disposition"))
+		assert.Equal(t, "Accept", res.Header().Get("Vary"))
+		assert.True(t, strings.HasPrefix(res.Body.String(), strings.Join(storage.CSVHeader, ",")+"\n"))
+
+		// exports import back
+		records, err := storage.DecodeCSV(res.Body)
+		require.NoError(t, err)
+		require.Len(t, records, len(want))
+		for i, r := range records {
+			assert.NoError(t, r.Err)
+			assert.Equal(t, want[i], r.Product)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		res := get("/products/", "application/x-ndjson")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, MIMENDJSON, res.Header().Get("Content-Type"))
+
+		var got []domain.Product
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			var p domain.Product
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &p))
+			got = append(got, p)
+		}
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("preferred", func(t *testing.T) {
+		res := get("/products/", "application/json;q=0.5, text/html;q=0.8, text/csv")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, MIMECSV, res.Header().Get("Content-Type"))
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		res := get("/v2/products/", "application/xml, application/json;q=0.5")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, MIMEXML, res.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(res.Body.String(), xml.Header+"<products>"))
+
+		var doc struct {
+			Products []xmlProduct `xml:"product"`
+		}
+		require.NoError(t, xml.Unmarshal(res.Body.Bytes(), &doc))
+		require.Len(t, doc.Products, len(want))
+		assert.Equal(t, want[1].CodeValue, doc.Products[1].CodeValue)
+		assert.Equal(t, want[1].Price, doc.Products[1].Price)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		for _, accept := range []string{
+			"",
+			"*/*",
+			"application/json",
+			"image/png",
+			"text/*",
+			// browsers accept xml, after html
+			"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			"application/json, text/csv",
+			"text/csv;q=0.5, application/json",
+			"text/csv;q=0",
+		} {
+			res := get("/products/", accept)
+			assert.Equal(t, http.StatusOK, res.Code, accept)
+			assert.Contains(t, res.Header().Get("Content-Type"), "application/json", accept)
+		}
+	})
+}
+
+func TestXMLProduct(t *testing.T) {
+	p := domain.Product{
+		Id:         1,
+		Name:       "x",
+		Attributes: map[string]any{"weight": 1.5, "color": "red"},
+		Variants:   []domain.Variant{{CodeValue: "X1", Attributes: domain.VariantAttributes{Size: "M"}}},
+	}
+
+	b, err := xml.Marshal(newXMLProduct(p))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `<attributes><attribute name="color">red</attribute><attribute name="weight">1.5</attribute></attributes>`)
+	assert.Contains(t, string(b), `<variants><variant><code_value>X1</code_value><price>0</price><quantity>0</quantity><size>M</size></variant></variants>`)
+}
@@ -0,0 +1,491 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/complexity"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const GraphQLPath = "/graphql"
+
+const (
+	defaultGraphQLComplexity = 1000
+	defaultGraphQLDepth      = 8
+	defaultGraphQLPageSize   = 20
+	maxGraphQLPageSize       = 100
+
+	// graphQLListSize is the size assumed for variants and media when
+	// pricing queries.
+	graphQLListSize = 10
+)
+
+type graphQLHandler struct {
+	svc    producti.ProductService
+	schema graphql.Schema
+	rules  complexity.Rules
+}
+
+type GraphQLOption func(*graphQLHandler)
+
+// MaxComplexity rejects queries costing more before they run. Every field
+// costs one, and lists multiply the cost of their fields by their page size.
+// Zero disables the limit.
+func MaxComplexity(n int) GraphQLOption {
+	return func(gh *graphQLHandler) {
+		gh.rules.MaxComplexity = n
+	}
+}
+
+// MaxDepth rejects queries nesting more fields before they run. Zero
+// disables the limit.
+func MaxDepth(n int) GraphQLOption {
+	return func(gh *graphQLHandler) {
+		gh.rules.MaxDepth = n
+	}
+}
+
+// NewGraphQL serves queries over the products and price quotes, with the
+// permission to read products.
+func NewGraphQL(mux *gin.Engine, s producti.ProductService, a *Auth, ops ...GraphQLOption) {
+	gh := &graphQLHandler{
+		svc: s,
+		rules: complexity.Rules{
+			ListSizes: map[string]int{
+				"products": defaultGraphQLPageSize,
+				"variants": graphQLListSize,
+				"media":    graphQLListSize,
+			},
+			SizeArg:       "first",
+			MaxComplexity: defaultGraphQLComplexity,
+			MaxDepth:      defaultGraphQLDepth,
+		},
+	}
+
+	for _, op := range ops {
+		op(gh)
+	}
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: gh.queryType(),
+	})
+	if err != nil {
+		panic(fmt.Sprintf("handler: graphql schema: %s", err.Error()))
+	}
+	gh.schema = schema
+
+	mux.GET(GraphQLPath, a.Authenticate, a.Authorize(PermProductsRead), gh.Query)
+	mux.POST(GraphQLPath, a.Authenticate, a.Authorize(PermProductsRead), gh.Query)
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Query runs the query of the json body, or of the query string for GET
+// requests. Errors of the query are reported in the result, as graphql
+// clients expect.
+func (gh *graphQLHandler) Query(ctx *gin.Context) {
+	var r graphQLRequest
+
+	if ctx.Request.Method == http.MethodGet {
+		r.Query = ctx.Query("query")
+		r.OperationName = ctx.Query("operationName")
+
+		if v := ctx.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &r.Variables); err != nil {
+				r.Query = ""
+			}
+		}
+	} else if err := ctx.ShouldBindJSON(&r); err != nil {
+		r.Query = ""
+	}
+
+	if r.Query == "" {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			"invalid graphql request",
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gh.execute(ctx, r))
+}
+
+func (gh *graphQLHandler) execute(ctx *gin.Context, r graphQLRequest) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: r.Query})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	vr := graphql.ValidateDocument(&gh.schema, doc, nil)
+	if !vr.IsValid {
+		return &graphql.Result{Errors: vr.Errors}
+	}
+
+	if _, err := gh.rules.Check(doc, r.OperationName, r.Variables); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        gh.schema,
+		AST:           doc,
+		OperationName: r.OperationName,
+		Args:          r.Variables,
+		Context:       ctx.Request.Context(),
+	})
+}
+
+// quote is the price of a list of products and variants.
+type quote struct {
+	products []domain.Product
+	variants []domain.Variant
+	total    float64
+}
+
+func (gh *graphQLHandler) queryType() *graphql.Object {
+	moneyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Money",
+		Description: "An amount of a currency, as a decimal string.",
+		Fields: graphql.Fields{
+			"amount":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"currency": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	variantType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Variant",
+		Fields: graphql.Fields{
+			"codeValue": variantField(graphql.String, func(v domain.Variant) any { return v.CodeValue }),
+			"price":     variantField(moneyType, func(v domain.Variant) any { return newMoney(v.Price) }),
+			"quantity":  variantField(graphql.Int, func(v domain.Variant) any { return v.Quantity }),
+			"size":      variantField(graphql.String, func(v domain.Variant) any { return v.Attributes.Size }),
+			"color":     variantField(graphql.String, func(v domain.Variant) any { return v.Attributes.Color }),
+			"pack":      variantField(graphql.Int, func(v domain.Variant) any { return v.Attributes.Pack }),
+		},
+	})
+
+	mediaType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Media",
+		Fields: graphql.Fields{
+			"id":           mediaField(graphql.String, func(m domain.Media) any { return m.Id }),
+			"filename":     mediaField(graphql.String, func(m domain.Media) any { return m.Filename }),
+			"contentType":  mediaField(graphql.String, func(m domain.Media) any { return m.ContentType }),
+			"size":         mediaField(graphql.Int, func(m domain.Media) any { return m.Size }),
+			"url":          mediaField(graphql.String, func(m domain.Media) any { return m.URL }),
+			"thumbnailUrl": mediaField(graphql.String, func(m domain.Media) any { return m.ThumbnailURL }),
+		},
+	})
+
+	productType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Product",
+		Fields: graphql.Fields{
+			"id":          productField(graphql.Int, func(p domain.Product) any { return p.Id }),
+			"name":        productField(graphql.String, func(p domain.Product) any { return p.Name }),
+			"quantity":    productField(graphql.Int, func(p domain.Product) any { return p.Quantity }),
+			"codeValue":   productField(graphql.String, func(p domain.Product) any { return p.CodeValue }),
+			"isPublished": productField(graphql.Boolean, func(p domain.Product) any { return p.IsPublished }),
+			"expiration":  productField(graphql.String, func(p domain.Product) any { return string(newDate(p.Expiration)) }),
+			"price":       productField(moneyType, func(p domain.Product) any { return newMoney(p.Price) }),
+			"variants":    productField(graphql.NewList(graphql.NewNonNull(variantType)), func(p domain.Product) any { return orEmpty(p.Variants) }),
+			"media":       productField(graphql.NewList(graphql.NewNonNull(mediaType)), func(p domain.Product) any { return orEmpty(p.Media) }),
+			"attributes": &graphql.Field{
+				Type: jsonScalar,
+				Resolve: func(rp graphql.ResolveParams) (any, error) {
+					return rp.Source.(domain.Product).Attributes, nil
+				},
+			},
+			"reorderPoint":    productField(graphql.Int, func(p domain.Product) any { return p.ReorderPoint }),
+			"reorderQuantity": productField(graphql.Int, func(p domain.Product) any { return p.ReorderQuantity }),
+		},
+	})
+
+	quoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Quote",
+		Description: "The consumer price of a list of products and variants.",
+		Fields: graphql.Fields{
+			"products": quoteField(graphql.NewList(graphql.NewNonNull(productType)), func(q quote) any { return orEmpty(q.products) }),
+			"variants": quoteField(graphql.NewList(graphql.NewNonNull(variantType)), func(q quote) any { return orEmpty(q.variants) }),
+			"total":    quoteField(moneyType, func(q quote) any { return newMoney(q.total) }),
+		},
+	})
+
+	filterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ProductFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"priceGt": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Float,
+				Description: "Minimum price, exclusive.",
+			},
+			"attributes": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(attributeFilterType)),
+				Description: "Attribute values the products must have.",
+			},
+			"published": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"codeValue": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"products": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(productType))),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: filterType},
+					"first": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: defaultGraphQLPageSize,
+						Description:  fmt.Sprintf("Page size, at most %d.", maxGraphQLPageSize),
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: gh.products,
+			},
+			"product": &graphql.Field{
+				Type: productType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: gh.product,
+			},
+			"quote": &graphql.Field{
+				Type: graphql.NewNonNull(quoteType),
+				Args: graphql.FieldConfigArgument{
+					"products": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(quoteLineType))},
+					"variants": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(variantQuoteLineType))},
+				},
+				Resolve: gh.quote,
+			},
+		},
+	})
+}
+
+var (
+	jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+		Name:        "JSON",
+		Description: "Any json value.",
+		Serialize: func(v any) any {
+			return v
+		},
+		ParseValue: func(v any) any {
+			return v
+		},
+		ParseLiteral: func(v ast.Value) any {
+			return v.GetValue()
+		},
+	})
+
+	attributeFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "AttributeFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	quoteLineType = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "QuoteLine",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id":       &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	variantQuoteLineType = graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "VariantQuoteLine",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"code":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+)
+
+func productField(t graphql.Output, get func(domain.Product) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(t),
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			return get(rp.Source.(domain.Product)), nil
+		},
+	}
+}
+
+func variantField(t graphql.Output, get func(domain.Variant) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(t),
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			return get(rp.Source.(domain.Variant)), nil
+		},
+	}
+}
+
+func mediaField(t graphql.Output, get func(domain.Media) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(t),
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			return get(rp.Source.(domain.Media)), nil
+		},
+	}
+}
+
+func quoteField(t graphql.Output, get func(quote) any) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(t),
+		Resolve: func(rp graphql.ResolveParams) (any, error) {
+			return get(rp.Source.(quote)), nil
+		},
+	}
+}
+
+// orEmpty returns an empty slice for nil ones, which non null lists can't
+// hold.
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+
+	return s
+}
+
+// products filters by price and attributes in the service, as the search
+// endpoint does, and then by the other fields.
+func (gh *graphQLHandler) products(rp graphql.ResolveParams) (any, error) {
+	first, _ := rp.Args["first"].(int)
+	offset, _ := rp.Args["offset"].(int)
+
+	if first < 0 || first > maxGraphQLPageSize || offset < 0 {
+		return nil, fmt.Errorf("first must be between 0 and %d, and offset can't be negative", maxGraphQLPageSize)
+	}
+
+	filter, _ := rp.Args["filter"].(map[string]any)
+
+	attributes := make(map[string]string)
+	if fs, ok := filter["attributes"].([]any); ok {
+		for _, f := range fs {
+			af, _ := f.(map[string]any)
+			name, _ := af["name"].(string)
+			value, _ := af["value"].(string)
+			attributes[name] = value
+		}
+	}
+
+	var (
+		ps  []domain.Product
+		err error
+	)
+
+	price, hasPrice := filter["priceGt"].(float64)
+
+	switch {
+	case hasPrice || len(attributes) > 0:
+		ps, err = gh.svc.Search(price, attributes)
+	default:
+		ps, err = gh.svc.All()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	published, hasPublished := filter["published"].(bool)
+	codeValue, hasCodeValue := filter["codeValue"].(string)
+
+	matches := make([]domain.Product, 0, len(ps))
+	for _, p := range ps {
+		if hasPublished && p.IsPublished != published {
+			continue
+		}
+
+		if hasCodeValue && p.CodeValue != codeValue {
+			continue
+		}
+
+		matches = append(matches, p)
+	}
+
+	if offset >= len(matches) {
+		return []domain.Product{}, nil
+	}
+
+	matches = matches[offset:]
+	if first < len(matches) {
+		matches = matches[:first]
+	}
+
+	return matches, nil
+}
+
+// product resolves to null when the product does not exist.
+func (gh *graphQLHandler) product(rp graphql.ResolveParams) (any, error) {
+	id, _ := rp.Args["id"].(int)
+
+	p, err := gh.svc.GetById(id)
+	if errors.Is(err, producti.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (gh *graphQLHandler) quote(rp graphql.ResolveParams) (any, error) {
+	productQuantities := make(map[int]int)
+	if lines, ok := rp.Args["products"].([]any); ok {
+		for _, l := range lines {
+			line, _ := l.(map[string]any)
+			id, _ := line["id"].(int)
+			quantity, _ := line["quantity"].(int)
+
+			if quantity < 1 {
+				return nil, producti.ErrInvalidConsumerPriceList
+			}
+			productQuantities[id] += quantity
+		}
+	}
+
+	variantQuantities := make(map[string]int)
+	if lines, ok := rp.Args["variants"].([]any); ok {
+		for _, l := range lines {
+			line, _ := l.(map[string]any)
+			code, _ := line["code"].(string)
+			quantity, _ := line["quantity"].(int)
+
+			if quantity < 1 {
+				return nil, producti.ErrInvalidConsumerPriceList
+			}
+			variantQuantities[code] += quantity
+		}
+	}
+
+	if len(productQuantities) == 0 && len(variantQuantities) == 0 {
+		return nil, producti.ErrInvalidConsumerPriceList
+	}
+
+	total, products, variants, err := gh.svc.CustomerPrice(productQuantities, variantQuantities)
+	if err != nil {
+		return nil, err
+	}
+
+	return quote{
+		products: products,
+		variants: variants,
+		total:    total,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+func graphQLMux(ps []domain.Product, ops ...GraphQLOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewGraphQL(mux, &stubProducts{ps: ps}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil), ops...)
+
+	return mux
+}
+
+func TestGraphQL(t *testing.T) {
+	mux := graphQLMux([]domain.Product{
+		{Id: 1, Name: "A", CodeValue: "A1", IsPublished: true, Expiration: "20/01/2030", Price: 10},
+		{Id: 2, Name: "B", CodeValue: "B2", IsPublished: false, Expiration: "20/01/2030", Price: 20},
+		{Id: 3, Name: "C", CodeValue: "C3", IsPublished: true, Expiration: "20/01/2030", Price: 30},
+	}, MaxComplexity(50))
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		data   string
+		errors []string
+	}{
+		{
+			name:   "filters and pages",
+			body:   `{"query":"{ products(filter: {published: true}, first: 1, offset: 1) { id price { amount currency } expiration } }"}`,
+			status: http.StatusOK,
+			data:   `{"products":[{"id":3,"price":{"amount":"30","currency":"USD"},"expiration":"2030-01-20"}]}`,
+		},
+		{
+			name:   "too complex",
+			body:   `{"query":"{ products(first: 100) { id } }"}`,
+			status: http.StatusOK,
+			errors: []string{"query is too complex: complexity 101 exceeds 50"},
+		},
+		{
+			name:   "invalid query",
+			body:   `{"query":"{ products { unknown } }"}`,
+			status: http.StatusOK,
+			errors: []string{`Cannot query field "unknown" on type "Product".`},
+		},
+		{
+			name:   "missing query",
+			body:   `{}`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, GraphQLPath, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code, res.Body.String())
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []struct {
+					Message string `json:"message"`
+				} `json:"errors"`
+			}
+			err := json.Unmarshal(res.Body.Bytes(), &body)
+			assert.NoError(t, err)
+
+			var errs []string
+			for _, e := range body.Errors {
+				errs = append(errs, e.Message)
+			}
+			assert.Equal(t, test.errors, errs)
+
+			if test.data != "" {
+				assert.JSONEq(t, test.data, string(body.Data))
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcPermissions maps the grpc methods to the permission they need.
+// Methods missing from it are denied.
+var grpcPermissions = map[string]string{
+	pb.ProductService_ListProducts_FullMethodName:   PermProductsRead,
+	pb.ProductService_GetProduct_FullMethodName:     PermProductsRead,
+	pb.ProductService_SearchProducts_FullMethodName: PermProductsRead,
+	pb.ProductService_ConsumerPrice_FullMethodName:  PermProductsRead,
+}
+
+// grpcRoutes maps the grpc methods to the http route whose rate limits and
+// quotas they share.
+var grpcRoutes = map[string][2]string{
+	pb.ProductService_ListProducts_FullMethodName:   {http.MethodGet, "/products/"},
+	pb.ProductService_GetProduct_FullMethodName:     {http.MethodGet, "/products/:id"},
+	pb.ProductService_SearchProducts_FullMethodName: {http.MethodGet, "/products/search"},
+	pb.ProductService_ConsumerPrice_FullMethodName:  {http.MethodGet, "/products/consumer_price"},
+}
+
+// grpcCaller is the authenticated caller of a grpc method.
+type grpcCaller struct {
+	subject     string
+	roles       []string
+	permissions []string
+	key         *domain.APIKey
+}
+
+// UnaryInterceptor authenticates, limits and authorizes grpc calls as
+// Authenticate and Authorize do for http requests, reading the credentials
+// from the "x-api-key" or "authorization" metadata.
+func (a *Auth) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ip := peerIP(ctx)
+
+	if _, locked := a.lockout.Locked(ip); locked {
+		logSecurityEvent("locked_out", ip, "", "GRPC", info.FullMethod, ErrLockedOut.Error())
+		return nil, status.Error(codes.ResourceExhausted, ErrLockedOut.Error())
+	}
+
+	c, err := a.grpcCaller(ctx)
+	if err != nil {
+		logSecurityEvent("auth_failed", ip, "", "GRPC", info.FullMethod, err.Error())
+
+		if a.lockout.Fail(ip) {
+			logSecurityEvent("lockout", ip, "", "GRPC", info.FullMethod, ErrLockedOut.Error())
+		}
+
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if c.subject != "" {
+		a.lockout.Reset(ip)
+	}
+
+	if route, ok := grpcRoutes[info.FullMethod]; ok && a.limiter != nil {
+		err = a.limiter.LimitCall(ip, c.key, route[0], route[1])
+		if err != nil {
+			logSecurityEvent(limitEvent(err), ip, c.subject, "GRPC", info.FullMethod, err.Error())
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+	}
+
+	permission, ok := grpcPermissions[info.FullMethod]
+	if !ok || !a.grpcAllowed(c, permission) {
+		if c.subject == "" {
+			return nil, status.Error(codes.Unauthenticated, ErrMissingToken.Error())
+		}
+
+		logSecurityEvent("permission_denied", ip, c.subject, "GRPC", info.FullMethod, permission)
+
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s: %s not granted to roles [%s]",
+			ErrPermissionDenied.Error(),
+			permission,
+			strings.Join(c.roles, " "),
+		))
+	}
+
+	return handler(ctx, req)
+}
+
+// grpcCaller verifies the api key or bearer token of the call, when present.
+// Calls without credentials get the anonymous role.
+func (a *Auth) grpcCaller(ctx context.Context) (grpcCaller, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if key := first(md.Get("x-api-key")); key != "" {
+		if a.keys == nil {
+			return grpcCaller{}, apikey.ErrInvalidKey
+		}
+
+		k, err := a.keys.Authenticate(key)
+		if err != nil {
+			return grpcCaller{}, err
+		}
+
+		var permissions []string
+		for _, scope := range k.Scopes {
+			permissions = append(permissions, scopePermissions[scope]...)
+		}
+
+		return grpcCaller{
+			subject:     fmt.Sprintf("apikey:%d", k.Id),
+			roles:       []string{},
+			permissions: permissions,
+			key:         &k,
+		}, nil
+	}
+
+	h := first(md.Get("authorization"))
+	if h == "" {
+		return grpcCaller{roles: []string{RoleAnonymous}}, nil
+	}
+
+	scheme, token, found := strings.Cut(h, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return grpcCaller{}, ErrMissingToken
+	}
+
+	claims, err := a.verifier.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return grpcCaller{}, err
+	}
+
+	roles := claims.Strings("roles")
+	if role, ok := claims.Extra["role"].(string); ok {
+		roles = append(roles, role)
+	}
+
+	return grpcCaller{
+		subject: claims.Subject,
+		roles:   roles,
+	}, nil
+}
+
+func (a *Auth) grpcAllowed(c grpcCaller, permission string) bool {
+	for _, p := range c.permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return a.policy.Allowed(c.roles, permission)
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type productServer struct {
+	pb.UnimplementedProductServiceServer
+	svc producti.ProductService
+}
+
+// NewProductServer registers the product catalog on g. Calls are secured
+// by Auth.UnaryInterceptor.
+func NewProductServer(g *grpc.Server, s producti.ProductService) {
+	pb.RegisterProductServiceServer(g, &productServer{
+		svc: s,
+	})
+}
+
+func (ps *productServer) ListProducts(ctx context.Context, req *pb.ListProductsRequest) (*pb.ListProductsResponse, error) {
+	products, err := ps.svc.All()
+	if err != nil {
+		return nil, grpcErr(err)
+	}
+
+	return newListProductsResponse(products)
+}
+
+func (ps *productServer) GetProduct(ctx context.Context, req *pb.GetProductRequest) (*pb.Product, error) {
+	p, err := ps.svc.GetById(int(req.GetId()))
+	if err != nil {
+		return nil, grpcErr(err)
+	}
+
+	return newPBProduct(p)
+}
+
+func (ps *productServer) SearchProducts(ctx context.Context, req *pb.SearchProductsRequest) (*pb.ListProductsResponse, error) {
+	products, err := ps.svc.Search(req.GetPriceGt(), req.GetAttributes())
+	if err != nil {
+		return nil, grpcErr(err)
+	}
+
+	return newListProductsResponse(products)
+}
+
+func (ps *productServer) ConsumerPrice(ctx context.Context, req *pb.ConsumerPriceRequest) (*pb.ConsumerPriceResponse, error) {
+	if len(req.GetProducts()) == 0 && len(req.GetVariants()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, producti.ErrInvalidConsumerPriceList.Error())
+	}
+
+	productQuantities := make(map[int]int, len(req.GetProducts()))
+	for id, quantity := range req.GetProducts() {
+		if quantity < 1 {
+			return nil, status.Error(codes.InvalidArgument, producti.ErrInvalidConsumerPriceList.Error())
+		}
+		productQuantities[int(id)] = int(quantity)
+	}
+
+	variantQuantities := make(map[string]int, len(req.GetVariants()))
+	for code, quantity := range req.GetVariants() {
+		if quantity < 1 {
+			return nil, status.Error(codes.InvalidArgument, producti.ErrInvalidConsumerPriceList.Error())
+		}
+		variantQuantities[code] = int(quantity)
+	}
+
+	total, products, variants, err := ps.svc.CustomerPrice(productQuantities, variantQuantities)
+	if err != nil {
+		return nil, grpcErr(err)
+	}
+
+	res := &pb.ConsumerPriceResponse{
+		Variants:   newPBVariants(variants),
+		TotalPrice: newPBMoney(total),
+	}
+
+	for _, p := range products {
+		pp, err := newPBProduct(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Products = append(res.Products, pp)
+	}
+
+	return res, nil
+}
+
+func newListProductsResponse(products []domain.Product) (*pb.ListProductsResponse, error) {
+	res := &pb.ListProductsResponse{
+		Products: make([]*pb.Product, 0, len(products)),
+	}
+
+	for _, p := range products {
+		pp, err := newPBProduct(p)
+		if err != nil {
+			return nil, err
+		}
+		res.Products = append(res.Products, pp)
+	}
+
+	return res, nil
+}
+
+func newPBProduct(p domain.Product) (*pb.Product, error) {
+	attributes, err := structpb.NewStruct(p.Attributes)
+	if err != nil {
+		return nil, status.Error(codes.Internal, producti.ErrInvalidAttributes.Error())
+	}
+
+	pp := &pb.Product{
+		Id:              int32(p.Id),
+		Name:            p.Name,
+		Quantity:        int32(p.Quantity),
+		CodeValue:       p.CodeValue,
+		IsPublished:     p.IsPublished,
+		Expiration:      newPBDate(p.Expiration),
+		Price:           newPBMoney(p.Price),
+		Variants:        newPBVariants(p.Variants),
+		Attributes:      attributes,
+		ReorderPoint:    int32(p.ReorderPoint),
+		ReorderQuantity: int32(p.ReorderQuantity),
+	}
+
+	for _, m := range p.Media {
+		pp.Media = append(pp.Media, &pb.Media{
+			Id:           m.Id,
+			Filename:     m.Filename,
+			ContentType:  m.ContentType,
+			Size:         m.Size,
+			Url:          m.URL,
+			ThumbnailUrl: m.ThumbnailURL,
+		})
+	}
+
+	return pp, nil
+}
+
+func newPBVariants(vs []domain.Variant) []*pb.Variant {
+	res := make([]*pb.Variant, 0, len(vs))
+	for _, v := range vs {
+		res = append(res, &pb.Variant{
+			CodeValue: v.CodeValue,
+			Price:     newPBMoney(v.Price),
+			Quantity:  int32(v.Quantity),
+			Attributes: &pb.VariantAttributes{
+				Size:  v.Attributes.Size,
+				Color: v.Attributes.Color,
+				Pack:  int32(v.Attributes.Pack),
+			},
+		})
+	}
+
+	return res
+}
+
+// newPBMoney rounds the amount to cents, as prices are stored.
+func newPBMoney(amount float64) *pb.Money {
+	cents := int64(math.Round(amount * 100))
+
+	return &pb.Money{
+		CurrencyCode: Currency,
+		Units:        cents / 100,
+		Nanos:        int32(cents%100) * 10_000_000,
+	}
+}
+
+// newPBDate returns nil for expirations that can't be parsed.
+func newPBDate(expiration string) *pb.Date {
+	t, err := time.Parse(expirationLayout, expiration)
+	if err != nil {
+		return nil
+	}
+
+	return &pb.Date{
+		Year:  int32(t.Year()),
+		Month: int32(t.Month()),
+		Day:   int32(t.Day()),
+	}
+}
+
+func grpcErr(err error) error {
+	switch {
+	case errors.Is(err, producti.ErrNotFound):
+		return status.Error(codes.NotFound, producti.ErrNotFound.Error())
+	case errors.Is(err, producti.ErrVariantNotFound):
+		return status.Error(codes.NotFound, producti.ErrVariantNotFound.Error())
+	case errors.Is(err, producti.ErrInvalidPrice):
+		return status.Error(codes.InvalidArgument, producti.ErrInvalidPrice.Error())
+	case errors.Is(err, producti.ErrInvalidAttributes):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, producti.ErrNoStock):
+		return status.Error(codes.FailedPrecondition, producti.ErrNoStock.Error())
+	case errors.Is(err, producti.ErrNotPublished):
+		return status.Error(codes.FailedPrecondition, producti.ErrNotPublished.Error())
+	}
+
+	return status.Error(codes.Internal, "internal server error")
+}
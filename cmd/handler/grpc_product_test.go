@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/pb"
+	"gituhb.com/juajosserand/goweb/pkg/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func grpcClient(t *testing.T, ps []domain.Product) pb.ProductServiceClient {
+	return grpcAuthClient(t, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil), ps)
+}
+
+func grpcAuthClient(t *testing.T, auth *Auth, ps []domain.Product) pb.ProductServiceClient {
+	lis := bufconn.Listen(1 << 20)
+
+	g := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryInterceptor))
+	NewProductServer(g, &stubProducts{ps: ps})
+
+	go func() {
+		_ = g.Serve(lis)
+	}()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewProductServiceClient(conn)
+}
+
+func TestGRPCListProducts(t *testing.T) {
+	client := grpcClient(t, []domain.Product{{
+		Id:         1,
+		Name:       "Test Product",
+		Quantity:   1,
+		CodeValue:  "A1B2C3",
+		Expiration: "20/01/2030",
+		Price:      100.5,
+		Attributes: map[string]any{"brand": "acme"},
+	}})
+
+	res, err := client.ListProducts(context.Background(), &pb.ListProductsRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.GetProducts(), 1)
+
+	p := res.GetProducts()[0]
+	assert.Equal(t, "A1B2C3", p.GetCodeValue())
+	assert.Equal(t, int64(100), p.GetPrice().GetUnits())
+	assert.Equal(t, int32(500_000_000), p.GetPrice().GetNanos())
+	assert.Equal(t, Currency, p.GetPrice().GetCurrencyCode())
+	assert.Equal(t, int32(2030), p.GetExpiration().GetYear())
+	assert.Equal(t, int32(1), p.GetExpiration().GetMonth())
+	assert.Equal(t, int32(20), p.GetExpiration().GetDay())
+	assert.Equal(t, "acme", p.GetAttributes().AsMap()["brand"])
+}
+
+func TestGRPCErrors(t *testing.T) {
+	client := grpcClient(t, nil)
+
+	tests := []struct {
+		name  string
+		token string
+		call  func(ctx context.Context) error
+		code  codes.Code
+	}{
+		{
+			name:  "invalid token",
+			token: "Bearer invalid",
+			call: func(ctx context.Context) error {
+				_, err := client.ListProducts(ctx, &pb.ListProductsRequest{})
+				return err
+			},
+			code: codes.Unauthenticated,
+		},
+		{
+			name:  "empty consumer price list",
+			token: bearer("viewer"),
+			call: func(ctx context.Context) error {
+				_, err := client.ConsumerPrice(ctx, &pb.ConsumerPriceRequest{})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", test.token)
+
+			err := test.call(ctx)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
+
+// stubKeys authenticates the api keys it holds by plain text.
+type stubKeys map[string]domain.APIKey
+
+func (s stubKeys) Authenticate(key string) (domain.APIKey, error) {
+	k, ok := s[key]
+	if !ok {
+		return domain.APIKey{}, apikey.ErrInvalidKey
+	}
+
+	return k, nil
+}
+
+func (s stubKeys) HasActive() (bool, error) {
+	return len(s) > 0, nil
+}
+
+func TestGRPCRateLimit(t *testing.T) {
+	limits := &ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 100, Period: time.Minute},
+		Routes: map[string]ratelimit.Limit{
+			"GET /products/consumer_price": {Requests: 1, Period: time.Minute},
+		},
+		DailyQuota: 2,
+	}
+
+	keys := stubKeys{"secret": {Id: 1, Scopes: []string{apikey.ScopeProductsRead}}}
+
+	auth := NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), keys, RateLimiter(NewRateLimit(limits)))
+	client := grpcAuthClient(t, auth, nil)
+
+	t.Run("route limit", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", bearer("viewer"))
+
+		// the limiter runs before the call fails on its arguments
+		_, err := client.ConsumerPrice(ctx, &pb.ConsumerPriceRequest{})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.ConsumerPrice(ctx, &pb.ConsumerPriceRequest{})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), ErrRateLimited.Error())
+	})
+
+	t.Run("daily quota", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "secret")
+
+		for i := 0; i < 2; i++ {
+			_, err := client.ListProducts(ctx, &pb.ListProductsRequest{})
+			assert.NoError(t, err)
+		}
+
+		_, err := client.ListProducts(ctx, &pb.ListProductsRequest{})
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), ErrQuotaExceeded.Error())
+	})
+}
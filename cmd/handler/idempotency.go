@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/pkg/idempotency"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+
+	// maxIdempotentBody bounds the bodies buffered to be fingerprinted.
+	maxIdempotentBody = 32 << 20
+)
+
+var (
+	ErrInvalidIdempotencyKey  = errors.New("invalid idempotency key")
+	ErrIdempotentBodyTooLarge = errors.New("request body too large for an idempotent request")
+)
+
+// Idempotency replays the response of POST requests retried with the same
+// Idempotency-Key header. Keys are scoped to the authenticated subject, or
+// to the client ip for anonymous requests.
+type Idempotency struct {
+	store *idempotency.Store
+}
+
+func NewIdempotency(s *idempotency.Store) *Idempotency {
+	return &Idempotency{
+		store: s,
+	}
+}
+
+func (i *Idempotency) Handle(ctx *gin.Context) {
+	key := ctx.GetHeader(IdempotencyKeyHeader)
+	if ctx.Request.Method != http.MethodPost || key == "" {
+		ctx.Next()
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLen {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			ErrInvalidIdempotencyKey.Error(),
+		))
+		return
+	}
+
+	scope := Subject(ctx)
+	if scope == "" {
+		scope = "ip:" + ctx.ClientIP()
+	}
+	key = scope + " " + key
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxIdempotentBody+1))
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			err.Error(),
+		))
+		return
+	}
+
+	if len(body) > maxIdempotentBody {
+		ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+			http.StatusRequestEntityTooLarge,
+			"request entity too large",
+			ErrIdempotentBodyTooLarge.Error(),
+		))
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	res, replay, err := i.store.Begin(key, fingerprint(ctx, body))
+	if err != nil {
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, web.ErrResponse(
+				http.StatusUnprocessableEntity,
+				"unprocessable entity",
+				err.Error(),
+			))
+		default:
+			ctx.AbortWithStatusJSON(http.StatusConflict, web.ErrResponse(
+				http.StatusConflict,
+				"conflict",
+				err.Error(),
+			))
+		}
+		return
+	}
+
+	if replay {
+		for k, v := range res.Header {
+			ctx.Writer.Header()[k] = v
+		}
+		ctx.Header("Idempotent-Replayed", "true")
+		ctx.Data(res.Status, res.Header.Get("Content-Type"), res.Body)
+		ctx.Abort()
+		return
+	}
+
+	// the key is released unless the response is stored, also when the
+	// handlers panic
+	completed := false
+	defer func() {
+		if !completed {
+			i.store.Release(key)
+		}
+	}()
+
+	w := &recorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = w
+
+	ctx.Next()
+
+	status := ctx.Writer.Status()
+	if !storable(status) {
+		return
+	}
+
+	i.store.Complete(key, idempotency.Response{
+		Status: status,
+		Header: http.Header{
+			"Content-Type": {ctx.Writer.Header().Get("Content-Type")},
+		},
+		Body: w.body.Bytes(),
+	})
+	completed = true
+}
+
+// storable reports whether a response with the status would be the same on
+// a retry. Server errors, auth failures and throttling are not stored, so
+// the request can be retried.
+func storable(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+
+	return status < http.StatusInternalServerError
+}
+
+// fingerprint identifies the request by method, path, query and body.
+func fingerprint(ctx *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.RequestURI() + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder copies the response body written through it.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
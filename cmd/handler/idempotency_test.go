@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/pkg/idempotency"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+// idempotentMux serves POST /things with h behind an idempotent auth.
+func idempotentMux(h gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	a := NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil,
+		Idempotent(NewIdempotency(idempotency.NewStore(time.Hour))),
+	)
+
+	mux := gin.New()
+	mux.Use(gin.CustomRecovery(func(ctx *gin.Context, _ any) {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	mux.POST("/things", a.Authenticate, h)
+
+	return mux
+}
+
+func post(mux *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer("editor"))
+	req.Header.Set(IdempotencyKeyHeader, key)
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	return res
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls int32
+
+	mux := idempotentMux(func(ctx *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		ctx.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	first := post(mux, "k1", `{"name":"a"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := post(mux, "k1", `{"name":"a"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+	// another key runs the handler again
+	other := post(mux, "k2", `{"name":"a"}`)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get("Idempotent-Replayed"))
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyConflictingBody(t *testing.T) {
+	mux := idempotentMux(func(ctx *gin.Context) {
+		ctx.Status(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusCreated, post(mux, "k", `{"name":"a"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, post(mux, "k", `{"name":"b"}`).Code)
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	mux := idempotentMux(func(ctx *gin.Context) {
+		close(entered)
+		<-release
+		ctx.Status(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- post(mux, "k", `{}`)
+	}()
+
+	<-entered
+	assert.Equal(t, http.StatusConflict, post(mux, "k", `{}`).Code)
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+}
+
+func TestIdempotencyReleaseOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(*gin.Context)
+	}{
+		{
+			name: "server error",
+			handler: func(ctx *gin.Context) {
+				ctx.Status(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "panic",
+			handler: func(ctx *gin.Context) {
+				panic("handler failed")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fail := true
+
+			mux := idempotentMux(func(ctx *gin.Context) {
+				if fail {
+					test.handler(ctx)
+					return
+				}
+				ctx.Status(http.StatusCreated)
+			})
+
+			assert.Equal(t, http.StatusInternalServerError, post(mux, "k", `{}`).Code)
+
+			// the retry runs instead of conflicting with the failed request
+			fail = false
+
+			res := post(mux, "k", `{}`)
+			assert.Equal(t, http.StatusCreated, res.Code)
+			assert.Empty(t, res.Header().Get("Idempotent-Replayed"))
+		})
+	}
+}
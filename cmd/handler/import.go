@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const ImportPath = "/products/import"
+
+const maxImportSize = 10 << 20
+
+var ErrUnsupportedImport = errors.New("unsupported import format, expected csv or json")
+
+// importDecoders decode the uploads by file extension or content type.
+var importDecoders = map[string]func(io.Reader) ([]storage.Record, error){
+	".csv":             storage.DecodeCSV,
+	".json":            storage.DecodeJSON,
+	"text/csv":         storage.DecodeCSV,
+	"application/json": storage.DecodeJSON,
+}
+
+// Results of the rows of an import.
+const (
+	importCreated  = "created"
+	importUpdated  = "updated"
+	importRejected = "rejected"
+)
+
+type importResponse struct {
+	DryRun   bool        `json:"dry_run"`
+	Created  int         `json:"created"`
+	Updated  int         `json:"updated"`
+	Rejected int         `json:"rejected"`
+	Rows     []importRow `json:"rows"`
+}
+
+// importRow is the result of a row of the upload. Dry runs have no id for
+// created rows.
+type importRow struct {
+	Line      int    `json:"line"`
+	CodeValue string `json:"code_value,omitempty"`
+	Id        int    `json:"id,omitempty"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Import upserts the products of a csv or json upload by code value, with
+// a single storage write. Each row is validated like the body of the
+// create route and by the service rules, and rejected rows do not stop the
+// others. With dry_run the rows are checked without being applied.
+func (ph *product) Import(ctx *gin.Context) {
+	dryRun := false
+	if v := ctx.Query("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				"invalid dry_run",
+			))
+			return
+		}
+		dryRun = b
+	}
+
+	// leave room for the multipart envelope
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize+1<<20)
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+				http.StatusRequestEntityTooLarge,
+				"request entity too large",
+				"import file too large",
+			))
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			"missing import file",
+		))
+		return
+	}
+
+	if fh.Size > maxImportSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+			http.StatusRequestEntityTooLarge,
+			"request entity too large",
+			"import file too large",
+		))
+		return
+	}
+
+	decode, ok := importDecoders[filepath.Ext(fh.Filename)]
+	if !ok {
+		contentType, _, _ := mime.ParseMediaType(fh.Header.Get("Content-Type"))
+		decode, ok = importDecoders[contentType]
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusUnsupportedMediaType, web.ErrResponse(
+			http.StatusUnsupportedMediaType,
+			"unsupported media type",
+			ErrUnsupportedImport.Error(),
+		))
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+	defer f.Close()
+
+	records, err := decode(f)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			err.Error(),
+		))
+		return
+	}
+
+	res := importResponse{
+		DryRun: dryRun,
+		Rows:   make([]importRow, len(records)),
+	}
+
+	ps := make([]domain.Product, 0, len(records))
+	indexes := make([]int, 0, len(records))
+
+	for i, rec := range records {
+		res.Rows[i] = importRow{Line: rec.Line, CodeValue: rec.Product.CodeValue}
+
+		if rec.Err != nil {
+			res.Rows[i].Result, res.Rows[i].Error = importRejected, producti.ErrInvalidData.Error()+": "+rec.Err.Error()
+			continue
+		}
+
+		p := rec.Product
+		r := request{
+			Name:        p.Name,
+			Quantity:    p.Quantity,
+			CodeValue:   p.CodeValue,
+			IsPublished: p.IsPublished,
+			Expiration:  p.Expiration,
+			Price:       p.Price,
+			Attributes:  p.Attributes,
+		}
+
+		if err := binding.Validator.ValidateStruct(&r); err != nil {
+			res.Rows[i].Result, res.Rows[i].Error = importRejected, producti.ErrInvalidData.Error()
+			continue
+		}
+
+		ps = append(ps, p)
+		indexes = append(indexes, i)
+	}
+
+	if !ph.importPricesAllowed(ctx, ps) {
+		ph.auth.Deny(ctx, PermProductsPrice)
+		return
+	}
+
+	results, err := ph.svc.Import(ps, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	for j, result := range results {
+		row := &res.Rows[indexes[j]]
+
+		switch {
+		case result.Err != nil:
+			row.Result, row.Error = importRejected, result.Err.Error()
+		case result.Type == producti.OpCreate:
+			row.Result = importCreated
+			if !dryRun {
+				row.Id = result.Id
+			}
+		default:
+			row.Result, row.Id = importUpdated, result.Id
+		}
+	}
+
+	for _, row := range res.Rows {
+		switch row.Result {
+		case importCreated:
+			res.Created++
+		case importUpdated:
+			res.Updated++
+		default:
+			res.Rejected++
+		}
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(res))
+}
+
+// importPricesAllowed reports whether the request may set the prices of the
+// products it updates, even in dry runs.
+func (ph *product) importPricesAllowed(ctx *gin.Context, ps []domain.Product) bool {
+	if ph.auth.Allowed(ctx, PermProductsPrice) {
+		return true
+	}
+
+	products, err := ph.svc.All()
+	if err != nil {
+		return false
+	}
+
+	prices := make(map[string]float64, len(products))
+	for _, p := range products {
+		prices[p.CodeValue] = p.Price
+	}
+
+	for _, p := range ps {
+		if price, ok := prices[p.CodeValue]; ok && price != p.Price {
+			return false
+		}
+	}
+
+	return true
+}
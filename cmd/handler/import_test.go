@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+)
+
+func upload(t *testing.T, mux *gin.Engine, role string, query string, filename string, content string) (int, importResponse) {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, ImportPath+query, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", bearer(role))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	var env struct {
+		Data importResponse `json:"data"`
+	}
+	_ = json.Unmarshal(res.Body.Bytes(), &env)
+
+	return res.Code, env.Data
+}
+
+func TestImport(t *testing.T) {
+	const catalog = `id,name,quantity,code_value,is_published,expiration,price
+,First,7,FIRST,true,20/01/2030,10
+,Third,3,THIRD,true,20/01/2030,15
+,Again,3,THIRD,true,20/01/2030,15
+,Broken,many,BROKEN,true,20/01/2030,15
+,Lower,3,lower,true,20/01/2030,15
+`
+
+	t.Run("dry run", func(t *testing.T) {
+		mux, path := bulkMux(t)
+		before, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		status, res := upload(t, mux, "admin", "?dry_run=true", "catalog.csv", catalog)
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, res.DryRun)
+		assert.Equal(t, 1, res.Created)
+		assert.Equal(t, 1, res.Updated)
+		assert.Equal(t, 3, res.Rejected)
+
+		assert.Equal(t, importRow{Line: 2, CodeValue: "FIRST", Id: 1, Result: importUpdated}, res.Rows[0])
+		assert.Equal(t, importRow{Line: 3, CodeValue: "THIRD", Result: importCreated}, res.Rows[1])
+		assert.Equal(t, producti.ErrDuplicatedCodeValue.Error(), res.Rows[2].Error)
+		assert.Equal(t, 5, res.Rows[3].Line)
+		assert.Equal(t, importRejected, res.Rows[3].Result)
+		assert.Equal(t, producti.ErrInvalidData.Error(), res.Rows[4].Error)
+
+		after, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		mux, path := bulkMux(t)
+
+		status, res := upload(t, mux, "admin", "", "catalog.csv", catalog)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 3, res.Rows[1].Id)
+
+		ps := storedProducts(t, path)
+		if assert.Len(t, ps, 3) {
+			assert.Equal(t, 7, ps[0].Quantity)
+			assert.Equal(t, "THIRD", ps[2].CodeValue)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		mux, path := bulkMux(t)
+
+		status, res := upload(t, mux, "admin", "", "catalog.json", `[
+			{"name": "Second", "quantity": 9, "code_value": "SECOND", "expiration": "20/01/2030", "price": 20},
+			{"name": "Fourth", "quantity": "one"}
+		]`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 1, res.Updated)
+		assert.Equal(t, 1, res.Rejected)
+		assert.Equal(t, 2, res.Rows[1].Line)
+
+		assert.Equal(t, 9, storedProducts(t, path)[1].Quantity)
+	})
+
+	t.Run("price changes", func(t *testing.T) {
+		mux, path := bulkMux(t)
+
+		status, _ := upload(t, mux, "editor", "?dry_run=true", "catalog.csv", ",First,7,FIRST,true,20/01/2030,99\n")
+		assert.Equal(t, http.StatusForbidden, status)
+
+		status, _ = upload(t, mux, "editor", "", "catalog.csv", ",First,7,FIRST,true,20/01/2030,10\n")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 7, storedProducts(t, path)[0].Quantity)
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		mux, _ := bulkMux(t)
+
+		status, _ := upload(t, mux, "admin", "", "catalog.txt", catalog)
+		assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+		status, _ = upload(t, mux, "admin", "", "catalog.json", `{"name": "not a list"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = upload(t, mux, "admin", "?dry_run=maybe", "catalog.csv", catalog)
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, _ = upload(t, mux, "viewer", "", "catalog.csv", catalog)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+}
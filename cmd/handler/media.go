@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/media"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type mediaHandler struct {
+	svc media.MediaService
+}
+
+func NewMedia(mux *gin.Engine, s media.MediaService, a *Auth) {
+	mh := &mediaHandler{
+		svc: s,
+	}
+
+	mux.GET("/media/*key", mh.Get)
+
+	productsMux := mux.Group("/products")
+
+	productsMux.Use(a.Authenticate, a.Authorize(PermProductsUpdate))
+
+	productsMux.POST("/:id/media", mh.Upload)
+	productsMux.DELETE("/:id/media/:mediaId", mh.Delete)
+}
+
+func (mh *mediaHandler) Get(ctx *gin.Context) {
+	data, contentType, err := mh.svc.Get(strings.TrimPrefix(ctx.Param("key"), "/"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			media.ErrNotFound.Error(),
+		))
+		return
+	}
+
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+func (mh *mediaHandler) Upload(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	// leave room for the multipart envelope
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, mh.svc.MaxSize()+1<<20)
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+				http.StatusRequestEntityTooLarge,
+				"request entity too large",
+				media.ErrTooLarge.Error(),
+			))
+			return
+		}
+
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			"missing media file",
+		))
+		return
+	}
+
+	if fh.Size > mh.svc.MaxSize() {
+		ctx.JSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+			http.StatusRequestEntityTooLarge,
+			"request entity too large",
+			media.ErrTooLarge.Error(),
+		))
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			media.ErrUpload.Error(),
+		))
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			media.ErrUpload.Error(),
+		))
+		return
+	}
+
+	m, err := mh.svc.Upload(id, fh.Filename, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrTooLarge):
+			ctx.JSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+				http.StatusRequestEntityTooLarge,
+				"request entity too large",
+				media.ErrTooLarge.Error(),
+			))
+		case errors.Is(err, media.ErrUnsupportedType):
+			ctx.JSON(http.StatusUnsupportedMediaType, web.ErrResponse(
+				http.StatusUnsupportedMediaType,
+				"unsupported media type",
+				media.ErrUnsupportedType.Error(),
+			))
+		case errors.Is(err, producti.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				producti.ErrNotFound.Error(),
+			))
+		default:
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				media.ErrUpload.Error(),
+			))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, web.Response(m))
+}
+
+func (mh *mediaHandler) Delete(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = mh.svc.Delete(id, ctx.Param("mediaId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, producti.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				producti.ErrNotFound.Error(),
+			))
+		case errors.Is(err, producti.ErrMediaNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				producti.ErrMediaNotFound.Error(),
+			))
+		default:
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				producti.ErrDeletion.Error(),
+			))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
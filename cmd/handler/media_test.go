@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/media"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// stubMedia fails the deletes with err.
+type stubMedia struct {
+	media.MediaService
+	err error
+}
+
+func (s stubMedia) Delete(int, string) error {
+	return s.err
+}
+
+func TestMediaDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"deleted", nil, http.StatusNoContent},
+		{"product not found", producti.ErrNotFound, http.StatusNotFound},
+		{"media not found", producti.ErrMediaNotFound, http.StatusNotFound},
+		{"write error", storage.ErrWriteFile, http.StatusInternalServerError},
+		{"other error", errors.New("disk failure"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := gin.New()
+			NewMedia(mux, stubMedia{err: test.err}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+			req := httptest.NewRequest(http.MethodDelete, "/products/1/media/m1", nil)
+			req.Header.Set("Authorization", bearer("admin"))
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code)
+			if test.status == http.StatusInternalServerError {
+				assert.Contains(t, res.Body.String(), producti.ErrDeletion.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,810 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/openapi"
+	"gituhb.com/juajosserand/goweb/pkg/ratelimit"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+const (
+	OpenAPIPath = "/openapi.json"
+	DocsPath    = "/docs"
+)
+
+// apiSpecs documents every registered route, keyed by "METHOD /path". The
+// openapi test fails when a route is added or removed without updating it.
+var apiSpecs = map[string]openapi.Spec{
+	"GET /ping": {
+		OperationId: "ping",
+		Summary:     "Health check",
+		Tags:        []string{"health"},
+		Response:    "",
+	},
+	"GET " + OpenAPIPath: {
+		OperationId: "getOpenAPI",
+		Summary:     "OpenAPI document of the api",
+		Tags:        []string{"docs"},
+		Response:    &openapi.Schema{Type: "object"},
+		Raw:         true,
+	},
+	"GET " + DocsPath: {
+		OperationId:  "getDocs",
+		Summary:      "Docs UI",
+		Tags:         []string{"docs"},
+		Response:     &openapi.Schema{Type: "string"},
+		ResponseType: "text/html",
+	},
+
+	// products
+	"GET /products/": secured(PermProductsRead, openapi.Spec{
+		OperationId: "listProducts",
+		Summary:     "List products, or export them as csv, ndjson or xml by the Accept header",
+		Tags:        []string{"products"},
+		Response:    []domain.Product{},
+		Negotiated:  []string{MIMECSV, MIMENDJSON, MIMEXML},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /products/:id": secured(PermProductsRead, openapi.Spec{
+		OperationId: "getProduct",
+		Summary:     "Get a product",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Status:      http.StatusFound,
+		Response:    domain.Product{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	}),
+	"GET /products/search": secured(PermProductsRead, openapi.Spec{
+		OperationId: "searchProducts",
+		Summary:     "Search products by price and attributes",
+		Tags:        []string{"products"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "priceGt",
+				In:          "query",
+				Description: "minimum price, exclusive; required without attribute filters",
+				Schema:      &openapi.Schema{Type: "number"},
+			},
+			{
+				Name:        "attributes",
+				In:          "query",
+				Description: "attribute filters, e.g. attributes[brand]=acme",
+				Style:       "deepObject",
+				Explode:     &explode,
+				Schema: &openapi.Schema{
+					Type:                 "object",
+					AdditionalProperties: &openapi.Schema{Type: "string"},
+				},
+			},
+		},
+		Response: []domain.Product{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"GET /products/consumer_price": secured(PermProductsRead, openapi.Spec{
+		OperationId: "consumerPrice",
+		Summary:     "Price a list of products and variants",
+		Tags:        []string{"products"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "list",
+				In:          "query",
+				Description: "product ids, e.g. [1,2,2]",
+				Schema:      &openapi.Schema{Type: "string", Pattern: `^\[\d+(,\d+)*\]$`},
+			},
+			{
+				Name:        "variants",
+				In:          "query",
+				Description: "variant codes, e.g. [ABC1,ABC2]",
+				Schema:      &openapi.Schema{Type: "string", Pattern: `^\[[A-Z0-9]+(,[A-Z0-9]+)*\]$`},
+			},
+		},
+		Response: consumerPriceResponse{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"POST /products/": secured(PermProductsCreate, openapi.Spec{
+		OperationId: "createProduct",
+		Summary:     "Create a product",
+		Tags:        []string{"products"},
+		Body:        request{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"POST /products/bulk": identified(PermProductsCreate, openapi.Spec{
+		OperationId: "bulkProducts",
+		Summary:     "Create, update and delete products in one batch; each operation needs the permission of its own route",
+		Tags:        []string{"products"},
+		Body:        bulkRequest{},
+		Response:    bulkResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"POST /products/import": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "importProducts",
+		Summary:     "Upsert products by code value from a csv or json file; also needs products:create, and products:price for price changes",
+		Tags:        []string{"products"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "dry_run",
+				In:          "query",
+				Description: "check the rows without applying them",
+				Schema:      &openapi.Schema{Type: "boolean"},
+			},
+		},
+		Body: &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+			Required:   []string{"file"},
+		},
+		BodyType: "multipart/form-data",
+		Response: importResponse{},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusInternalServerError,
+		},
+	}),
+	"PUT /products/:id": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "updateProduct",
+		Summary:     "Replace a product; price changes need products:price",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Body:        request{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"PATCH /products/:id": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "patchProduct",
+		Summary:     "Update some fields of a product; price changes need products:price",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Body:        domain.Product{},
+		PartialBody: true,
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"DELETE /products/:id": secured(PermProductsDelete, openapi.Spec{
+		OperationId: "deleteProduct",
+		Summary:     "Delete a product",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"PUT /products/:id/reorder": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "setReorder",
+		Summary:     "Set the reorder point and quantity of a product",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Body:        reorderRequest{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"GET /products/reorder": secured(PermProductsRead, openapi.Spec{
+		OperationId: "reorderReport",
+		Summary:     "Products at or below their reorder point",
+		Tags:        []string{"products"},
+		Response:    []domain.ReorderSuggestion{},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+
+	// graphql
+	"GET " + GraphQLPath: secured(PermProductsRead, openapi.Spec{
+		OperationId: "graphQLGet",
+		Summary:     "Run a graphql query over products and price quotes",
+		Tags:        []string{"graphql"},
+		Params: []openapi.Parameter{
+			{
+				Name:     "query",
+				In:       "query",
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:   "operationName",
+				In:     "query",
+				Schema: &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        "variables",
+				In:          "query",
+				Description: "json object of the query variables",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Response: graphQLResult,
+		Raw:      true,
+		Errors:   []int{http.StatusBadRequest},
+	}),
+	"POST " + GraphQLPath: secured(PermProductsRead, openapi.Spec{
+		OperationId: "graphQL",
+		Summary:     "Run a graphql query over products and price quotes",
+		Tags:        []string{"graphql"},
+		Body:        graphQLRequest{},
+		Response:    graphQLResult,
+		Raw:         true,
+		Errors:      []int{http.StatusBadRequest},
+	}),
+
+	// events
+	"GET " + EventsPath: secured(PermProductsRead, openapi.Spec{
+		OperationId: "streamProductEvents",
+		Summary:     "Stream product.created, product.updated and product.deleted events",
+		Tags:        []string{"events"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "Last-Event-ID",
+				In:          "header",
+				Description: "id of the last event received, to resume the stream",
+				Schema:      &openapi.Schema{Type: "string", Pattern: "^[0-9]+$"},
+			},
+		},
+		Response:     &openapi.Schema{Type: "string"},
+		ResponseType: "text/event-stream",
+		Errors:       []int{http.StatusBadRequest},
+	}),
+
+	// stock
+	"GET " + StockPath: identified(PermProductsRead, openapi.Spec{
+		OperationId: "subscribeStock",
+		Summary:     "Subscribe to the stock of products over a websocket",
+		Tags:        []string{"stock"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "Upgrade",
+				In:          "header",
+				Description: "websocket; clients then send {\"type\":\"subscribe\",\"ids\":[1]} and receive {\"type\":\"stock\",\"id\":1,\"quantity\":5}",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Status: http.StatusSwitchingProtocols,
+		Errors: []int{http.StatusBadRequest},
+	}),
+
+	// variants
+	"POST /products/:id/variants": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "createVariant",
+		Summary:     "Add a variant to a product",
+		Tags:        []string{"variants"},
+		Params:      intParams("id"),
+		Body:        variantRequest{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"PUT /products/:id/variants/:code": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "updateVariant",
+		Summary:     "Replace a variant; price changes need products:price",
+		Tags:        []string{"variants"},
+		Params:      intParams("id"),
+		Body:        variantRequest{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"DELETE /products/:id/variants/:code": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "deleteVariant",
+		Summary:     "Delete a variant",
+		Tags:        []string{"variants"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+
+	// media
+	"GET /media/*key": {
+		OperationId:  "getMedia",
+		Summary:      "Download a media file or thumbnail",
+		Tags:         []string{"media"},
+		Response:     &openapi.Schema{Type: "string", Format: "binary"},
+		ResponseType: "application/octet-stream",
+		Errors:       []int{http.StatusNotFound},
+	},
+	"POST /products/:id/media": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "uploadMedia",
+		Summary:     "Upload an image of a product",
+		Tags:        []string{"media"},
+		Params:      intParams("id"),
+		Body: &openapi.Schema{
+			Type:       "object",
+			Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+			Required:   []string{"file"},
+		},
+		BodyType: "multipart/form-data",
+		Status:   http.StatusCreated,
+		Response: domain.Media{},
+		Errors: []int{
+			http.StatusBadRequest,
+			http.StatusNotFound,
+			http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType,
+			http.StatusInternalServerError,
+		},
+	}),
+	"DELETE /products/:id/media/:mediaId": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "deleteMedia",
+		Summary:     "Delete an image of a product",
+		Tags:        []string{"media"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+
+	// attributes
+	"GET /attributes/": secured(PermProductsRead, openapi.Spec{
+		OperationId: "listAttributes",
+		Summary:     "List attribute definitions",
+		Tags:        []string{"attributes"},
+		Response:    []domain.Attribute{},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /attributes/:name": secured(PermProductsRead, openapi.Spec{
+		OperationId: "getAttribute",
+		Summary:     "Get an attribute definition",
+		Tags:        []string{"attributes"},
+		Response:    domain.Attribute{},
+		Errors:      []int{http.StatusNotFound},
+	}),
+	"POST /attributes/": secured(PermAttributesWrite, openapi.Spec{
+		OperationId: "createAttribute",
+		Summary:     "Define an attribute",
+		Tags:        []string{"attributes"},
+		Body:        domain.Attribute{},
+		Status:      http.StatusCreated,
+		Errors:      []int{http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"DELETE /attributes/:name": secured(PermAttributesWrite, openapi.Spec{
+		OperationId: "deleteAttribute",
+		Summary:     "Delete an attribute definition",
+		Tags:        []string{"attributes"},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusNotFound, http.StatusInternalServerError},
+	}),
+
+	// suppliers
+	"GET /suppliers/": secured(PermProductsRead, openapi.Spec{
+		OperationId: "listSuppliers",
+		Summary:     "List suppliers",
+		Tags:        []string{"suppliers"},
+		Params: []openapi.Parameter{
+			{
+				Name:        "product_id",
+				In:          "query",
+				Description: "only suppliers of the product",
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+		},
+		Response: []domain.Supplier{},
+		Errors:   []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"GET /suppliers/:id": secured(PermProductsRead, openapi.Spec{
+		OperationId: "getSupplier",
+		Summary:     "Get a supplier",
+		Tags:        []string{"suppliers"},
+		Params:      intParams("id"),
+		Response:    domain.Supplier{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	}),
+	"POST /suppliers/": secured(PermSuppliersWrite, openapi.Spec{
+		OperationId: "createSupplier",
+		Summary:     "Create a supplier",
+		Tags:        []string{"suppliers"},
+		Body:        supplierRequest{},
+		Status:      http.StatusCreated,
+		Response:    domain.Supplier{},
+		Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"PUT /suppliers/:id": secured(PermSuppliersWrite, openapi.Spec{
+		OperationId: "updateSupplier",
+		Summary:     "Replace a supplier",
+		Tags:        []string{"suppliers"},
+		Params:      intParams("id"),
+		Body:        supplierRequest{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"DELETE /suppliers/:id": secured(PermSuppliersWrite, openapi.Spec{
+		OperationId: "deleteSupplier",
+		Summary:     "Delete a supplier",
+		Tags:        []string{"suppliers"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"PUT /suppliers/:id/products/:productId": secured(PermSuppliersWrite, openapi.Spec{
+		OperationId: "linkSupplierProduct",
+		Summary:     "Link a product to a supplier with its cost and lead time",
+		Tags:        []string{"suppliers"},
+		Params:      intParams("id", "productId"),
+		Body:        supplierProductRequest{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"DELETE /suppliers/:id/products/:productId": secured(PermSuppliersWrite, openapi.Spec{
+		OperationId: "unlinkSupplierProduct",
+		Summary:     "Unlink a product from a supplier",
+		Tags:        []string{"suppliers"},
+		Params:      intParams("id", "productId"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+
+	// purchase orders
+	"GET /purchase_orders/": secured(PermProductsRead, openapi.Spec{
+		OperationId: "listPurchaseOrders",
+		Summary:     "List purchase orders",
+		Tags:        []string{"purchase orders"},
+		Response:    []domain.PurchaseOrder{},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /purchase_orders/:id": secured(PermProductsRead, openapi.Spec{
+		OperationId: "getPurchaseOrder",
+		Summary:     "Get a purchase order",
+		Tags:        []string{"purchase orders"},
+		Params:      intParams("id"),
+		Response:    domain.PurchaseOrder{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	}),
+	"GET /purchase_orders/:id/margins": secured(PermProductsRead, openapi.Spec{
+		OperationId: "purchaseOrderMargins",
+		Summary:     "Margins of the lines of a purchase order",
+		Tags:        []string{"purchase orders"},
+		Params:      intParams("id"),
+		Response:    marginsResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"POST /purchase_orders/": secured(PermOrdersWrite, openapi.Spec{
+		OperationId: "createPurchaseOrder",
+		Summary:     "Open a purchase order",
+		Tags:        []string{"purchase orders"},
+		Body:        purchaseOrderRequest{},
+		Status:      http.StatusCreated,
+		Response:    domain.PurchaseOrder{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"POST /purchase_orders/:id/receive": secured(PermOrdersWrite, openapi.Spec{
+		OperationId: "receivePurchaseOrder",
+		Summary:     "Receive a purchase order, adding its lines to stock",
+		Tags:        []string{"purchase orders"},
+		Params:      intParams("id"),
+		Response:    receiveResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+	}),
+	"POST /purchase_orders/:id/cancel": secured(PermOrdersWrite, openapi.Spec{
+		OperationId: "cancelPurchaseOrder",
+		Summary:     "Cancel an open purchase order",
+		Tags:        []string{"purchase orders"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+	}),
+
+	// api keys
+	"GET /apikeys/": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "listAPIKeys",
+		Summary:     "List api keys",
+		Tags:        []string{"api keys"},
+		Response:    []apiKeyResponse{},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /apikeys/:id": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "getAPIKey",
+		Summary:     "Get an api key",
+		Tags:        []string{"api keys"},
+		Params:      intParams("id"),
+		Response:    apiKeyResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	}),
+	"POST /apikeys/": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "createAPIKey",
+		Summary:     "Create an api key; the plain key is only returned here",
+		Tags:        []string{"api keys"},
+		Body:        apiKeyRequest{},
+		Status:      http.StatusCreated,
+		Response:    apiKeyResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"DELETE /apikeys/:id": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "revokeAPIKey",
+		Summary:     "Revoke an api key",
+		Tags:        []string{"api keys"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"POST /apikeys/:id/rotate": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "rotateAPIKey",
+		Summary:     "Replace an api key with a new one; the old key keeps working for grace_period seconds, a day by default",
+		Tags:        []string{"api keys"},
+		Params:      intParams("id"),
+		Body:        rotateRequest{},
+		Status:      http.StatusCreated,
+		Response:    apiKeyResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+	}),
+	"GET /apikeys/:id/usage": secured(PermAPIKeysManage, openapi.Spec{
+		OperationId: "apiKeyUsage",
+		Summary:     "Daily quota usage of an api key; also open to the key itself",
+		Tags:        []string{"api keys"},
+		Params:      intParams("id"),
+		Response:    ratelimit.Usage{},
+		Errors:      []int{http.StatusBadRequest},
+	}),
+
+	// webhooks
+	"GET /webhooks/": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "listWebhooks",
+		Summary:     "List webhooks",
+		Tags:        []string{"webhooks"},
+		Response:    []webhookResponse{},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /webhooks/:id": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "getWebhook",
+		Summary:     "Get a webhook",
+		Tags:        []string{"webhooks"},
+		Params:      intParams("id"),
+		Response:    webhookResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"POST /webhooks/": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "createWebhook",
+		Summary:     "Subscribe a url to product events; the secret is only returned here",
+		Tags:        []string{"webhooks"},
+		Body:        webhookRequest{},
+		Status:      http.StatusCreated,
+		Response:    webhookResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError},
+	}),
+	"DELETE /webhooks/:id": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "deleteWebhook",
+		Summary:     "Delete a webhook",
+		Tags:        []string{"webhooks"},
+		Params:      intParams("id"),
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"GET /webhooks/:id/deliveries": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "listWebhookDeliveries",
+		Summary:     "Delivery log of a webhook, newest first",
+		Tags:        []string{"webhooks"},
+		Params: append(intParams("id"), openapi.Parameter{
+			Name:   "status",
+			In:     "query",
+			Schema: &openapi.Schema{Type: "string", Enum: []any{"pending", "succeeded", "dead"}},
+		}),
+		Response: []domain.Delivery{},
+		Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+	}),
+	"POST /webhooks/:id/deliveries/:deliveryId/redeliver": secured(PermWebhooksManage, openapi.Spec{
+		OperationId: "redeliverWebhookDelivery",
+		Summary:     "Queue a dead or succeeded delivery again",
+		Tags:        []string{"webhooks"},
+		Params:      intParams("id", "deliveryId"),
+		Status:      http.StatusAccepted,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError},
+	}),
+}
+
+// productRoutes are served by every version, under its prefix.
+var productRoutes = []string{
+	"GET /products/",
+	"GET /products/:id",
+	"GET /products/search",
+	"GET /products/consumer_price",
+	"POST /products/",
+	"PUT /products/:id",
+	"PATCH /products/:id",
+	"DELETE /products/:id",
+	"POST /products/:id/variants",
+	"PUT /products/:id/variants/:code",
+	"DELETE /products/:id/variants/:code",
+	"PUT /products/:id/reorder",
+}
+
+// apiSpecsV2 documents the product routes whose shapes change in V2. The
+// other routes are documented as in V1.
+var apiSpecsV2 = map[string]openapi.Spec{
+	"GET /products/": secured(PermProductsRead, openapi.Spec{
+		OperationId: "listProducts",
+		Summary:     "List products, or export them as csv, ndjson or xml by the Accept header",
+		Tags:        []string{"products"},
+		Response:    []productV2{},
+		Negotiated:  []string{MIMECSV, MIMENDJSON, MIMEXML},
+		Errors:      []int{http.StatusInternalServerError},
+	}),
+	"GET /products/:id": secured(PermProductsRead, openapi.Spec{
+		OperationId: "getProduct",
+		Summary:     "Get a product",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Response:    productV2{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	}),
+	"GET /products/search":         withResponse(apiSpecs["GET /products/search"], []productV2{}),
+	"GET /products/consumer_price": withResponse(apiSpecs["GET /products/consumer_price"], consumerPriceResponseV2{}),
+	"POST /products/":              withBody(apiSpecs["POST /products/"], requestV2{}),
+	"PUT /products/:id":            withBody(apiSpecs["PUT /products/:id"], requestV2{}),
+	"PATCH /products/:id": secured(PermProductsUpdate, openapi.Spec{
+		OperationId: "patchProduct",
+		Summary:     "Update some fields of a product; price changes need products:price",
+		Tags:        []string{"products"},
+		Params:      intParams("id"),
+		Body:        patchV2{},
+		Status:      http.StatusNoContent,
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusInternalServerError},
+	}),
+	"POST /products/:id/variants":      withBody(apiSpecs["POST /products/:id/variants"], variantRequestV2{}),
+	"PUT /products/:id/variants/:code": withBody(apiSpecs["PUT /products/:id/variants/:code"], variantRequestV2{}),
+}
+
+// init documents the versioned product routes. The unversioned ones serve
+// V1 unless another version is negotiated.
+func init() {
+	for _, route := range productRoutes {
+		method, path, _ := strings.Cut(route, " ")
+
+		for _, v := range []Version{V1, V2} {
+			spec := apiSpecs[route]
+			if s, ok := apiSpecsV2[route]; ok && v == V2 {
+				spec = s
+			}
+
+			spec.OperationId += fmt.Sprintf("V%d", v)
+			spec.Deprecated = v < LatestVersion
+			apiSpecs[method+" "+v.prefix()+path] = spec
+		}
+
+		spec := apiSpecs[route]
+		spec.Deprecated = true
+		spec.Errors = append(spec.Errors[:len(spec.Errors):len(spec.Errors)], http.StatusNotAcceptable)
+		apiSpecs[route] = spec
+	}
+}
+
+func withResponse(s openapi.Spec, response any) openapi.Spec {
+	s.Response = response
+	return s
+}
+
+func withBody(s openapi.Spec, body any) openapi.Spec {
+	s.Body = body
+	return s
+}
+
+var explode = true
+
+// graphQLResult holds the data of the query, shaped by its selections, and
+// its errors.
+var graphQLResult = &openapi.Schema{
+	Type: "object",
+	Properties: map[string]*openapi.Schema{
+		"data": {Type: "object", Nullable: true, AdditionalProperties: true},
+		"errors": {
+			Type: "array",
+			Items: &openapi.Schema{
+				Type:                 "object",
+				Properties:           map[string]*openapi.Schema{"message": {Type: "string"}},
+				Required:             []string{"message"},
+				AdditionalProperties: true,
+			},
+		},
+	},
+}
+
+// secured sets the permission of the spec and documents the errors of the
+// auth middleware.
+func secured(permission string, s openapi.Spec) openapi.Spec {
+	s.Permission = permission
+	s.Anonymous = DefaultPolicy().Allowed([]string{RoleAnonymous}, permission)
+	s.Errors = append(s.Errors,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusTooManyRequests,
+	)
+
+	return s
+}
+
+// identified secures the operation like secured, without the anonymous
+// role.
+func identified(permission string, s openapi.Spec) openapi.Spec {
+	s = secured(permission, s)
+	s.Anonymous = false
+
+	return s
+}
+
+func intParams(names ...string) []openapi.Parameter {
+	params := make([]openapi.Parameter, 0, len(names))
+	for _, name := range names {
+		params = append(params, openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &openapi.Schema{Type: "integer"},
+		})
+	}
+
+	return params
+}
+
+// Routes returns the routes registered on mux.
+func Routes(mux *gin.Engine) []openapi.Route {
+	var routes []openapi.Route
+	for _, r := range mux.Routes() {
+		routes = append(routes, openapi.Route{Method: r.Method, Path: r.Path})
+	}
+
+	return routes
+}
+
+// OpenAPI documents the routes registered on mux.
+func OpenAPI(mux *gin.Engine) *openapi.Document {
+	g := openapi.New(
+		openapi.Info{
+			Title:       "goweb products api",
+			Version:     "1.0.0",
+			Description: "Products, variants, media, suppliers and purchase orders.",
+		},
+		openapi.Envelope("data"),
+		openapi.ErrorBody(web.ErrResponse(0, "", "")),
+		openapi.Security("bearerAuth", openapi.SecurityScheme{
+			Type:         "http",
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+		}),
+		openapi.Security("apiKey", openapi.SecurityScheme{
+			Type: "apiKey",
+			In:   "header",
+			Name: "X-API-Key",
+		}),
+	)
+
+	return g.Generate(Routes(mux), apiSpecs)
+}
+
+type openAPIHandler struct {
+	mux  *gin.Engine
+	once sync.Once
+	doc  []byte
+	err  error
+}
+
+// NewOpenAPI serves the document of the routes registered on mux and the
+// docs UI. The document is built on the first request, once every route
+// is registered.
+func NewOpenAPI(mux *gin.Engine) {
+	oh := &openAPIHandler{
+		mux: mux,
+	}
+
+	mux.GET(OpenAPIPath, oh.Spec)
+	mux.GET(DocsPath, oh.Docs)
+}
+
+func (oh *openAPIHandler) Spec(ctx *gin.Context) {
+	oh.once.Do(func() {
+		oh.doc, oh.err = json.Marshal(OpenAPI(oh.mux))
+	})
+
+	if oh.err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			oh.err.Error(),
+		))
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/json", oh.doc)
+}
+
+func (oh *openAPIHandler) Docs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", openapi.UI(OpenAPIPath))
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/openapi"
+)
+
+// apiMux registers every handler, as main does. Services are not called.
+func apiMux() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	auth := NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil)
+
+	NewProduct(mux, nil, auth)
+	NewAttribute(mux, nil, auth)
+	NewMedia(mux, nil, auth)
+	NewSupplier(mux, nil, auth)
+	NewPurchaseOrder(mux, nil, auth)
+	NewReorder(mux, nil, auth)
+	NewAPIKey(mux, nil, auth)
+	NewWebhook(mux, nil, auth)
+	NewUsage(mux, NewRateLimit(DefaultRateLimits()), auth)
+	NewGraphQL(mux, nil, auth)
+	NewEvents(mux, nil, auth)
+	NewStock(mux, nil, nil, auth)
+	NewOpenAPI(mux)
+
+	return mux
+}
+
+func TestOpenAPIDrift(t *testing.T) {
+	err := openapi.Drift(Routes(apiMux()), apiSpecs)
+	assert.NoError(t, err)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	mux := apiMux()
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var doc openapi.Document
+	err := json.Unmarshal(res.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+
+	// every route is served, with the same operation ids as the specs
+	for _, r := range Routes(mux) {
+		op := doc.Paths[openapi.Path(r.Path)][strings.ToLower(r.Method)]
+		if assert.NotNil(t, op, r.Method+" "+r.Path) {
+			assert.Equal(t, apiSpecs[r.Method+" "+r.Path].OperationId, op.OperationId)
+		}
+	}
+
+	// validation tags end up in the schemas
+	req := doc.Components.Schemas["Request"]
+	if assert.NotNil(t, req) {
+		assert.ElementsMatch(t, []string{"name", "quantity", "code_value", "expiration", "price"}, req.Required)
+		assert.Equal(t, "^[A-Z0-9]*$", req.Properties["code_value"].Pattern)
+		assert.Equal(t, 1.0, *req.Properties["quantity"].Minimum)
+	}
+
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, DocsPath, nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `data-spec="`+OpenAPIPath+`"`)
+}
+
+func TestOpenAPIIds(t *testing.T) {
+	seen := make(map[string]string)
+
+	for key, s := range apiSpecs {
+		assert.NotEmpty(t, s.OperationId, key)
+
+		if other, ok := seen[s.OperationId]; ok {
+			t.Errorf("operation id %s used by %s and %s", s.OperationId, other, key)
+		}
+		seen[s.OperationId] = key
+	}
+}
@@ -3,64 +3,118 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
 	producti "gituhb.com/juajosserand/goweb/internal/product"
 	"gituhb.com/juajosserand/goweb/pkg/storage"
 	"gituhb.com/juajosserand/goweb/pkg/web"
 )
 
 type product struct {
-	svc producti.ProductService
+	svc  producti.ProductService
+	auth *Auth
 }
 
-func NewProduct(mux *gin.Engine, s producti.ProductService) {
+// NewProduct registers the product routes of each version under its
+// prefix, e.g. /v2/products, defaulting to every version. The unversioned
+// /products routes keep serving V1.
+func NewProduct(mux *gin.Engine, s producti.ProductService, a *Auth, versions ...Version) {
 	ph := &product{
-		svc: s,
+		svc:  s,
+		auth: a,
 	}
 
 	mux.GET("/ping", ph.Pong)
 
-	productsMux := mux.Group("/products")
-	productsMux.GET("/", ph.GetAll)
-	productsMux.GET("/:id", ph.GetById)
-	productsMux.GET("/search", ph.Search)
-	productsMux.GET("/consumer_price", ph.ConsumerPrice)
+	if len(versions) == 0 {
+		versions = []Version{V1, V2}
+	}
+
+	ph.register(mux.Group("/products", a.Authenticate), V1)
+
+	// the batch routes are unversioned, their items have the V1 shape
+	mux.POST(BulkPath, a.Authenticate, a.Identified, ph.Bulk)
+	mux.POST(ImportPath, a.Authenticate, a.Authorize(PermProductsCreate), a.Authorize(PermProductsUpdate), ph.Import)
+
+	for _, v := range versions {
+		ph.register(mux.Group(v.prefix()+"/products", a.Authenticate), v)
+	}
+}
 
-	productsMux.Use(auth)
+// register adds the handlers of the version to productsMux.
+func (ph *product) register(productsMux *gin.RouterGroup, v Version) {
+	a := ph.auth
+
+	switch v {
+	case V1:
+		productsMux.GET("/", a.Authorize(PermProductsRead), ph.GetAll)
+		productsMux.GET("/:id", a.Authorize(PermProductsRead), ph.GetById)
+		productsMux.GET("/search", a.Authorize(PermProductsRead), ph.Search)
+		productsMux.GET("/consumer_price", a.Authorize(PermProductsRead), ph.ConsumerPrice)
+
+		productsMux.POST("/", a.Authorize(PermProductsCreate), ph.Create)
+		productsMux.PUT("/:id", a.Authorize(PermProductsUpdate), ph.Update)
+		productsMux.PATCH("/:id", a.Authorize(PermProductsUpdate), ph.PartialUpdate)
+		productsMux.POST("/:id/variants", a.Authorize(PermProductsUpdate), ph.CreateVariant)
+		productsMux.PUT("/:id/variants/:code", a.Authorize(PermProductsUpdate), ph.UpdateVariant)
+	case V2:
+		productsMux.GET("/", a.Authorize(PermProductsRead), ph.GetAllV2)
+		productsMux.GET("/:id", a.Authorize(PermProductsRead), ph.GetByIdV2)
+		productsMux.GET("/search", a.Authorize(PermProductsRead), ph.SearchV2)
+		productsMux.GET("/consumer_price", a.Authorize(PermProductsRead), ph.ConsumerPriceV2)
+
+		productsMux.POST("/", a.Authorize(PermProductsCreate), ph.CreateV2)
+		productsMux.PUT("/:id", a.Authorize(PermProductsUpdate), ph.UpdateV2)
+		productsMux.PATCH("/:id", a.Authorize(PermProductsUpdate), ph.PartialUpdateV2)
+		productsMux.POST("/:id/variants", a.Authorize(PermProductsUpdate), ph.CreateVariantV2)
+		productsMux.PUT("/:id/variants/:code", a.Authorize(PermProductsUpdate), ph.UpdateVariantV2)
+	default:
+		panic(fmt.Sprintf("handler: %s: %d", ErrUnsupportedVersion.Error(), v))
+	}
 
-	productsMux.POST("/", ph.Create)
-	productsMux.PUT("/:id", ph.Update)
-	productsMux.PATCH("/:id", ph.PartialUpdate)
-	productsMux.DELETE("/:id", ph.Delete)
+	// unchanged across versions
+	productsMux.DELETE("/:id", a.Authorize(PermProductsDelete), ph.Delete)
+	productsMux.DELETE("/:id/variants/:code", a.Authorize(PermProductsUpdate), ph.DeleteVariant)
+	productsMux.PUT("/:id/reorder", a.Authorize(PermProductsUpdate), ph.SetReorder)
+}
+
+type reorderRequest struct {
+	ReorderPoint    int `json:"reorder_point" binding:"gte=0"`
+	ReorderQuantity int `json:"reorder_quantity" binding:"gte=0"`
+}
+
+type consumerPriceResponse struct {
+	Products   []domain.Product `json:"products"`
+	Variants   []domain.Variant `json:"variants"`
+	TotalPrice float64          `json:"total_price"`
 }
 
 type request struct {
-	Name        string  `json:"name" binding:"required"`
-	Quantity    int     `json:"quantity" binding:"required,gte=1"`
-	CodeValue   string  `json:"code_value" binding:"required,uppercase,alphanum"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration" binding:"required"`
-	Price       float64 `json:"price" binding:"required,gte=0"`
+	Name        string         `json:"name" binding:"required"`
+	Quantity    int            `json:"quantity" binding:"required,gte=1"`
+	CodeValue   string         `json:"code_value" binding:"required,uppercase,alphanum"`
+	IsPublished bool           `json:"is_published"`
+	Expiration  string         `json:"expiration" binding:"required"`
+	Price       float64        `json:"price" binding:"required,gte=0"`
+	Attributes  map[string]any `json:"attributes"`
 }
 
-func auth(ctx *gin.Context) {
-	if ctx.GetHeader("token") != os.Getenv("TOKEN") {
-		ctx.JSON(http.StatusUnauthorized, web.ErrResponse(
-			http.StatusUnauthorized,
-			"Unauthorized",
-			"invalid token",
-		))
-		return
+// priceChangeAllowed reports whether the request may set the product price,
+// which needs its own permission when it changes.
+func (ph *product) priceChangeAllowed(ctx *gin.Context, id int, price float64) bool {
+	p, err := ph.svc.GetById(id)
+	if err != nil || p.Price == price {
+		return true
 	}
 
-	ctx.Next()
+	return ph.auth.Allowed(ctx, PermProductsPrice)
 }
 
 func (ph *product) Pong(ctx *gin.Context) {
@@ -68,6 +122,16 @@ func (ph *product) Pong(ctx *gin.Context) {
 }
 
 func (ph *product) GetAll(ctx *gin.Context) {
+	ps, ok := ph.all(ctx)
+	if !ok || ph.export(ctx, ps) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(ps))
+}
+
+// all returns every product, or writes the error response.
+func (ph *product) all(ctx *gin.Context) ([]domain.Product, bool) {
 	ps, err := ph.svc.All()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
@@ -75,13 +139,24 @@ func (ph *product) GetAll(ctx *gin.Context) {
 			"internal server error",
 			"internal server error",
 		))
-		return
+		return nil, false
 	}
 
-	ctx.JSON(http.StatusOK, web.Response(ps))
+	return ps, true
 }
 
 func (ph *product) GetById(ctx *gin.Context) {
+	p, ok := ph.getById(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusFound, web.Response(p))
+}
+
+// getById returns the product of the id param, or writes the error
+// response.
+func (ph *product) getById(ctx *gin.Context) (domain.Product, bool) {
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
@@ -89,7 +164,7 @@ func (ph *product) GetById(ctx *gin.Context) {
 			"bad request",
 			producti.ErrInvalidId.Error(),
 		))
-		return
+		return domain.Product{}, false
 	}
 
 	p, err := ph.svc.GetById(id)
@@ -102,24 +177,43 @@ func (ph *product) GetById(ctx *gin.Context) {
 				producti.ErrNotFound.Error(),
 			))
 		}
-		return
+		return domain.Product{}, false
 	}
 
-	ctx.JSON(http.StatusFound, web.Response(p))
+	return p, true
 }
 
 func (ph *product) Search(ctx *gin.Context) {
-	price, err := strconv.ParseFloat(ctx.Query("priceGt"), 64)
+	ps, ok := ph.search(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(ps))
+}
+
+// search returns the products matching the query, or writes the error
+// response.
+func (ph *product) search(ctx *gin.Context) ([]domain.Product, bool) {
+	// attribute filters, e.g. attributes[brand]=acme
+	attributes := ctx.QueryMap("attributes")
+
+	priceStr := ctx.Query("priceGt")
+	if priceStr == "" && len(attributes) > 0 {
+		priceStr = "0"
+	}
+
+	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
 			http.StatusBadRequest,
 			"bad request",
 			producti.ErrInvalidPrice.Error(),
 		))
-		return
+		return nil, false
 	}
 
-	ps, err := ph.svc.PriceGreaterThan(price)
+	ps, err := ph.svc.Search(price, attributes)
 	if err != nil {
 		switch {
 		case errors.Is(err, producti.ErrInvalidPrice):
@@ -128,11 +222,17 @@ func (ph *product) Search(ctx *gin.Context) {
 				"bad request",
 				producti.ErrInvalidPrice.Error(),
 			))
+		case errors.Is(err, producti.ErrInvalidAttributes):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				err.Error(),
+			))
 		}
-		return
+		return nil, false
 	}
 
-	ctx.JSON(http.StatusOK, web.Response(ps))
+	return ps, true
 }
 
 func (ph *product) Create(ctx *gin.Context) {
@@ -148,13 +248,19 @@ func (ph *product) Create(ctx *gin.Context) {
 		return
 	}
 
-	err = ph.svc.Create(
+	ph.create(ctx, r)
+}
+
+// create creates the product and writes the response.
+func (ph *product) create(ctx *gin.Context, r request) {
+	err := ph.svc.Create(
 		r.Name,
 		r.Quantity,
 		r.CodeValue,
 		r.IsPublished,
 		r.Expiration,
 		r.Price,
+		r.Attributes,
 	)
 	if err != nil {
 		switch {
@@ -165,6 +271,12 @@ func (ph *product) Create(ctx *gin.Context) {
 				producti.ErrInvalidData.Error(),
 			))
 			log.Println(err)
+		case errors.Is(err, producti.ErrInvalidAttributes):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				err.Error(),
+			))
 		case errors.Is(err, producti.ErrDuplicatedCodeValue):
 			ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
 				http.StatusUnprocessableEntity,
@@ -207,7 +319,17 @@ func (ph *product) Update(ctx *gin.Context) {
 		return
 	}
 
-	err = ph.svc.Update(
+	ph.update(ctx, id, r)
+}
+
+// update replaces the product and writes the response.
+func (ph *product) update(ctx *gin.Context, id int, r request) {
+	if !ph.priceChangeAllowed(ctx, id, r.Price) {
+		ph.auth.Deny(ctx, PermProductsPrice)
+		return
+	}
+
+	err := ph.svc.Update(
 		id,
 		r.Name,
 		r.Quantity,
@@ -215,6 +337,7 @@ func (ph *product) Update(ctx *gin.Context) {
 		r.IsPublished,
 		r.Expiration,
 		r.Price,
+		r.Attributes,
 	)
 	if err != nil {
 		switch {
@@ -224,6 +347,12 @@ func (ph *product) Update(ctx *gin.Context) {
 				"bad request",
 				producti.ErrInvalidData.Error(),
 			))
+		case errors.Is(err, producti.ErrInvalidAttributes):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				err.Error(),
+			))
 		case errors.Is(err, producti.ErrDuplicatedCodeValue):
 			ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
 				http.StatusUnprocessableEntity,
@@ -280,6 +409,11 @@ func (ph *product) PartialUpdate(ctx *gin.Context) {
 		return
 	}
 
+	if !ph.priceChangeAllowed(ctx, id, p.Price) {
+		ph.auth.Deny(ctx, PermProductsPrice)
+		return
+	}
+
 	err = ph.svc.Update(
 		p.Id,
 		p.Name,
@@ -288,6 +422,7 @@ func (ph *product) PartialUpdate(ctx *gin.Context) {
 		p.IsPublished,
 		p.Expiration,
 		p.Price,
+		p.Attributes,
 	)
 	if err != nil {
 		switch {
@@ -297,6 +432,12 @@ func (ph *product) PartialUpdate(ctx *gin.Context) {
 				"bad request",
 				producti.ErrInvalidData.Error(),
 			))
+		case errors.Is(err, producti.ErrInvalidAttributes):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				err.Error(),
+			))
 		case errors.Is(err, producti.ErrDuplicatedCodeValue):
 			ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
 				http.StatusUnprocessableEntity,
@@ -355,52 +496,134 @@ func (ph *product) Delete(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+func (ph *product) SetReorder(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r reorderRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	err = ph.svc.SetReorder(id, r.ReorderPoint, r.ReorderQuantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, producti.ErrInvalidData):
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				producti.ErrInvalidData.Error(),
+			))
+		case errors.Is(err, storage.ErrWriteFile):
+			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+				http.StatusInternalServerError,
+				"internal server error",
+				producti.ErrCreation.Error(),
+			))
+		case errors.Is(err, producti.ErrNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				producti.ErrNotFound.Error(),
+			))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
 func (ph *product) ConsumerPrice(ctx *gin.Context) {
+	res, ok := ph.consumerPrice(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(res))
+}
+
+// consumerPrice prices the list of the query, or writes the error response.
+func (ph *product) consumerPrice(ctx *gin.Context) (consumerPriceResponse, bool) {
 	// compile regex
-	r, err := regexp.Compile(`\[\d+(?:,\d+)*\]`)
+	r, err := regexp.Compile(`^\[\d+(?:,\d+)*\]$`)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
 			http.StatusInternalServerError,
 			"internal server error",
 			"unable to validate list of product ids",
 		))
-		return
+		return consumerPriceResponse{}, false
+	}
+
+	rv, err := regexp.Compile(`^\[[A-Z0-9]+(?:,[A-Z0-9]+)*\]$`)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"unable to validate list of variant codes",
+		))
+		return consumerPriceResponse{}, false
 	}
 
-	// validate list string
+	// validate list strings, at least one of them is required
 	listStr := ctx.Query("list")
+	variantsStr := ctx.Query("variants")
 
-	if !r.MatchString(listStr) {
+	if (listStr == "" && variantsStr == "") ||
+		(listStr != "" && !r.MatchString(listStr)) ||
+		(variantsStr != "" && !rv.MatchString(variantsStr)) {
 		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
 			http.StatusBadRequest,
 			"bad request",
 			producti.ErrInvalidConsumerPriceList.Error(),
 		))
-		return
+		return consumerPriceResponse{}, false
 	}
 
-	// parse ids
-	listStr = listStr[1 : len(listStr)-1]
-	split := strings.Split(listStr, ",")
-
 	// convert ids and count products
 	productQuantities := make(map[int]int)
 
-	for _, s := range split {
-		id, err := strconv.Atoi(s)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
-				http.StatusInternalServerError,
-				"internal server error",
-				producti.ErrInvalidConsumerPriceList.Error(),
-			))
+	if listStr != "" {
+		for _, s := range strings.Split(listStr[1:len(listStr)-1], ",") {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+					http.StatusBadRequest,
+					"bad request",
+					producti.ErrInvalidConsumerPriceList.Error(),
+				))
+				return consumerPriceResponse{}, false
+			}
+
+			productQuantities[id]++
 		}
+	}
+
+	// count variants
+	variantQuantities := make(map[string]int)
 
-		productQuantities[id]++
+	if variantsStr != "" {
+		for _, code := range strings.Split(variantsStr[1:len(variantsStr)-1], ",") {
+			variantQuantities[code]++
+		}
 	}
 
 	// compute total
-	total, products, err := ph.svc.CustomerPrice(productQuantities)
+	total, products, variants, err := ph.svc.CustomerPrice(productQuantities, variantQuantities)
 	if err != nil {
 		switch {
 		case errors.Is(err, producti.ErrNotFound):
@@ -409,6 +632,12 @@ func (ph *product) ConsumerPrice(ctx *gin.Context) {
 				"not found",
 				producti.ErrNotFound.Error(),
 			))
+		case errors.Is(err, producti.ErrVariantNotFound):
+			ctx.JSON(http.StatusNotFound, web.ErrResponse(
+				http.StatusNotFound,
+				"not found",
+				producti.ErrVariantNotFound.Error(),
+			))
 		case errors.Is(err, producti.ErrNoStock):
 			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
 				http.StatusBadRequest,
@@ -422,11 +651,12 @@ func (ph *product) ConsumerPrice(ctx *gin.Context) {
 				producti.ErrNotPublished.Error(),
 			))
 		}
-		return
+		return consumerPriceResponse{}, false
 	}
 
-	ctx.JSON(http.StatusOK, web.Response(gin.H{
-		"products":    products,
-		"total_price": total,
-	}))
+	return consumerPriceResponse{
+		Products:   products,
+		Variants:   variants,
+		TotalPrice: total,
+	}, true
 }
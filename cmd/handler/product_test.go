@@ -9,12 +9,14 @@ import (
 	"path"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
 	"gituhb.com/juajosserand/goweb/internal/domain"
 	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
 	"gituhb.com/juajosserand/goweb/pkg/web"
 )
 
@@ -27,6 +29,18 @@ var testProduct = &domain.Product{
 	Price:       100.0,
 }
 
+const testSecret = "test-secret"
+
+func bearer(role string) string {
+	token, _ := jwt.SignHS256(map[string]any{
+		"sub":  "test",
+		"role": role,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}, []byte(testSecret))
+
+	return "Bearer " + token
+}
+
 func init() {
 	_, filename, _, _ := runtime.Caller(0)
 	rootDir := path.Join(path.Dir(filename), "../..")
@@ -45,7 +59,7 @@ func arrange(method string, endpoint string, headers map[string]string, body []b
 
 	mux := gin.Default()
 	gin.SetMode(gin.ReleaseMode)
-	NewProduct(mux, svc)
+	NewProduct(mux, svc, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
 
 	req, err := http.NewRequest(method, endpoint, bytes.NewBuffer(body))
 	if err != nil {
@@ -96,7 +110,7 @@ func TestCreate(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	act, err := arrange(http.MethodPost, "/products/", map[string]string{"token": os.Getenv("TOKEN")}, bytes)
+	act, err := arrange(http.MethodPost, "/products/", map[string]string{"Authorization": bearer("admin")}, bytes)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -107,7 +121,7 @@ func TestCreate(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
-	act, err := arrange(http.MethodDelete, "/products/501", map[string]string{"token": os.Getenv("TOKEN")}, []byte(""))
+	act, err := arrange(http.MethodDelete, "/products/501", map[string]string{"Authorization": bearer("admin")}, []byte(""))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -135,21 +149,21 @@ func TestBadRequestErr(t *testing.T) {
 		{
 			http.MethodPut,
 			"/products/501",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			testProduct,
 			producti.ErrInvalidData.Error(),
 		},
 		{
 			http.MethodPatch,
 			"/products/501a",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			testProduct,
 			producti.ErrInvalidId.Error(),
 		},
 		{
 			http.MethodDelete,
 			"/products/501a",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			nil,
 			producti.ErrInvalidId.Error(),
 		},
@@ -199,19 +213,19 @@ func TestNotFoundErr(t *testing.T) {
 		{
 			http.MethodPut,
 			"/products/502",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			testProduct,
 		},
 		{
 			http.MethodPatch,
 			"/products/502",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			testProduct,
 		},
 		{
 			http.MethodDelete,
 			"/products/502",
-			map[string]string{"token": os.Getenv("TOKEN")},
+			map[string]string{"Authorization": bearer("admin")},
 			nil,
 		},
 	}
@@ -291,7 +305,55 @@ func TestUnauthorizedErr(t *testing.T) {
 			}
 
 			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
-			assert.Equal(t, "invalid token", r.Message)
+			assert.Equal(t, ErrMissingToken.Error(), r.Message)
+		})
+	}
+}
+
+func TestForbiddenErr(t *testing.T) {
+	tests := []struct {
+		method     string
+		endpoint   string
+		role       string
+		body       *domain.Product
+		permission string
+	}{
+		{
+			http.MethodPost,
+			"/products/",
+			"viewer",
+			testProduct,
+			PermProductsCreate,
+		},
+		{
+			http.MethodDelete,
+			"/products/1",
+			"editor",
+			nil,
+			PermProductsDelete,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("TestForbiddenErr"+test.method, func(t *testing.T) {
+			bytes, err := json.Marshal(test.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := arrange(test.method, test.endpoint, map[string]string{"Authorization": bearer(test.role)}, bytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res := act()
+			r := web.ErrResponse(0, "", "")
+			if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+			assert.Contains(t, r.Message, test.permission)
 		})
 	}
 }
@@ -0,0 +1,467 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/openapi"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+// Currency of the product prices; the store keeps amounts only.
+const Currency = "USD"
+
+const (
+	expirationLayout = "02/01/2006"
+	dateLayout       = "2006-01-02"
+)
+
+// date is a calendar date, e.g. 2023-01-20.
+type date string
+
+func (date) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Format: "date"}
+}
+
+func newDate(expiration string) date {
+	t, err := time.Parse(expirationLayout, expiration)
+	if err != nil {
+		return date(expiration)
+	}
+
+	return date(t.Format(dateLayout))
+}
+
+// expiration returns the date in the layout of the store.
+func (d date) expiration() (string, error) {
+	t, err := time.Parse(dateLayout, string(d))
+	if err != nil {
+		return "", err
+	}
+
+	return t.Format(expirationLayout), nil
+}
+
+// money is an amount of a currency. Amounts are decimal strings holding the
+// stored amount exactly, e.g. "100.5", so that clients don't lose precision
+// parsing them as binary floats.
+type money struct {
+	Amount   string `json:"amount" binding:"required,numeric"`
+	Currency string `json:"currency" binding:"required,oneof=USD"`
+}
+
+func newMoney(amount float64) money {
+	return money{
+		Amount:   strconv.FormatFloat(amount, 'f', -1, 64),
+		Currency: Currency,
+	}
+}
+
+func (m money) value() (float64, error) {
+	// zero prices are rejected as in v1
+	v, err := strconv.ParseFloat(m.Amount, 64)
+	if err != nil || v <= 0 || m.Currency != Currency {
+		return 0, producti.ErrInvalidData
+	}
+
+	return v, nil
+}
+
+type productV2 struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name"`
+	Quantity    int            `json:"quantity"`
+	CodeValue   string         `json:"code_value"`
+	IsPublished bool           `json:"is_published"`
+	Expiration  date           `json:"expiration"`
+	Price       money          `json:"price"`
+	Variants    []variantV2    `json:"variants,omitempty"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	Media       []domain.Media `json:"media,omitempty"`
+
+	ReorderPoint    int `json:"reorder_point,omitempty"`
+	ReorderQuantity int `json:"reorder_quantity,omitempty"`
+}
+
+type variantV2 struct {
+	CodeValue  string                   `json:"code_value"`
+	Price      money                    `json:"price"`
+	Quantity   int                      `json:"quantity"`
+	Attributes domain.VariantAttributes `json:"attributes"`
+}
+
+func newProductV2(p domain.Product) productV2 {
+	return productV2{
+		Id:              p.Id,
+		Name:            p.Name,
+		Quantity:        p.Quantity,
+		CodeValue:       p.CodeValue,
+		IsPublished:     p.IsPublished,
+		Expiration:      newDate(p.Expiration),
+		Price:           newMoney(p.Price),
+		Variants:        newVariantsV2(p.Variants),
+		Attributes:      p.Attributes,
+		Media:           p.Media,
+		ReorderPoint:    p.ReorderPoint,
+		ReorderQuantity: p.ReorderQuantity,
+	}
+}
+
+func newProductsV2(ps []domain.Product) []productV2 {
+	res := make([]productV2, 0, len(ps))
+	for _, p := range ps {
+		res = append(res, newProductV2(p))
+	}
+
+	return res
+}
+
+func newVariantsV2(vs []domain.Variant) []variantV2 {
+	if vs == nil {
+		return nil
+	}
+
+	res := make([]variantV2, 0, len(vs))
+	for _, v := range vs {
+		res = append(res, variantV2{
+			CodeValue:  v.CodeValue,
+			Price:      newMoney(v.Price),
+			Quantity:   v.Quantity,
+			Attributes: v.Attributes,
+		})
+	}
+
+	return res
+}
+
+type requestV2 struct {
+	Name        string         `json:"name" binding:"required"`
+	Quantity    int            `json:"quantity" binding:"required,gte=1"`
+	CodeValue   string         `json:"code_value" binding:"required,uppercase,alphanum"`
+	IsPublished bool           `json:"is_published"`
+	Expiration  date           `json:"expiration" binding:"required,datetime=2006-01-02"`
+	Price       money          `json:"price" binding:"required"`
+	Attributes  map[string]any `json:"attributes"`
+}
+
+// v1 converts the request to the shape of the service.
+func (r requestV2) v1() (request, error) {
+	expiration, err := r.Expiration.expiration()
+	if err != nil {
+		return request{}, producti.ErrInvalidData
+	}
+
+	price, err := r.Price.value()
+	if err != nil {
+		return request{}, err
+	}
+
+	return request{
+		Name:        r.Name,
+		Quantity:    r.Quantity,
+		CodeValue:   r.CodeValue,
+		IsPublished: r.IsPublished,
+		Expiration:  expiration,
+		Price:       price,
+		Attributes:  r.Attributes,
+	}, nil
+}
+
+// patchV2 holds the fields to change, unlike v1 where the body is decoded
+// over the stored product.
+type patchV2 struct {
+	Name        *string        `json:"name"`
+	Quantity    *int           `json:"quantity" binding:"omitempty,gte=1"`
+	CodeValue   *string        `json:"code_value" binding:"omitempty,uppercase,alphanum"`
+	IsPublished *bool          `json:"is_published"`
+	Expiration  *date          `json:"expiration" binding:"omitempty,datetime=2006-01-02"`
+	Price       *money         `json:"price"`
+	Attributes  map[string]any `json:"attributes"`
+}
+
+// apply returns the request replacing p with the patched fields.
+func (r patchV2) apply(p domain.Product) (request, error) {
+	req := request{
+		Name:        p.Name,
+		Quantity:    p.Quantity,
+		CodeValue:   p.CodeValue,
+		IsPublished: p.IsPublished,
+		Expiration:  p.Expiration,
+		Price:       p.Price,
+		Attributes:  p.Attributes,
+	}
+
+	if r.Name != nil {
+		req.Name = *r.Name
+	}
+
+	if r.Quantity != nil {
+		req.Quantity = *r.Quantity
+	}
+
+	if r.CodeValue != nil {
+		req.CodeValue = *r.CodeValue
+	}
+
+	if r.IsPublished != nil {
+		req.IsPublished = *r.IsPublished
+	}
+
+	if r.Expiration != nil {
+		expiration, err := r.Expiration.expiration()
+		if err != nil {
+			return request{}, producti.ErrInvalidData
+		}
+		req.Expiration = expiration
+	}
+
+	if r.Price != nil {
+		price, err := r.Price.value()
+		if err != nil {
+			return request{}, err
+		}
+		req.Price = price
+	}
+
+	if r.Attributes != nil {
+		req.Attributes = r.Attributes
+	}
+
+	return req, nil
+}
+
+type variantRequestV2 struct {
+	CodeValue  string                   `json:"code_value" binding:"required,uppercase,alphanum"`
+	Price      money                    `json:"price" binding:"required"`
+	Quantity   int                      `json:"quantity" binding:"gte=0"`
+	Attributes domain.VariantAttributes `json:"attributes"`
+}
+
+func (r variantRequestV2) v1() (variantRequest, error) {
+	price, err := r.Price.value()
+	if err != nil {
+		return variantRequest{}, err
+	}
+
+	return variantRequest{
+		CodeValue:  r.CodeValue,
+		Price:      price,
+		Quantity:   r.Quantity,
+		Attributes: r.Attributes,
+	}, nil
+}
+
+type consumerPriceResponseV2 struct {
+	Products   []productV2 `json:"products"`
+	Variants   []variantV2 `json:"variants"`
+	TotalPrice money       `json:"total_price"`
+}
+
+func (ph *product) GetAllV2(ctx *gin.Context) {
+	ps, ok := ph.all(ctx)
+	if !ok || ph.export(ctx, ps) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(newProductsV2(ps)))
+}
+
+func (ph *product) GetByIdV2(ctx *gin.Context) {
+	p, ok := ph.getById(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(newProductV2(p)))
+}
+
+func (ph *product) SearchV2(ctx *gin.Context) {
+	ps, ok := ph.search(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(newProductsV2(ps)))
+}
+
+func (ph *product) ConsumerPriceV2(ctx *gin.Context) {
+	res, ok := ph.consumerPrice(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(consumerPriceResponseV2{
+		Products:   newProductsV2(res.Products),
+		Variants:   newVariantsV2(res.Variants),
+		TotalPrice: newMoney(res.TotalPrice),
+	}))
+}
+
+func (ph *product) CreateV2(ctx *gin.Context) {
+	var r requestV2
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	req, err := r.v1()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.create(ctx, req)
+}
+
+func (ph *product) UpdateV2(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r requestV2
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	req, err := r.v1()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.update(ctx, id, req)
+}
+
+func (ph *product) PartialUpdateV2(ctx *gin.Context) {
+	var r patchV2
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	p, ok := ph.getById(ctx)
+	if !ok {
+		return
+	}
+
+	req, err := r.apply(p)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.update(ctx, p.Id, req)
+}
+
+func (ph *product) CreateVariantV2(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r variantRequestV2
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	req, err := r.v1()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.createVariant(ctx, id, req)
+}
+
+func (ph *product) UpdateVariantV2(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r variantRequestV2
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	req, err := r.v1()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.updateVariant(ctx, id, ctx.Param("code"), req)
+}
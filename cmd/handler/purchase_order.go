@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/internal/purchase"
+	"gituhb.com/juajosserand/goweb/internal/supplier"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type purchaseOrderHandler struct {
+	svc purchase.PurchaseOrderService
+}
+
+func NewPurchaseOrder(mux *gin.Engine, s purchase.PurchaseOrderService, a *Auth) {
+	oh := &purchaseOrderHandler{
+		svc: s,
+	}
+
+	ordersMux := mux.Group("/purchase_orders", a.Authenticate)
+	ordersMux.GET("/", a.Authorize(PermProductsRead), oh.GetAll)
+	ordersMux.GET("/:id", a.Authorize(PermProductsRead), oh.GetById)
+	ordersMux.GET("/:id/margins", a.Authorize(PermProductsRead), oh.Margins)
+
+	ordersMux.POST("/", a.Authorize(PermOrdersWrite), oh.Create)
+	ordersMux.POST("/:id/receive", a.Authorize(PermOrdersWrite), oh.Receive)
+	ordersMux.POST("/:id/cancel", a.Authorize(PermOrdersWrite), oh.Cancel)
+}
+
+type purchaseOrderRequest struct {
+	SupplierId int                        `json:"supplier_id" binding:"required"`
+	Lines      []purchaseOrderLineRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+type purchaseOrderLineRequest struct {
+	ProductId int     `json:"product_id" binding:"required"`
+	Quantity  int     `json:"quantity" binding:"required,gte=1"`
+	CostPrice float64 `json:"cost_price" binding:"gte=0"`
+}
+
+type marginsResponse struct {
+	Margins     []domain.Margin `json:"margins"`
+	TotalMargin float64         `json:"total_margin"`
+}
+
+type receiveResponse struct {
+	PurchaseOrder domain.PurchaseOrder `json:"purchase_order"`
+	Margins       []domain.Margin      `json:"margins"`
+	TotalMargin   float64              `json:"total_margin"`
+}
+
+func (oh *purchaseOrderHandler) GetAll(ctx *gin.Context) {
+	pos, err := oh.svc.All()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(pos))
+}
+
+func (oh *purchaseOrderHandler) GetById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	o, err := oh.svc.GetById(id)
+	if err != nil {
+		purchaseOrderErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(o))
+}
+
+func (oh *purchaseOrderHandler) Margins(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	ms, err := oh.svc.Margins(id)
+	if err != nil {
+		purchaseOrderErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(marginsResponse{
+		Margins:     ms,
+		TotalMargin: totalMargin(ms),
+	}))
+}
+
+func (oh *purchaseOrderHandler) Create(ctx *gin.Context) {
+	var r purchaseOrderRequest
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	lines := make([]domain.PurchaseOrderLine, 0, len(r.Lines))
+	for _, l := range r.Lines {
+		lines = append(lines, domain.PurchaseOrderLine{
+			ProductId: l.ProductId,
+			Quantity:  l.Quantity,
+			CostPrice: l.CostPrice,
+		})
+	}
+
+	o, err := oh.svc.Create(r.SupplierId, lines)
+	if err != nil {
+		purchaseOrderErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, web.Response(o))
+}
+
+func (oh *purchaseOrderHandler) Receive(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	o, ms, err := oh.svc.Receive(id)
+	if err != nil {
+		purchaseOrderErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(receiveResponse{
+		PurchaseOrder: o,
+		Margins:       ms,
+		TotalMargin:   totalMargin(ms),
+	}))
+}
+
+func (oh *purchaseOrderHandler) Cancel(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = oh.svc.Cancel(id)
+	if err != nil {
+		purchaseOrderErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func totalMargin(ms []domain.Margin) (total float64) {
+	for _, m := range ms {
+		total += m.Margin
+	}
+
+	return
+}
+
+func purchaseOrderErr(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, purchase.ErrInvalidData):
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			purchase.ErrInvalidData.Error(),
+		))
+	case errors.Is(err, purchase.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			purchase.ErrNotFound.Error(),
+		))
+	case errors.Is(err, supplier.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			supplier.ErrNotFound.Error(),
+		))
+	case errors.Is(err, producti.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			producti.ErrNotFound.Error(),
+		))
+	case errors.Is(err, purchase.ErrProductNotLinked):
+		ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
+			http.StatusUnprocessableEntity,
+			"unprocessable entity",
+			err.Error(),
+		))
+	case errors.Is(err, purchase.ErrNotOpen):
+		ctx.JSON(http.StatusConflict, web.ErrResponse(
+			http.StatusConflict,
+			"conflict",
+			purchase.ErrNotOpen.Error(),
+		))
+	case errors.Is(err, purchase.ErrReceive):
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			purchase.ErrReceive.Error(),
+		))
+	case errors.Is(err, storage.ErrWriteFile):
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			purchase.ErrCreation.Error(),
+		))
+	}
+}
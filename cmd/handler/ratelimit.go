@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/ratelimit"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+var (
+	ErrRateLimited   = errors.New("rate limit exceeded")
+	ErrQuotaExceeded = errors.New("daily quota exceeded")
+)
+
+// DefaultRateLimits is used when no rate limit file is configured.
+func DefaultRateLimits() *ratelimit.Config {
+	return &ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 120, Period: time.Minute},
+		Routes: map[string]ratelimit.Limit{
+			"GET /products/consumer_price": {Requests: 20, Period: time.Minute},
+		},
+	}
+}
+
+// RateLimit limits requests per client with token buckets and counts the
+// daily quota of api keys.
+type RateLimit struct {
+	config  *ratelimit.Config
+	limiter *ratelimit.Limiter
+	quota   *ratelimit.Quota
+}
+
+func NewRateLimit(c *ratelimit.Config) *RateLimit {
+	return &RateLimit{
+		config:  c,
+		limiter: ratelimit.NewLimiter(),
+		quota:   ratelimit.NewQuota(),
+	}
+}
+
+// Limit rejects the request with 429 when the client ran out of tokens or
+// of daily quota, and reports whether it may go on. Clients are api keys,
+// or client ips otherwise. Routes are limited without their version prefix,
+// so that every version of a route shares its limit.
+func (rl *RateLimit) Limit(ctx *gin.Context) bool {
+	k, isKey := ctx.Value(APIKeyKey).(domain.APIKey)
+
+	var key *domain.APIKey
+	if isKey {
+		key = &k
+	}
+
+	client := rateClient(ctx.ClientIP(), key)
+
+	r, retryAfter, err := rl.allow(client, key, ctx.Request.Method, unversioned(ctx.FullPath()))
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(r.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	ctx.Header("RateLimit-Reset", seconds(r.Reset))
+
+	if err != nil {
+		securityEvent(ctx, limitEvent(err), client)
+		tooManyRequests(ctx, retryAfter, err)
+		return false
+	}
+
+	return true
+}
+
+// LimitCall counts a grpc call against the limits of the http route it
+// serves, returning ErrRateLimited or ErrQuotaExceeded when it may not go
+// on.
+func (rl *RateLimit) LimitCall(ip string, key *domain.APIKey, method string, path string) error {
+	_, _, err := rl.allow(rateClient(ip, key), key, method, path)
+	return err
+}
+
+// allow takes a token of the client for the route and counts the daily
+// quota of api keys. The error comes with the time to wait before retrying.
+func (rl *RateLimit) allow(client string, key *domain.APIKey, method string, path string) (ratelimit.Result, time.Duration, error) {
+	limit, own := rl.config.Route(method, path)
+
+	bucket := client
+	if own {
+		bucket = client + " " + method + " " + path
+	}
+
+	r := rl.limiter.Allow(bucket, limit)
+	if !r.Allowed {
+		return r, r.RetryAfter, ErrRateLimited
+	}
+
+	if key != nil {
+		u, ok := rl.quota.Use(client, rl.config.Quota(key.Id))
+		if !ok {
+			return r, time.Until(u.Reset), ErrQuotaExceeded
+		}
+	}
+
+	return r, 0, nil
+}
+
+// rateClient names the client limits are counted for, the api key or the
+// client ip otherwise.
+func rateClient(ip string, key *domain.APIKey) string {
+	if key != nil {
+		return fmt.Sprintf("key:%d", key.Id)
+	}
+
+	return "ip:" + ip
+}
+
+// limitEvent names the security event of a limit error.
+func limitEvent(err error) string {
+	if errors.Is(err, ErrQuotaExceeded) {
+		return "quota_exceeded"
+	}
+
+	return "rate_limited"
+}
+
+// Usage returns the daily quota usage of the api key.
+func (rl *RateLimit) Usage(keyId int) ratelimit.Usage {
+	return rl.quota.Usage(fmt.Sprintf("key:%d", keyId), rl.config.Quota(keyId))
+}
+
+type usageHandler struct {
+	rl   *RateLimit
+	auth *Auth
+}
+
+// NewUsage registers the quota usage endpoint, open to key managers and to
+// the key itself.
+func NewUsage(mux *gin.Engine, rl *RateLimit, a *Auth) {
+	uh := &usageHandler{
+		rl:   rl,
+		auth: a,
+	}
+
+	mux.GET("/apikeys/:id/usage", a.Authenticate, a.Identified, uh.Get)
+}
+
+func (uh *usageHandler) Get(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			apikey.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	k, isKey := ctx.Value(APIKeyKey).(domain.APIKey)
+	if !(isKey && k.Id == id) && !uh.auth.Allowed(ctx, PermAPIKeysManage) {
+		uh.auth.Deny(ctx, PermAPIKeysManage)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(uh.rl.Usage(id)))
+}
+
+func tooManyRequests(ctx *gin.Context, retryAfter time.Duration, err error) {
+	ctx.Header("Retry-After", seconds(retryAfter))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, web.ErrResponse(
+		http.StatusTooManyRequests,
+		"too many requests",
+		err.Error(),
+	))
+}
+
+// seconds formats the duration as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/reorder"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type reorderHandler struct {
+	svc reorder.ReorderService
+}
+
+func NewReorder(mux *gin.Engine, s reorder.ReorderService, a *Auth) {
+	rh := &reorderHandler{
+		svc: s,
+	}
+
+	mux.GET("/products/reorder", a.Authenticate, a.Authorize(PermProductsRead), rh.Report)
+}
+
+func (rh *reorderHandler) Report(ctx *gin.Context) {
+	rs, err := rh.svc.Report()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(rs))
+}
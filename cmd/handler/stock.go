@@ -0,0 +1,363 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/events"
+)
+
+const StockPath = "/products/stock"
+
+const (
+	defaultPingInterval = 30 * time.Second
+	stockWriteWait      = 10 * time.Second
+
+	maxStockSubscriptions = 100
+	maxStockRequestSize   = 4096
+	maxStockErrors        = 10
+
+	// unknownQuantity marks the products subscribed but not yet read.
+	unknownQuantity = -1
+)
+
+var (
+	ErrTooManySubscriptions = errors.New("too many subscribed products")
+	ErrInvalidStockRequest  = errors.New("invalid stock request")
+)
+
+type stockHandler struct {
+	svc          producti.ProductService
+	bus          *events.Bus
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+}
+
+type StockOption func(*stockHandler)
+
+// PingInterval pings the clients every d. Clients missing two pings in a
+// row are disconnected.
+func PingInterval(d time.Duration) StockOption {
+	return func(sh *stockHandler) {
+		if d > 0 {
+			sh.pingInterval = d
+		}
+	}
+}
+
+// NewStock serves websocket subscriptions to the stock of products, fed by
+// the changes published on bus. Like the write routes it needs a bearer
+// token or api key, besides the permission to read products.
+func NewStock(mux *gin.Engine, s producti.ProductService, bus *events.Bus, a *Auth, ops ...StockOption) {
+	sh := &stockHandler{
+		svc:          s,
+		bus:          bus,
+		pingInterval: defaultPingInterval,
+	}
+
+	for _, op := range ops {
+		op(sh)
+	}
+
+	mux.GET(StockPath, a.Authenticate, a.Identified, a.Authorize(PermProductsRead), sh.Subscribe)
+}
+
+// stockRequest subscribes to or unsubscribes from the products.
+type stockRequest struct {
+	Type string `json:"type" binding:"required,oneof=subscribe unsubscribe"`
+	Ids  []int  `json:"ids" binding:"required,min=1,dive,gt=0"`
+}
+
+// stockMessage is the current quantity of a product, or tells that it was
+// deleted or that a request failed.
+type stockMessage struct {
+	Type     string `json:"type"`
+	Id       int    `json:"id,omitempty"`
+	Quantity int    `json:"quantity"`
+	Message  string `json:"message,omitempty"`
+}
+
+const (
+	stockMessageStock   = "stock"
+	stockMessageDeleted = "deleted"
+	stockMessageError   = "error"
+)
+
+// Subscribe upgrades the connection and sends the quantity of each
+// subscribed product, then its changes. Slow clients only get the latest
+// quantity of each product, and are disconnected when a write times out.
+func (sh *stockHandler) Subscribe(ctx *gin.Context) {
+	ws, err := sh.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// the upgrader already replied
+		return
+	}
+	defer ws.Close()
+
+	sub, _ := sh.bus.Subscribe(0)
+	defer sub.Close()
+
+	c := &stockConn{
+		ws:      ws,
+		svc:     sh.svc,
+		ids:     make(map[int]int),
+		pending: make(map[int]stockMessage),
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	go c.read(2 * sh.pingInterval)
+	go c.consume(sub)
+
+	c.write(sh.pingInterval)
+}
+
+// stockConn holds the subscriptions of a connection and the messages not
+// yet written, at most one per product.
+type stockConn struct {
+	ws  *websocket.Conn
+	svc producti.ProductService
+
+	mu      sync.Mutex
+	ids     map[int]int
+	pending map[int]stockMessage
+	errs    []stockMessage
+	notify  chan struct{}
+
+	once      sync.Once
+	done      chan struct{}
+	closeCode int
+}
+
+// read handles the requests of the client until it goes away or misses
+// the pings for wait.
+func (c *stockConn) read(wait time.Duration) {
+	c.ws.SetReadLimit(maxStockRequestSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(wait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(wait))
+	})
+
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			c.close(websocket.CloseNormalClosure)
+			return
+		}
+
+		var r stockRequest
+
+		err = json.Unmarshal(msg, &r)
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&r)
+		}
+
+		if err != nil {
+			c.fail(0, ErrInvalidStockRequest)
+			continue
+		}
+
+		switch r.Type {
+		case "subscribe":
+			c.subscribe(r.Ids)
+		case "unsubscribe":
+			c.unsubscribe(r.Ids)
+		}
+	}
+}
+
+// subscribe queues the current quantity of the products. They are
+// subscribed before being read, so that no change is missed meanwhile.
+func (c *stockConn) subscribe(ids []int) {
+	c.mu.Lock()
+	n := len(c.ids)
+	for _, id := range ids {
+		if _, ok := c.ids[id]; !ok {
+			n++
+		}
+	}
+
+	if n > maxStockSubscriptions {
+		c.mu.Unlock()
+		c.fail(0, ErrTooManySubscriptions)
+		return
+	}
+
+	for _, id := range ids {
+		if _, ok := c.ids[id]; !ok {
+			c.ids[id] = unknownQuantity
+		}
+	}
+	c.mu.Unlock()
+
+	for _, id := range ids {
+		p, err := c.svc.GetById(id)
+
+		c.mu.Lock()
+		quantity, ok := c.ids[id]
+		switch {
+		case !ok:
+			// unsubscribed meanwhile
+		case err != nil:
+			delete(c.ids, id)
+		case quantity == unknownQuantity:
+			c.ids[id] = p.Quantity
+			c.pending[id] = stockMessage{Type: stockMessageStock, Id: id, Quantity: p.Quantity}
+		default:
+			c.pending[id] = stockMessage{Type: stockMessageStock, Id: id, Quantity: quantity}
+		}
+		c.mu.Unlock()
+
+		if ok && err != nil {
+			c.fail(id, err)
+		}
+	}
+
+	c.signal()
+}
+
+func (c *stockConn) unsubscribe(ids []int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range ids {
+		delete(c.ids, id)
+		delete(c.pending, id)
+	}
+}
+
+// consume queues the quantity changes of the subscribed products. Changes
+// replace the ones not yet written.
+func (c *stockConn) consume(sub *events.Subscription) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case e, ok := <-sub.C:
+			// the bus dropped the subscription, the client reconnects
+			if !ok {
+				c.close(websocket.CloseTryAgainLater)
+				return
+			}
+			c.apply(e)
+		}
+	}
+}
+
+func (c *stockConn) apply(e events.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch data := e.Data.(type) {
+	case domain.Product:
+		quantity, ok := c.ids[data.Id]
+		if !ok || quantity == data.Quantity {
+			return
+		}
+
+		c.ids[data.Id] = data.Quantity
+		c.pending[data.Id] = stockMessage{Type: stockMessageStock, Id: data.Id, Quantity: data.Quantity}
+	case producti.Deleted:
+		if _, ok := c.ids[data.Id]; !ok {
+			return
+		}
+
+		delete(c.ids, data.Id)
+		c.pending[data.Id] = stockMessage{Type: stockMessageDeleted, Id: data.Id}
+	default:
+		return
+	}
+
+	c.signal()
+}
+
+// fail queues an error message, keeping the last few only.
+func (c *stockConn) fail(id int, err error) {
+	c.mu.Lock()
+	if len(c.errs) == maxStockErrors {
+		c.errs = c.errs[1:]
+	}
+	c.errs = append(c.errs, stockMessage{Type: stockMessageError, Id: id, Message: err.Error()})
+	c.mu.Unlock()
+
+	c.signal()
+}
+
+func (c *stockConn) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (c *stockConn) close(code int) {
+	c.once.Do(func() {
+		c.closeCode = code
+		close(c.done)
+	})
+}
+
+// write sends the queued messages and the pings until the connection is
+// closed. It is the only writer of the connection.
+func (c *stockConn) write(pingInterval time.Duration) {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.ws.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, ""),
+				time.Now().Add(stockWriteWait),
+			)
+			return
+		case <-ping.C:
+			err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(stockWriteWait))
+			if err != nil {
+				return
+			}
+		case <-c.notify:
+			err := c.flush()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// flush writes the errors, then the quantities by product id.
+func (c *stockConn) flush() error {
+	c.mu.Lock()
+	msgs := c.errs
+	quantities := make([]stockMessage, 0, len(c.pending))
+	for _, m := range c.pending {
+		quantities = append(quantities, m)
+	}
+	c.errs = nil
+	c.pending = make(map[int]stockMessage)
+	c.mu.Unlock()
+
+	sort.Slice(quantities, func(i, j int) bool {
+		return quantities[i].Id < quantities[j].Id
+	})
+
+	for _, m := range append(msgs, quantities...) {
+		_ = c.ws.SetWriteDeadline(time.Now().Add(stockWriteWait))
+
+		err := c.ws.WriteJSON(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/events"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+type stockProducts struct {
+	producti.ProductService
+	ps map[int]domain.Product
+}
+
+func (s *stockProducts) GetById(id int) (domain.Product, error) {
+	p, ok := s.ps[id]
+	if !ok {
+		return domain.Product{}, producti.ErrNotFound
+	}
+
+	return p, nil
+}
+
+func stockServer(bus *events.Bus) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewStock(mux, &stockProducts{ps: map[int]domain.Product{
+		1: {Id: 1, Quantity: 5},
+		2: {Id: 2, Quantity: 7},
+	}}, bus, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil), PingInterval(time.Second))
+
+	return httptest.NewServer(mux)
+}
+
+func dialStock(t *testing.T, srv *httptest.Server, token string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", token)
+	}
+
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+StockPath, header)
+}
+
+func readStock(t *testing.T, ws *websocket.Conn) stockMessage {
+	t.Helper()
+
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+
+	var m stockMessage
+	require.NoError(t, ws.ReadJSON(&m))
+
+	return m
+}
+
+func TestStockAnonymous(t *testing.T) {
+	srv := stockServer(events.NewBus(8))
+	defer srv.Close()
+
+	_, res, err := dialStock(t, srv, "")
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestStock(t *testing.T) {
+	bus := events.NewBus(8)
+
+	srv := stockServer(bus)
+	defer srv.Close()
+
+	ws, _, err := dialStock(t, srv, bearer("viewer"))
+	require.NoError(t, err)
+	defer ws.Close()
+
+	require.NoError(t, ws.WriteJSON(stockRequest{Type: "subscribe", Ids: []int{1, 3}}))
+
+	assert.Equal(t, stockMessage{Type: stockMessageError, Id: 3, Message: producti.ErrNotFound.Error()}, readStock(t, ws))
+	assert.Equal(t, stockMessage{Type: stockMessageStock, Id: 1, Quantity: 5}, readStock(t, ws))
+
+	// unsubscribed products and unchanged quantities are left out
+	bus.Publish(producti.EventUpdated, domain.Product{Id: 2, Quantity: 1})
+	bus.Publish(producti.EventUpdated, domain.Product{Id: 1, Quantity: 5})
+	bus.Publish(producti.EventUpdated, domain.Product{Id: 1, Quantity: 4})
+	assert.Equal(t, stockMessage{Type: stockMessageStock, Id: 1, Quantity: 4}, readStock(t, ws))
+
+	bus.Publish(producti.EventDeleted, producti.Deleted{Id: 1})
+	assert.Equal(t, stockMessage{Type: stockMessageDeleted, Id: 1}, readStock(t, ws))
+
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"subscribe"}`)))
+	assert.Equal(t, stockMessage{Type: stockMessageError, Message: ErrInvalidStockRequest.Error()}, readStock(t, ws))
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/internal/supplier"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type supplierHandler struct {
+	svc supplier.SupplierService
+}
+
+func NewSupplier(mux *gin.Engine, s supplier.SupplierService, a *Auth) {
+	sh := &supplierHandler{
+		svc: s,
+	}
+
+	// suppliers are read with the products they restock
+	suppliersMux := mux.Group("/suppliers", a.Authenticate)
+	suppliersMux.GET("/", a.Authorize(PermProductsRead), sh.GetAll)
+	suppliersMux.GET("/:id", a.Authorize(PermProductsRead), sh.GetById)
+
+	suppliersMux.POST("/", a.Authorize(PermSuppliersWrite), sh.Create)
+	suppliersMux.PUT("/:id", a.Authorize(PermSuppliersWrite), sh.Update)
+	suppliersMux.DELETE("/:id", a.Authorize(PermSuppliersWrite), sh.Delete)
+	suppliersMux.PUT("/:id/products/:productId", a.Authorize(PermSuppliersWrite), sh.LinkProduct)
+	suppliersMux.DELETE("/:id/products/:productId", a.Authorize(PermSuppliersWrite), sh.UnlinkProduct)
+}
+
+type supplierRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Phone string `json:"phone"`
+}
+
+type supplierProductRequest struct {
+	CostPrice    float64 `json:"cost_price" binding:"required,gte=0"`
+	LeadTimeDays int     `json:"lead_time_days" binding:"gte=0"`
+}
+
+func (sh *supplierHandler) GetAll(ctx *gin.Context) {
+	var (
+		ss        []domain.Supplier
+		productId int
+		err       error
+	)
+
+	// filter by supplied product
+	if productIdStr := ctx.Query("product_id"); productIdStr != "" {
+		productId, err = strconv.Atoi(productIdStr)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+				http.StatusBadRequest,
+				"bad request",
+				supplier.ErrProductNotFound.Error(),
+			))
+			return
+		}
+
+		ss, err = sh.svc.ForProduct(productId)
+	} else {
+		ss, err = sh.svc.All()
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(ss))
+}
+
+func (sh *supplierHandler) GetById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	s, err := sh.svc.GetById(id)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(s))
+}
+
+func (sh *supplierHandler) Create(ctx *gin.Context) {
+	var r supplierRequest
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	s, err := sh.svc.Create(r.Name, r.Email, r.Phone)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, web.Response(s))
+}
+
+func (sh *supplierHandler) Update(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r supplierRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	err = sh.svc.Update(id, r.Name, r.Email, r.Phone)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (sh *supplierHandler) Delete(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = sh.svc.Delete(id)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (sh *supplierHandler) LinkProduct(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	productId, err := strconv.Atoi(ctx.Param("productId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrProductNotFound.Error(),
+		))
+		return
+	}
+
+	var r supplierProductRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	err = sh.svc.LinkProduct(id, productId, r.CostPrice, r.LeadTimeDays)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (sh *supplierHandler) UnlinkProduct(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	productId, err := strconv.Atoi(ctx.Param("productId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrProductNotFound.Error(),
+		))
+		return
+	}
+
+	err = sh.svc.UnlinkProduct(id, productId)
+	if err != nil {
+		supplierErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func supplierErr(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, supplier.ErrInvalidData):
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			supplier.ErrInvalidData.Error(),
+		))
+	case errors.Is(err, supplier.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			supplier.ErrNotFound.Error(),
+		))
+	case errors.Is(err, supplier.ErrProductNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			supplier.ErrProductNotFound.Error(),
+		))
+	case errors.Is(err, supplier.ErrProductNotLinked):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			supplier.ErrProductNotLinked.Error(),
+		))
+	case errors.Is(err, storage.ErrWriteFile):
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			supplier.ErrCreation.Error(),
+		))
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/internal/purchase"
+	"gituhb.com/juajosserand/goweb/internal/supplier"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+// stubSuppliers holds no supplier.
+type stubSuppliers struct {
+	supplier.SupplierService
+}
+
+func (stubSuppliers) All() ([]domain.Supplier, error) {
+	return []domain.Supplier{}, nil
+}
+
+// stubOrders holds no purchase order.
+type stubOrders struct {
+	purchase.PurchaseOrderService
+}
+
+func (stubOrders) All() ([]domain.PurchaseOrder, error) {
+	return []domain.PurchaseOrder{}, nil
+}
+
+func TestSupplierPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	auth := NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil)
+
+	mux := gin.New()
+	NewSupplier(mux, stubSuppliers{}, auth)
+	NewPurchaseOrder(mux, stubOrders{}, auth)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"anonymous suppliers read", http.MethodGet, "/suppliers/", "", http.StatusOK},
+		{"viewer suppliers read", http.MethodGet, "/suppliers/", bearer("viewer"), http.StatusOK},
+		{"invalid token suppliers read", http.MethodGet, "/suppliers/", "Bearer invalid", http.StatusUnauthorized},
+		{"viewer suppliers write", http.MethodPost, "/suppliers/", bearer("viewer"), http.StatusForbidden},
+		{"viewer orders read", http.MethodGet, "/purchase_orders/", bearer("viewer"), http.StatusOK},
+		{"invalid token orders read", http.MethodGet, "/purchase_orders/", "Bearer invalid", http.StatusUnauthorized},
+		{"viewer orders write", http.MethodPost, "/purchase_orders/", bearer("viewer"), http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", test.token)
+			}
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code)
+		})
+	}
+}
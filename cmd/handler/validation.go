@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/pkg/openapi"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+// Validation checks requests against the openapi document of the routes
+// registered on mux, and in gin test mode also the json responses, so that
+// handlers drifting from the document fail their tests.
+type Validation struct {
+	mux         *gin.Engine
+	once        sync.Once
+	doc         *openapi.Document
+	maxBodySize int64
+}
+
+type ValidationOption func(*Validation)
+
+// MaxBodySize limits the json request bodies, rejected with 413 above it.
+func MaxBodySize(n int64) ValidationOption {
+	return func(v *Validation) {
+		v.maxBodySize = n
+	}
+}
+
+// requestCheckKey holds the request check of a secured operation until the
+// request is authenticated.
+const requestCheckKey = "request_check"
+
+// NewValidation must be used by mux before the routes are registered. The
+// document is built on the first request.
+func NewValidation(mux *gin.Engine, ops ...ValidationOption) *Validation {
+	v := &Validation{
+		mux: mux,
+	}
+
+	for _, op := range ops {
+		op(v)
+	}
+
+	return v
+}
+
+func (v *Validation) Handle(ctx *gin.Context) {
+	v.once.Do(func() {
+		v.doc = OpenAPI(v.mux)
+		v.doc.MaxBodySize = v.maxBodySize
+	})
+
+	route := ctx.FullPath()
+	if route == "" {
+		ctx.Next()
+		return
+	}
+
+	op := v.doc.Operation(ctx.Request.Method, route)
+
+	rejected := false
+	check := func(ctx *gin.Context) bool {
+		rejected = !v.checkRequest(ctx, route)
+		return !rejected
+	}
+
+	// secured requests are checked once authenticated, so that callers
+	// without credentials get 401 rather than schema errors
+	if op != nil && len(op.Security) > 0 {
+		ctx.Set(requestCheckKey, check)
+	} else if !check(ctx) {
+		return
+	}
+
+	// upgraded connections are written directly
+	if gin.Mode() != gin.TestMode || ctx.IsWebsocket() || !jsonResponses(ctx, op) {
+		ctx.Next()
+		return
+	}
+
+	w := &bufferedWriter{ResponseWriter: ctx.Writer, status: http.StatusOK}
+	ctx.Writer = w
+
+	ctx.Next()
+
+	ctx.Writer = w.ResponseWriter
+
+	// rejected requests are answered as they are
+	if rejected {
+		w.flush()
+		return
+	}
+
+	errs := v.doc.ValidateResponse(ctx.Request.Method, route, w.status, w.Header().Get("Content-Type"), w.body.Bytes())
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusInternalServerError, web.ValidationErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"response does not match the api schema",
+			fieldErrors(errs),
+		))
+		return
+	}
+
+	w.flush()
+}
+
+// checkRequest validates the request against the route operation, aborting
+// it otherwise.
+func (v *Validation) checkRequest(ctx *gin.Context, route string) bool {
+	params := make(map[string]string, len(ctx.Params))
+	for _, p := range ctx.Params {
+		params[p.Key] = p.Value
+	}
+
+	errs := v.doc.ValidateRequest(ctx.Request, route, params)
+	if len(errs) == 0 {
+		return true
+	}
+
+	for _, e := range errs {
+		if e.Rule == openapi.RuleMaxBytes {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, web.ErrResponse(
+				http.StatusRequestEntityTooLarge,
+				"request entity too large",
+				e.Error(),
+			))
+			return false
+		}
+	}
+
+	ctx.AbortWithStatusJSON(http.StatusBadRequest, web.ValidationErrResponse(
+		http.StatusBadRequest,
+		"bad request",
+		"request does not match the api schema",
+		fieldErrors(errs),
+	))
+
+	return false
+}
+
+// checked runs the request check deferred by the validation, if any, once.
+func checked(ctx *gin.Context) bool {
+	check, ok := ctx.Value(requestCheckKey).(func(*gin.Context) bool)
+	if !ok {
+		return true
+	}
+
+	ctx.Set(requestCheckKey, nil)
+
+	return check(ctx)
+}
+
+// jsonResponses reports whether every documented response of the operation
+// is json or empty, leaving out files and streams, or whether json is
+// negotiated among the content types of the operation.
+func jsonResponses(ctx *gin.Context, op *openapi.Operation) bool {
+	if op == nil {
+		return false
+	}
+
+	offered := []string{"application/json"}
+	for _, res := range op.Responses {
+		if _, ok := res.Content["application/json"]; len(res.Content) > 0 && !ok {
+			return false
+		}
+
+		for contentType := range res.Content {
+			if contentType != "application/json" {
+				offered = append(offered, contentType)
+			}
+		}
+	}
+
+	// negotiated responses are validated when json is served
+	return len(offered) == 1 || ctx.NegotiateFormat(offered...) == "application/json"
+}
+
+func fieldErrors(errs []openapi.ValidationError) []web.FieldError {
+	fes := make([]web.FieldError, 0, len(errs))
+	for _, e := range errs {
+		fes = append(fes, web.FieldError{
+			Field:   e.Field,
+			Rule:    e.Rule,
+			Message: e.Message,
+		})
+	}
+
+	return fes
+}
+
+// bufferedWriter holds the response back until it is validated.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if w.body.Len() == 0 {
+		return -1
+	}
+
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// flush writes the held response.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.body.Len() == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
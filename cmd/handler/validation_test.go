@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+// stubProducts answers All with fixed products.
+type stubProducts struct {
+	producti.ProductService
+	ps []domain.Product
+}
+
+func (s *stubProducts) All() ([]domain.Product, error) {
+	return s.ps, nil
+}
+
+func validatedMux(ps []domain.Product) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewProduct(mux, &stubProducts{ps: ps}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	return mux
+}
+
+type validationErr struct {
+	Status  int              `json:"status"`
+	Message string           `json:"message"`
+	Errors  []web.FieldError `json:"errors"`
+}
+
+func fields(t *testing.T, body string) []string {
+	var res validationErr
+	err := json.Unmarshal([]byte(body), &res)
+	assert.NoError(t, err)
+
+	var fs []string
+	for _, e := range res.Errors {
+		fs = append(fs, e.Field+" "+e.Rule)
+	}
+
+	return fs
+}
+
+func TestValidationRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		fields []string
+	}{
+		{
+			name:   "missing and invalid fields",
+			method: http.MethodPost,
+			url:    "/products/",
+			body:   `{"name":"x","quantity":0,"code_value":"abc","expiration":"01/01/2030"}`,
+			fields: []string{
+				"body.price required",
+				"body.quantity minimum",
+				"body.code_value pattern",
+			},
+		},
+		{
+			name:   "wrong types",
+			method: http.MethodPost,
+			url:    "/products/",
+			body:   `{"name":1,"quantity":1.5,"code_value":"ABC","expiration":"01/01/2030","price":"10"}`,
+			fields: []string{
+				"body.name type",
+				"body.quantity type",
+				"body.price type",
+			},
+		},
+		{
+			name:   "path parameter",
+			method: http.MethodDelete,
+			url:    "/products/abc",
+			fields: []string{"path.id type"},
+		},
+		{
+			name:   "query parameter",
+			method: http.MethodGet,
+			url:    "/products/consumer_price?list=1,2",
+			fields: []string{"query.list pattern"},
+		},
+	}
+
+	mux := validatedMux(nil)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", bearer("admin"))
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, http.StatusBadRequest, res.Code)
+			assert.ElementsMatch(t, tc.fields, fields(t, res.Body.String()))
+		})
+	}
+}
+
+func TestValidationResponse(t *testing.T) {
+	valid := domain.Product{Id: 1, Name: "x", Quantity: 1, CodeValue: "X1", Expiration: "01/01/2030", Price: 1}
+
+	res := httptest.NewRecorder()
+	validatedMux([]domain.Product{valid}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/products/", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// a stored product out of the documented bounds is caught in test mode
+	invalid := valid
+	invalid.Quantity = 0
+
+	res = httptest.NewRecorder()
+	validatedMux([]domain.Product{invalid}).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/products/", nil))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, []string{"response.data[0].quantity minimum"}, fields(t, res.Body.String()))
+}
+
+func TestValidationAfterAuth(t *testing.T) {
+	invalid := `{"name":1}`
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{
+			name:   "without credentials",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid token",
+			token:  "Bearer invalid",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "not granted",
+			token:  bearer("viewer"),
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "authorized",
+			token:  bearer("admin"),
+			status: http.StatusBadRequest,
+		},
+	}
+
+	mux := validatedMux(nil)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products/", strings.NewReader(invalid))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.token != "" {
+				req.Header.Set("Authorization", tc.token)
+			}
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, tc.status, res.Code)
+		})
+	}
+}
+
+func TestValidationBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux, MaxBodySize(64)).Handle)
+	NewProduct(mux, &stubProducts{}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	body := `{"name":"` + strings.Repeat("x", 128) + `"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/products/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer("admin"))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type variantRequest struct {
+	CodeValue  string                   `json:"code_value" binding:"required,uppercase,alphanum"`
+	Price      float64                  `json:"price" binding:"required,gte=0"`
+	Quantity   int                      `json:"quantity" binding:"gte=0"`
+	Attributes domain.VariantAttributes `json:"attributes"`
+}
+
+func (ph *product) CreateVariant(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r variantRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.createVariant(ctx, id, r)
+}
+
+// createVariant adds the variant and writes the response.
+func (ph *product) createVariant(ctx *gin.Context, id int, r variantRequest) {
+	err := ph.svc.CreateVariant(id, r.CodeValue, r.Price, r.Quantity, r.Attributes)
+	if err != nil {
+		variantErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+func (ph *product) UpdateVariant(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	var r variantRequest
+
+	err = ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	ph.updateVariant(ctx, id, ctx.Param("code"), r)
+}
+
+// updateVariant replaces the variant and writes the response.
+func (ph *product) updateVariant(ctx *gin.Context, id int, code string, r variantRequest) {
+	if !ph.variantPriceChangeAllowed(ctx, code, r.Price) {
+		ph.auth.Deny(ctx, PermProductsPrice)
+		return
+	}
+
+	err := ph.svc.UpdateVariant(id, code, r.CodeValue, r.Price, r.Quantity, r.Attributes)
+	if err != nil {
+		variantErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ph *product) DeleteVariant(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = ph.svc.DeleteVariant(id, ctx.Param("code"))
+	if err != nil {
+		variantErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (ph *product) variantPriceChangeAllowed(ctx *gin.Context, code string, price float64) bool {
+	_, v, err := ph.svc.GetVariant(code)
+	if err != nil || v.Price == price {
+		return true
+	}
+
+	return ph.auth.Allowed(ctx, PermProductsPrice)
+}
+
+func variantErr(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, producti.ErrInvalidData):
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			producti.ErrInvalidData.Error(),
+		))
+	case errors.Is(err, producti.ErrDuplicatedCodeValue):
+		ctx.JSON(http.StatusUnprocessableEntity, web.ErrResponse(
+			http.StatusUnprocessableEntity,
+			"unprocessable entity",
+			producti.ErrDuplicatedCodeValue.Error(),
+		))
+	case errors.Is(err, storage.ErrWriteFile):
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			producti.ErrCreation.Error(),
+		))
+	case errors.Is(err, producti.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			producti.ErrNotFound.Error(),
+		))
+	case errors.Is(err, producti.ErrVariantNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			producti.ErrVariantNotFound.Error(),
+		))
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+// Version is a major version of the api, served under its "/vN" prefix.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+
+	LatestVersion = V2
+)
+
+const (
+	// VersionHeader selects the version of unversioned routes, e.g.
+	// "Accept-Version: 2". An Accept of "application/vnd.goweb.v2+json"
+	// does the same.
+	VersionHeader = "Accept-Version"
+
+	// APIVersionHeader tells the version that served the response.
+	APIVersionHeader = "API-Version"
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported api version")
+
+func (v Version) prefix() string {
+	return fmt.Sprintf("/v%d", v)
+}
+
+// Versioning routes requests to unversioned paths, e.g. /products, to the
+// version asked for in the headers, defaulting to V1 so that existing
+// callers keep working. Responses of versions older than LatestVersion get
+// a Deprecation header.
+type Versioning struct {
+	mux    *gin.Engine
+	once   sync.Once
+	routes map[string]bool
+}
+
+// NewVersioning must be used by mux before the routes are registered, and
+// before any middleware checking requests against their route.
+func NewVersioning(mux *gin.Engine) *Versioning {
+	return &Versioning{
+		mux: mux,
+	}
+}
+
+func (vs *Versioning) Handle(ctx *gin.Context) {
+	vs.once.Do(func() {
+		vs.routes = make(map[string]bool)
+		for _, r := range vs.mux.Routes() {
+			vs.routes[r.Method+" "+r.Path] = true
+		}
+	})
+
+	route := ctx.FullPath()
+	if route == "" {
+		ctx.Next()
+		return
+	}
+
+	if v, ok := pathVersion(route); ok {
+		vs.serve(ctx, v, strings.TrimPrefix(route, v.prefix()))
+		return
+	}
+
+	// unversioned routes without versions are left alone
+	if !vs.routes[ctx.Request.Method+" "+V1.prefix()+route] {
+		ctx.Next()
+		return
+	}
+
+	v, err := requestedVersion(ctx)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusNotAcceptable, web.ErrResponse(
+			http.StatusNotAcceptable,
+			"not acceptable",
+			err.Error(),
+		))
+		return
+	}
+
+	if v != V1 {
+		if !vs.routes[ctx.Request.Method+" "+v.prefix()+route] {
+			ctx.AbortWithStatusJSON(http.StatusNotAcceptable, web.ErrResponse(
+				http.StatusNotAcceptable,
+				"not acceptable",
+				fmt.Sprintf("%s: %d", ErrUnsupportedVersion.Error(), v),
+			))
+			return
+		}
+
+		// serve the request again under the versioned path, which runs the
+		// whole chain of that route
+		ctx.Request.URL.Path = v.prefix() + ctx.Request.URL.Path
+		vs.mux.HandleContext(ctx)
+		ctx.Abort()
+		return
+	}
+
+	vs.serve(ctx, V1, route)
+}
+
+// serve sets the version headers of the response.
+func (vs *Versioning) serve(ctx *gin.Context, v Version, route string) {
+	ctx.Header(APIVersionHeader, strconv.Itoa(int(v)))
+	ctx.Header("Vary", VersionHeader+", Accept")
+
+	if v < LatestVersion {
+		ctx.Header("Deprecation", "true")
+
+		if vs.routes[ctx.Request.Method+" "+LatestVersion.prefix()+route] {
+			ctx.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, LatestVersion.prefix(), strings.TrimPrefix(ctx.Request.URL.Path, v.prefix())))
+		}
+	}
+
+	ctx.Next()
+}
+
+// pathVersion returns the version of a "/vN/..." route.
+func pathVersion(route string) (Version, bool) {
+	prefix, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+	if !strings.HasPrefix(prefix, "v") {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(prefix[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+
+	return Version(n), true
+}
+
+// unversioned returns the route without its "/vN" prefix, if any.
+func unversioned(route string) string {
+	if v, ok := pathVersion(route); ok {
+		return strings.TrimPrefix(route, v.prefix())
+	}
+
+	return route
+}
+
+// requestedVersion reads the version of the Accept-Version header or of a
+// vendor media type in Accept, defaulting to V1.
+func requestedVersion(ctx *gin.Context) (Version, error) {
+	if h := ctx.GetHeader(VersionHeader); h != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(h), "v"))
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("%w: %s", ErrUnsupportedVersion, h)
+		}
+		return Version(n), nil
+	}
+
+	for _, accept := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		var n int
+		if _, err := fmt.Sscanf(mediaType, "application/vnd.goweb.v%d+json", &n); err == nil && n >= 1 {
+			return Version(n), nil
+		}
+	}
+
+	return V1, nil
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/ratelimit"
+)
+
+func versionedMux(ps []domain.Product) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewVersioning(mux).Handle)
+	mux.Use(NewValidation(mux).Handle)
+	NewProduct(mux, &stubProducts{ps: ps}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	return mux
+}
+
+func TestVersioning(t *testing.T) {
+	mux := versionedMux([]domain.Product{{
+		Id:          1,
+		Name:        "Test Product",
+		Quantity:    1,
+		CodeValue:   "A1B2C3",
+		IsPublished: true,
+		Expiration:  "20/01/2030",
+		Price:       100.5,
+	}})
+
+	tests := []struct {
+		name       string
+		url        string
+		headers    map[string]string
+		status     int
+		version    string
+		deprecated bool
+		price      string
+	}{
+		{
+			name:       "unversioned defaults to v1",
+			url:        "/products/",
+			status:     http.StatusOK,
+			version:    "1",
+			deprecated: true,
+			price:      `100.5`,
+		},
+		{
+			name:       "v1 prefix",
+			url:        "/v1/products/",
+			status:     http.StatusOK,
+			version:    "1",
+			deprecated: true,
+			price:      `100.5`,
+		},
+		{
+			name:    "v2 prefix",
+			url:     "/v2/products/",
+			status:  http.StatusOK,
+			version: "2",
+			price:   `{"amount":"100.5","currency":"USD"}`,
+		},
+		{
+			name:    "version header",
+			url:     "/products/",
+			headers: map[string]string{VersionHeader: "2"},
+			status:  http.StatusOK,
+			version: "2",
+			price:   `{"amount":"100.5","currency":"USD"}`,
+		},
+		{
+			name:    "vendor media type",
+			url:     "/products/",
+			headers: map[string]string{"Accept": "application/vnd.goweb.v2+json"},
+			status:  http.StatusOK,
+			version: "2",
+			price:   `{"amount":"100.5","currency":"USD"}`,
+		},
+		{
+			name:    "unsupported version",
+			url:     "/products/",
+			headers: map[string]string{VersionHeader: "3"},
+			status:  http.StatusNotAcceptable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			for h, v := range test.headers {
+				req.Header.Set(h, v)
+			}
+
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			assert.Equal(t, test.status, res.Code, res.Body.String())
+			if test.status != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, test.version, res.Header().Get(APIVersionHeader))
+			assert.Equal(t, test.deprecated, res.Header().Get("Deprecation") == "true")
+			if test.deprecated {
+				assert.Equal(t, `</v2/products/>; rel="successor-version"`, res.Header().Get("Link"))
+			}
+
+			var body struct {
+				Data []map[string]json.RawMessage `json:"data"`
+			}
+			err := json.Unmarshal(res.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			assert.Len(t, body.Data, 1)
+			assert.JSONEq(t, test.price, string(body.Data[0]["price"]))
+		})
+	}
+}
+
+func TestVersioningRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limits := &ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 100, Period: time.Minute},
+		Routes: map[string]ratelimit.Limit{
+			"GET /products/consumer_price": {Requests: 1, Period: time.Minute},
+		},
+	}
+
+	mux := gin.New()
+	mux.Use(NewVersioning(mux).Handle)
+	NewProduct(mux, &stubProducts{}, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil, RateLimiter(NewRateLimit(limits))))
+
+	get := func(url string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", bearer("viewer"))
+		for h, v := range headers {
+			req.Header.Set(h, v)
+		}
+
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+
+		return res
+	}
+
+	res := get("/v2/products/consumer_price", nil)
+	assert.NotEqual(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "1", res.Header().Get("RateLimit-Limit"))
+
+	// every version of the route shares its limit
+	assert.Equal(t, http.StatusTooManyRequests, get("/v1/products/consumer_price", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/products/consumer_price", map[string]string{VersionHeader: "2"}).Code)
+	assert.Equal(t, http.StatusTooManyRequests, get("/products/consumer_price", nil).Code)
+
+	// other routes keep the default limit
+	res = get("/v2/products/", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "100", res.Header().Get("RateLimit-Limit"))
+}
+
+func TestVersioningRequestV2(t *testing.T) {
+	mux := versionedMux(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/products/", strings.NewReader(
+		`{"name":"x","quantity":1,"code_value":"ABC","expiration":"20/01/2030","price":{"amount":"ten","currency":"EUR"}}`,
+	))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer("admin"))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.ElementsMatch(t, []string{
+		"body.expiration format",
+		"body.price.amount pattern",
+		"body.price.currency enum",
+	}, fields(t, res.Body.String()))
+}
+
+func TestVersioningZeroPriceV2(t *testing.T) {
+	mux := versionedMux(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/products/", strings.NewReader(
+		`{"name":"x","quantity":1,"code_value":"ABC","expiration":"2030-01-20","price":{"amount":"0","currency":"USD"}}`,
+	))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer("admin"))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{30, "30"},
+		{100.5, "100.5"},
+		// not rounded to cents
+		{100.125, "100.125"},
+		{0.001, "0.001"},
+	}
+
+	for _, test := range tests {
+		m := newMoney(test.amount)
+		assert.Equal(t, test.want, m.Amount)
+		assert.Equal(t, Currency, m.Currency)
+
+		v, err := m.value()
+		assert.NoError(t, err)
+		assert.Equal(t, test.amount, v)
+	}
+}
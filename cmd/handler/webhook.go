@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/internal/webhook"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+	"gituhb.com/juajosserand/goweb/pkg/web"
+)
+
+type webhookHandler struct {
+	svc webhook.WebhookService
+}
+
+func NewWebhook(mux *gin.Engine, s webhook.WebhookService, a *Auth) {
+	wh := &webhookHandler{
+		svc: s,
+	}
+
+	webhooksMux := mux.Group("/webhooks", a.Authenticate, a.Authorize(PermWebhooksManage))
+	webhooksMux.GET("/", wh.GetAll)
+	webhooksMux.GET("/:id", wh.GetById)
+	webhooksMux.POST("/", wh.Create)
+	webhooksMux.DELETE("/:id", wh.Delete)
+	webhooksMux.GET("/:id/deliveries", wh.Deliveries)
+	webhooksMux.POST("/:id/deliveries/:deliveryId/redeliver", wh.Redeliver)
+}
+
+type webhookRequest struct {
+	URL    string   `json:"url" binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1"`
+	// Secret is generated when empty.
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+}
+
+// webhookResponse hides the secret.
+type webhookResponse struct {
+	Id        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(w domain.Webhook) webhookResponse {
+	return webhookResponse{
+		Id:        w.Id,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func (wh *webhookHandler) GetAll(ctx *gin.Context) {
+	ws, err := wh.svc.All()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+		return
+	}
+
+	res := make([]webhookResponse, 0, len(ws))
+	for _, w := range ws {
+		res = append(res, newWebhookResponse(w))
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(res))
+}
+
+func (wh *webhookHandler) GetById(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	w, err := wh.svc.GetById(id)
+	if err != nil {
+		webhookErr(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(newWebhookResponse(w)))
+}
+
+func (wh *webhookHandler) Create(ctx *gin.Context) {
+	var r webhookRequest
+
+	err := ctx.ShouldBindJSON(&r)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidData.Error(),
+		))
+		return
+	}
+
+	w, err := wh.svc.Create(r.URL, r.Events, r.Secret)
+	if err != nil {
+		webhookErr(ctx, err)
+		return
+	}
+
+	securityEvent(ctx, "webhook_created", fmt.Sprintf("id=%d events=[%s]", w.Id, strings.Join(w.Events, " ")))
+
+	// the secret is only shown once
+	res := newWebhookResponse(w)
+	res.Secret = w.Secret
+
+	ctx.JSON(http.StatusCreated, web.Response(res))
+}
+
+func (wh *webhookHandler) Delete(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	err = wh.svc.Delete(id)
+	if err != nil {
+		webhookErr(ctx, err)
+		return
+	}
+
+	securityEvent(ctx, "webhook_deleted", fmt.Sprintf("id=%d", id))
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// Deliveries returns the delivery log of the webhook, newest first.
+func (wh *webhookHandler) Deliveries(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	ds, err := wh.svc.Deliveries(id)
+	if err != nil {
+		webhookErr(ctx, err)
+		return
+	}
+
+	// filter by status, e.g. the dead letters
+	if status := ctx.Query("status"); status != "" {
+		filtered := []domain.Delivery{}
+		for _, d := range ds {
+			if string(d.Status) == status {
+				filtered = append(filtered, d)
+			}
+		}
+		ds = filtered
+	}
+
+	ctx.JSON(http.StatusOK, web.Response(ds))
+}
+
+// Redeliver queues a dead or succeeded delivery again.
+func (wh *webhookHandler) Redeliver(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidId.Error(),
+		))
+		return
+	}
+
+	deliveryId, err := strconv.Atoi(ctx.Param("deliveryId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrDeliveryNotFound.Error(),
+		))
+		return
+	}
+
+	err = wh.svc.Redeliver(id, deliveryId)
+	if err != nil {
+		webhookErr(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func webhookErr(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidData):
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			webhook.ErrInvalidData.Error(),
+		))
+	case errors.Is(err, webhook.ErrInvalidEvent):
+		ctx.JSON(http.StatusBadRequest, web.ErrResponse(
+			http.StatusBadRequest,
+			"bad request",
+			err.Error(),
+		))
+	case errors.Is(err, webhook.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			webhook.ErrNotFound.Error(),
+		))
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		ctx.JSON(http.StatusNotFound, web.ErrResponse(
+			http.StatusNotFound,
+			"not found",
+			webhook.ErrDeliveryNotFound.Error(),
+		))
+	case errors.Is(err, webhook.ErrDeliveryPending):
+		ctx.JSON(http.StatusConflict, web.ErrResponse(
+			http.StatusConflict,
+			"conflict",
+			webhook.ErrDeliveryPending.Error(),
+		))
+	case errors.Is(err, storage.ErrWriteFile):
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			webhook.ErrCreation.Error(),
+		))
+	default:
+		ctx.JSON(http.StatusInternalServerError, web.ErrResponse(
+			http.StatusInternalServerError,
+			"internal server error",
+			"internal server error",
+		))
+	}
+}
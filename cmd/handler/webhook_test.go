@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	producti "gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/internal/webhook"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+// receiver records the deliveries it gets, answering with the queued
+// status codes and then 200.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.headers = append(r.headers, req.Header.Clone())
+	r.bodies = append(r.bodies, body)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func webhookService(t *testing.T) webhook.WebhookService {
+	t.Helper()
+
+	dir := t.TempDir()
+	for env, name := range map[string]string{
+		"WEBHOOKS_FILENAME":           "webhooks.json",
+		"WEBHOOK_DELIVERIES_FILENAME": "webhook_deliveries.json",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("[]"), 0o644))
+		t.Setenv(env, path)
+	}
+
+	r, err := webhook.NewRepository()
+	require.NoError(t, err)
+
+	q, err := webhook.NewQueue()
+	require.NoError(t, err)
+
+	return webhook.NewService(r, q,
+		webhook.Events(producti.EventCreated, producti.EventUpdated, producti.EventDeleted),
+		webhook.MaxAttempts(2),
+		webhook.Backoff(time.Millisecond, time.Millisecond),
+		// the receivers listen on loopback
+		webhook.AllowPrivateNetworks(),
+	)
+}
+
+func webhookMux(s webhook.WebhookService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(NewValidation(mux).Handle)
+	NewWebhook(mux, s, NewAuth(jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))), DefaultPolicy(), nil))
+
+	return mux
+}
+
+func serveWebhook(mux *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer("admin"))
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, req)
+
+	return res
+}
+
+func deliveries(t *testing.T, mux *gin.Engine, query string) []domain.Delivery {
+	t.Helper()
+
+	res := serveWebhook(mux, http.MethodGet, "/webhooks/1/deliveries"+query, "")
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+	var body struct {
+		Data []domain.Delivery `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+
+	return body.Data
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	svc := webhookService(t)
+	mux := webhookMux(svc)
+
+	res := serveWebhook(mux, http.MethodPost, "/webhooks/", `{"url":"`+srv.URL+`","events":["product.unknown"]}`)
+	assert.Equal(t, http.StatusBadRequest, res.Code, res.Body.String())
+
+	res = serveWebhook(mux, http.MethodPost, "/webhooks/", `{"url":"`+srv.URL+`","events":["product.updated"]}`)
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+
+	var created struct {
+		Data webhookResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Data.Secret)
+
+	svc.Publish(producti.EventCreated, domain.Product{Id: 1})
+	svc.Publish(producti.EventUpdated, domain.Product{Id: 1, Name: "A"})
+
+	// retried after a failed attempt
+	svc.Dispatch()
+	time.Sleep(2 * time.Millisecond)
+	svc.Dispatch()
+
+	ds := deliveries(t, mux, "")
+	require.Len(t, ds, 1)
+	assert.Equal(t, domain.DeliverySucceeded, ds[0].Status)
+	assert.Equal(t, producti.EventUpdated, ds[0].Event)
+	require.Len(t, ds[0].Attempts, 2)
+	assert.Equal(t, http.StatusInternalServerError, ds[0].Attempts[0].StatusCode)
+	assert.Equal(t, http.StatusOK, ds[0].Attempts[1].StatusCode)
+
+	require.Len(t, rcv.bodies, 2)
+	assert.Equal(t, producti.EventUpdated, rcv.headers[1].Get(webhook.EventHeader))
+	assert.NoError(t, webhook.Verify(created.Data.Secret, rcv.headers[1].Get(webhook.SignatureHeader), rcv.bodies[1], time.Minute, time.Now()))
+	assert.ErrorIs(t, webhook.Verify("wrong-secret-value", rcv.headers[1].Get(webhook.SignatureHeader), rcv.bodies[1], time.Minute, time.Now()), webhook.ErrInvalidSignature)
+
+	// dead-lettered after the max attempts
+	rcv.mu.Lock()
+	rcv.statuses = []int{http.StatusBadGateway, http.StatusBadGateway}
+	rcv.mu.Unlock()
+
+	svc.Publish(producti.EventUpdated, domain.Product{Id: 1, Name: "B"})
+	svc.Dispatch()
+	time.Sleep(2 * time.Millisecond)
+	svc.Dispatch()
+
+	dead := deliveries(t, mux, "?status=dead")
+	require.Len(t, dead, 1)
+	assert.Len(t, dead[0].Attempts, 2)
+	assert.Nil(t, dead[0].NextAttemptAt)
+
+	res = serveWebhook(mux, http.MethodPost, "/webhooks/1/deliveries/"+strconv.Itoa(dead[0].Id)+"/redeliver", "")
+	assert.Equal(t, http.StatusAccepted, res.Code, res.Body.String())
+
+	res = serveWebhook(mux, http.MethodPost, "/webhooks/1/deliveries/"+strconv.Itoa(dead[0].Id)+"/redeliver", "")
+	assert.Equal(t, http.StatusConflict, res.Code, res.Body.String())
+
+	svc.Dispatch()
+	assert.Empty(t, deliveries(t, mux, "?status=dead"))
+}
@@ -5,13 +5,31 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"gituhb.com/juajosserand/goweb/cmd/handler"
+	"gituhb.com/juajosserand/goweb/internal/apikey"
+	"gituhb.com/juajosserand/goweb/internal/attribute"
+	"gituhb.com/juajosserand/goweb/internal/media"
 	"gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/internal/purchase"
+	"gituhb.com/juajosserand/goweb/internal/reorder"
+	"gituhb.com/juajosserand/goweb/internal/supplier"
+	"gituhb.com/juajosserand/goweb/internal/webhook"
+	"gituhb.com/juajosserand/goweb/pkg/events"
+	"gituhb.com/juajosserand/goweb/pkg/grpcserver"
 	"gituhb.com/juajosserand/goweb/pkg/httpserver"
+	"gituhb.com/juajosserand/goweb/pkg/idempotency"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+	"gituhb.com/juajosserand/goweb/pkg/outbox"
+	"gituhb.com/juajosserand/goweb/pkg/ratelimit"
+	"gituhb.com/juajosserand/goweb/pkg/rbac"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -27,13 +45,274 @@ func main() {
 		log.Println(fmt.Errorf("error: %w", err))
 	}
 
+	attributeRepo, err := attribute.NewRepository()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	supplierRepo, err := supplier.NewRepository()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	orderRepo, err := purchase.NewRepository()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	ledger, err := reorder.NewLedger()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	keyRepo, err := apikey.NewRepository()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	webhookRepo, err := webhook.NewRepository()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	deliveryQueue, err := webhook.NewQueue()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	// product writes stage their events in the outbox, and writes
+	// interrupted by a crash are resolved before serving
+	outboxStore, err := outbox.NewStore(os.Getenv("OUTBOX_FILENAME"))
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	err = product.Recover(repo, outboxStore)
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
+
+	// product changes are published to the stream subscribers
+	bufferSize, err := strconv.Atoi(os.Getenv("EVENTS_BUFFER_SIZE"))
+	if err != nil || bufferSize <= 0 {
+		bufferSize = 256
+	}
+
+	bus := events.NewBus(bufferSize)
+
+	maxAttempts, _ := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	webhookOps := []webhook.Option{
+		webhook.Events(product.EventCreated, product.EventUpdated, product.EventDeleted),
+		webhook.MaxAttempts(maxAttempts),
+	}
+
+	// deliveries only reach public addresses unless allowed, e.g. locally
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+		webhookOps = append(webhookOps, webhook.AllowPrivateNetworks())
+	}
+
+	webhookSvc := webhook.NewService(webhookRepo, deliveryQueue, webhookOps...)
+
 	// service
-	svc := product.NewService(repo)
+	attributeSvc := attribute.NewService(attributeRepo)
+	svc := product.NewService(
+		product.NewOutboxRepository(repo, outboxStore),
+		product.WithAttributes(attributeSvc),
+		product.WithStockRecorder(ledger),
+	)
+
+	supplierSvc := supplier.NewService(supplierRepo, svc)
+	orderSvc := purchase.NewService(orderRepo, supplierSvc, svc)
+
+	velocityDays, _ := strconv.Atoi(os.Getenv("REORDER_VELOCITY_DAYS"))
+	reorderSvc := reorder.NewService(
+		svc,
+		supplierSvc,
+		ledger,
+		reorder.Window(time.Duration(velocityDays)*24*time.Hour),
+	)
+
+	maxMediaSize, _ := strconv.ParseInt(os.Getenv("MEDIA_MAX_SIZE"), 10, 64)
+	maxMediaDimension, _ := strconv.Atoi(os.Getenv("MEDIA_MAX_DIMENSION"))
+	mediaSvc := media.NewService(
+		media.NewDiskStore(os.Getenv("MEDIA_DIR")),
+		svc,
+		media.BaseURL(os.Getenv("MEDIA_BASE_URL")),
+		media.MaxSize(maxMediaSize),
+		media.MaxDimension(maxMediaDimension),
+	)
+
+	keySvc := apikey.NewService(keyRepo)
+
+	// auth
+	verifierOps := []jwt.Option{
+		jwt.HS256Secret([]byte(os.Getenv("JWT_HS256_SECRET"))),
+		jwt.Audience(os.Getenv("JWT_AUDIENCE")),
+		jwt.Issuer(os.Getenv("JWT_ISSUER")),
+	}
+
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		keys, err := jwt.LoadJWKS(path)
+		if err != nil {
+			log.Println(fmt.Errorf("error: %w", err))
+		} else {
+			verifierOps = append(verifierOps, jwt.RS256Keys(keys))
+		}
+	}
+
+	policy := handler.DefaultPolicy()
+
+	if path := os.Getenv("RBAC_POLICY_FILE"); path != "" {
+		policy, err = rbac.LoadPolicy(path)
+		if err != nil {
+			log.Fatal(fmt.Errorf("error: %w", err))
+		}
+	}
+
+	var authOps []handler.AuthOption
+
+	if attempts, err := strconv.Atoi(os.Getenv("AUTH_LOCKOUT_ATTEMPTS")); err == nil && attempts > 0 {
+		window, _ := time.ParseDuration(os.Getenv("AUTH_LOCKOUT_WINDOW"))
+		if window <= 0 {
+			window = 15 * time.Minute
+		}
+
+		duration, _ := time.ParseDuration(os.Getenv("AUTH_LOCKOUT_DURATION"))
+		if duration <= 0 {
+			duration = 15 * time.Minute
+		}
+
+		authOps = append(authOps, handler.Lockout(attempts, window, duration))
+	}
+
+	limits := handler.DefaultRateLimits()
+
+	if path := os.Getenv("RATELIMIT_CONFIG_FILE"); path != "" {
+		limits, err = ratelimit.LoadConfig(path)
+		if err != nil {
+			log.Fatal(fmt.Errorf("error: %w", err))
+		}
+	}
+
+	rateLimit := handler.NewRateLimit(limits)
+	authOps = append(authOps, handler.RateLimiter(rateLimit))
+
+	ttl, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	authOps = append(authOps, handler.Idempotent(handler.NewIdempotency(idempotency.NewStore(ttl))))
+
+	auth := handler.NewAuth(jwt.NewVerifier(verifierOps...), policy, keySvc, authOps...)
+
+	// refuse to start when only anonymous requests could be served
+	err = auth.Check()
+	if err != nil {
+		log.Fatal(fmt.Errorf("error: %w", err))
+	}
 
 	// http server
 	mux := gin.Default()
-	handler.NewProduct(mux, svc)
-	server := httpserver.New(mux, httpserver.Port(os.Getenv("HTTP_SERVER_PORT")))
+
+	// client ips drive the lockout, so forwarded headers are only trusted
+	// from the configured proxies
+	var proxies []string
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		proxies = strings.Split(v, ",")
+	}
+
+	err = mux.SetTrustedProxies(proxies)
+	if err != nil {
+		log.Fatal(fmt.Errorf("error: %w", err))
+	}
+
+	// unversioned routes are served in the negotiated version
+	mux.Use(handler.NewVersioning(mux).Handle)
+
+	// requests are checked against the openapi document of the routes
+	var validationOps []handler.ValidationOption
+
+	if n, err := strconv.ParseInt(os.Getenv("MAX_BODY_SIZE"), 10, 64); err == nil {
+		validationOps = append(validationOps, handler.MaxBodySize(n))
+	}
+
+	mux.Use(handler.NewValidation(mux, validationOps...).Handle)
+
+	handler.NewProduct(mux, svc, auth)
+	handler.NewAttribute(mux, attributeSvc, auth)
+	handler.NewMedia(mux, mediaSvc, auth)
+	handler.NewSupplier(mux, supplierSvc, auth)
+	handler.NewPurchaseOrder(mux, orderSvc, auth)
+	handler.NewReorder(mux, reorderSvc, auth)
+	handler.NewAPIKey(mux, keySvc, auth)
+	handler.NewWebhook(mux, webhookSvc, auth)
+	handler.NewUsage(mux, rateLimit, auth)
+
+	var graphQLOps []handler.GraphQLOption
+
+	if n, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_COMPLEXITY")); err == nil {
+		graphQLOps = append(graphQLOps, handler.MaxComplexity(n))
+	}
+
+	if n, err := strconv.Atoi(os.Getenv("GRAPHQL_MAX_DEPTH")); err == nil {
+		graphQLOps = append(graphQLOps, handler.MaxDepth(n))
+	}
+
+	handler.NewGraphQL(mux, svc, auth, graphQLOps...)
+	handler.NewEvents(mux, bus, auth)
+	handler.NewStock(mux, svc, bus, auth)
+	handler.NewOpenAPI(mux)
+	server := httpserver.New(
+		handler.Streams(mux),
+		httpserver.Port(os.Getenv("HTTP_SERVER_PORT")),
+		httpserver.ConnContext(handler.StreamConn),
+	)
+
+	// grpc server
+	grpcMux := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryInterceptor))
+	handler.NewProductServer(grpcMux, svc)
+	grpcServer := grpcserver.New(grpcMux, grpcserver.Port(os.Getenv("GRPC_SERVER_PORT")))
+
+	// reorder evaluator
+	if interval, err := time.ParseDuration(os.Getenv("REORDER_EVALUATOR_INTERVAL")); err == nil && interval > 0 {
+		evaluator := reorder.NewEvaluator(reorderSvc, reorder.NewLogNotifier(), interval)
+		evaluator.Start()
+		defer evaluator.Stop()
+	}
+
+	// outbox relay, each committed event reaches every sink once
+	relayOps := []outbox.Option{
+		outbox.WithSink("bus", outbox.NewBusSink(bus, product.DecodeEvent)),
+		outbox.WithSink("webhooks", webhook.NewSink(webhookSvc)),
+	}
+
+	if path := os.Getenv("OUTBOX_EVENTS_FILE"); path != "" {
+		fileSink, err := outbox.NewFileSink(path)
+		if err != nil {
+			log.Println(fmt.Errorf("error: %w", err))
+		} else {
+			relayOps = append(relayOps, outbox.WithSink("file", fileSink))
+		}
+	}
+
+	if os.Getenv("OUTBOX_LOG_EVENTS") == "true" {
+		relayOps = append(relayOps, outbox.WithSink("log", outbox.NewLogSink()))
+	}
+
+	relay := outbox.NewRelay(outboxStore, relayOps...)
+	relay.Start()
+	defer relay.Stop()
+
+	// webhook deliveries, pending ones are resumed after a restart
+	dispatchInterval, err := time.ParseDuration(os.Getenv("WEBHOOK_DISPATCH_INTERVAL"))
+	if err != nil || dispatchInterval <= 0 {
+		dispatchInterval = 5 * time.Second
+	}
+
+	dispatcher := webhook.NewDispatcher(webhookSvc, dispatchInterval)
+	dispatcher.Start()
+	defer dispatcher.Stop()
 
 	// signal
 	interrupt := make(chan os.Signal, 1)
@@ -44,10 +323,18 @@ func main() {
 		log.Println("signal:", s.String())
 	case err := <-server.Notify():
 		log.Println(fmt.Errorf("error: %w", err))
+	case err := <-grpcServer.Notify():
+		log.Println(fmt.Errorf("error: %w", err))
 	}
 
+	// both servers are drained before exiting
 	err = server.Shutdown()
 	if err != nil {
 		log.Println(fmt.Errorf("error: %w", err))
 	}
+
+	err = grpcServer.Shutdown()
+	if err != nil {
+		log.Println(fmt.Errorf("error: %w", err))
+	}
 }
@@ -0,0 +1,17 @@
+package apikey
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidData = errors.New("invalid api key data")
+	ErrCreation    = errors.New("unable to create api key")
+	ErrNotFound    = errors.New("unable to find api key")
+
+	ErrInvalidId    = errors.New("invalid api key id")
+	ErrInvalidScope = errors.New("invalid api key scope")
+	ErrInvalidKey   = errors.New("invalid api key")
+	ErrExpired      = errors.New("api key is expired")
+	ErrRevoked      = errors.New("api key is revoked")
+)
@@ -0,0 +1,108 @@
+package apikey
+
+import (
+	"os"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type APIKeyRepository interface {
+	All() ([]domain.APIKey, error)
+	GetById(int) (domain.APIKey, error)
+	GetByPrefix(string) (domain.APIKey, error)
+	Create(domain.APIKey) (domain.APIKey, error)
+	Update(domain.APIKey) error
+	SetLastUsed(map[int]time.Time) error
+}
+
+type repository struct {
+	Keys   []domain.APIKey `json:"api_keys"`
+	lastId int
+}
+
+func NewRepository() (APIKeyRepository, error) {
+	r := &repository{}
+
+	err := storage.ReadFile(os.Getenv("APIKEYS_FILENAME"), &r.Keys)
+	if err != nil {
+		return r, err
+	}
+
+	if len(r.Keys) > 0 {
+		r.lastId = r.Keys[len(r.Keys)-1].Id
+	}
+
+	return r, nil
+}
+
+func (r *repository) All() ([]domain.APIKey, error) {
+	return r.Keys, nil
+}
+
+func (r *repository) GetById(id int) (domain.APIKey, error) {
+	for _, k := range r.Keys {
+		if k.Id == id {
+			return k, nil
+		}
+	}
+
+	return domain.APIKey{}, ErrNotFound
+}
+
+func (r *repository) GetByPrefix(prefix string) (domain.APIKey, error) {
+	for _, k := range r.Keys {
+		if k.Prefix == prefix {
+			return k, nil
+		}
+	}
+
+	return domain.APIKey{}, ErrNotFound
+}
+
+func (r *repository) Create(k domain.APIKey) (domain.APIKey, error) {
+	r.lastId++
+	k.Id = r.lastId
+	r.Keys = append(r.Keys, k)
+
+	err := storage.WriteFile(os.Getenv("APIKEYS_FILENAME"), &r.Keys)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+
+	return k, nil
+}
+
+func (r *repository) Update(k domain.APIKey) error {
+	for i, key := range r.Keys {
+		if key.Id == k.Id {
+			r.Keys[i] = k
+
+			err := storage.WriteFile(os.Getenv("APIKEYS_FILENAME"), &r.Keys)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// SetLastUsed stamps the last used time of several keys in one write.
+func (r *repository) SetLastUsed(lastUsed map[int]time.Time) error {
+	if len(lastUsed) == 0 {
+		return nil
+	}
+
+	for i, k := range r.Keys {
+		if t, ok := lastUsed[k.Id]; ok {
+			t := t
+			r.Keys[i].LastUsedAt = &t
+		}
+	}
+
+	return storage.WriteFile(os.Getenv("APIKEYS_FILENAME"), &r.Keys)
+}
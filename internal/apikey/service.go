@@ -0,0 +1,301 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeOrdersWrite   = "orders:write"
+
+	keyPrefix = "gw"
+
+	// lastUsedInterval limits how often last used times are persisted.
+	lastUsedInterval = time.Minute
+
+	// DefaultGracePeriod keeps a rotated key working while clients move to
+	// the new one, up to MaxGracePeriod.
+	DefaultGracePeriod = 24 * time.Hour
+	MaxGracePeriod     = 7 * 24 * time.Hour
+)
+
+var scopes = map[string]bool{
+	ScopeProductsRead:  true,
+	ScopeProductsWrite: true,
+	ScopeOrdersWrite:   true,
+}
+
+type APIKeyService interface {
+	All() ([]domain.APIKey, error)
+	GetById(int) (domain.APIKey, error)
+	Create(string, []string, *time.Time) (domain.APIKey, string, error)
+	Revoke(int) error
+	Rotate(int, time.Duration) (domain.APIKey, string, error)
+	Authenticate(string) (domain.APIKey, error)
+	HasActive() (bool, error)
+}
+
+type service struct {
+	repo APIKeyRepository
+	mu   sync.Mutex
+	now  func() time.Time
+
+	// last used times waiting to be persisted, in one write per interval
+	lastUsed  map[int]time.Time
+	flushedAt time.Time
+}
+
+func NewService(r APIKeyRepository) APIKeyService {
+	return &service{
+		repo:     r,
+		now:      time.Now,
+		lastUsed: make(map[int]time.Time),
+	}
+}
+
+func (s *service) All() ([]domain.APIKey, error) {
+	return s.repo.All()
+}
+
+func (s *service) GetById(id int) (domain.APIKey, error) {
+	return s.repo.GetById(id)
+}
+
+// Create generates a new key and returns it in plain text. Only its hash is
+// stored, so it cannot be recovered afterwards.
+func (s *service) Create(name string, keyScopes []string, expiresAt *time.Time) (domain.APIKey, string, error) {
+	k := domain.APIKey{
+		Name:      name,
+		Scopes:    keyScopes,
+		CreatedAt: s.now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+
+	if err := validator.New().Struct(&k); err != nil {
+		return domain.APIKey{}, "", ErrInvalidData
+	}
+
+	for _, scope := range keyScopes {
+		if !scopes[scope] {
+			return domain.APIKey{}, "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	if expiresAt != nil && k.IsExpired(s.now()) {
+		return domain.APIKey{}, "", ErrInvalidData
+	}
+
+	plain, err := generate(&k)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, err = s.repo.Create(k)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	return k, plain, nil
+}
+
+func (s *service) Revoke(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	if k.IsRevoked() {
+		return nil
+	}
+
+	now := s.now().UTC()
+	k.RevokedAt = &now
+
+	return s.repo.Update(k)
+}
+
+// Rotate replaces the key with a new one of the same name, scopes and
+// expiration, returned in plain text. The old key keeps working for the
+// grace period.
+func (s *service) Rotate(id int, grace time.Duration) (domain.APIKey, string, error) {
+	if grace < 0 || grace > MaxGracePeriod {
+		return domain.APIKey{}, "", ErrInvalidData
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.repo.GetById(id)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	now := s.now().UTC()
+
+	if old.IsRevoked() {
+		return domain.APIKey{}, "", ErrRevoked
+	}
+
+	if old.IsExpired(now) {
+		return domain.APIKey{}, "", ErrExpired
+	}
+
+	k := domain.APIKey{
+		Name:      old.Name,
+		Scopes:    old.Scopes,
+		CreatedAt: now,
+		ExpiresAt: old.ExpiresAt,
+	}
+
+	plain, err := generate(&k)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	k, err = s.repo.Create(k)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	// keys expiring within the grace period keep their expiration
+	expiresAt := now.Add(grace)
+	if !old.IsExpired(expiresAt) {
+		old.ExpiresAt = &expiresAt
+
+		err = s.repo.Update(old)
+		if err != nil {
+			return domain.APIKey{}, "", err
+		}
+	}
+
+	return k, plain, nil
+}
+
+// Authenticate checks a plain text key and records its use. Last used times
+// are persisted together, at most once per interval.
+func (s *service) Authenticate(plain string) (domain.APIKey, error) {
+	parts := strings.Split(plain, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix {
+		return domain.APIKey{}, ErrInvalidKey
+	}
+
+	// hash even for unknown prefixes, so both paths take the same time
+	sum := hash(plain)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, err := s.repo.GetByPrefix(parts[1])
+	if err != nil {
+		return domain.APIKey{}, ErrInvalidKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(sum)) != 1 {
+		return domain.APIKey{}, ErrInvalidKey
+	}
+
+	now := s.now().UTC()
+
+	if k.IsRevoked() {
+		return domain.APIKey{}, ErrRevoked
+	}
+
+	if k.IsExpired(now) {
+		return domain.APIKey{}, ErrExpired
+	}
+
+	k.LastUsedAt = &now
+	s.lastUsed[k.Id] = now
+
+	if now.Sub(s.flushedAt) >= lastUsedInterval {
+		s.flushLastUsed(now)
+	}
+
+	return k, nil
+}
+
+// flushLastUsed persists the pending last used times in a single write.
+func (s *service) flushLastUsed(now time.Time) {
+	err := s.repo.SetLastUsed(s.lastUsed)
+	if err != nil {
+		log.Println(fmt.Errorf("[apikey.flushLastUsed] error: %w", err))
+		return
+	}
+
+	s.lastUsed = make(map[int]time.Time)
+	s.flushedAt = now
+}
+
+// HasActive reports whether any key can still authenticate.
+func (s *service) HasActive() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.repo.All()
+	if err != nil {
+		return false, err
+	}
+
+	now := s.now()
+	for _, k := range keys {
+		if !k.IsRevoked() && !k.IsExpired(now) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// generate sets a new prefix and hash on the key and returns it in plain
+// text.
+func generate(k *domain.APIKey) (string, error) {
+	prefix, err := randomHex(4)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCreation, err.Error())
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCreation, err.Error())
+	}
+
+	plain := fmt.Sprintf("%s_%s_%s", keyPrefix, prefix, secret)
+	k.Prefix = prefix
+	k.Hash = hash(plain)
+
+	return plain, nil
+}
+
+func hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,218 @@
+package apikey
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+var testNow = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestService stores the keys in a temporary file, with a clock set to
+// testNow.
+func newTestService(t *testing.T) (*service, *time.Time, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	require.NoError(t, storage.WriteFile(path, []domain.APIKey{}))
+	t.Setenv("APIKEYS_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	now := testNow
+	s := NewService(repo).(*service)
+	s.now = func() time.Time { return now }
+
+	return s, &now, path
+}
+
+func storedKeys(t *testing.T, path string) []domain.APIKey {
+	t.Helper()
+
+	var ks []domain.APIKey
+	require.NoError(t, storage.ReadFile(path, &ks))
+
+	return ks
+}
+
+func TestCreateStoresHash(t *testing.T) {
+	s, _, path := newTestService(t)
+
+	k, plain, err := s.Create("ci", []string{ScopeProductsRead}, nil)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(plain, keyPrefix+"_"+k.Prefix+"_"))
+	assert.Equal(t, hash(plain), k.Hash)
+
+	// only the hash reaches the file
+	ks := storedKeys(t, path)
+	require.Len(t, ks, 1)
+	assert.Equal(t, hash(plain), ks[0].Hash)
+	assert.NotContains(t, ks[0].Hash, strings.Split(plain, "_")[2])
+
+	_, _, err = s.Create("ci", []string{"products:everything"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, now, _ := newTestService(t)
+
+	k, plain, err := s.Create("ci", []string{ScopeProductsRead}, nil)
+	require.NoError(t, err)
+
+	got, err := s.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, k.Id, got.Id)
+
+	parts := strings.Split(plain, "_")
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"malformed", "not-a-key"},
+		{"other prefix", "xx_" + parts[1] + "_" + parts[2]},
+		{"unknown key prefix", keyPrefix + "_00000000_" + parts[2]},
+		{"wrong secret", keyPrefix + "_" + parts[1] + "_" + strings.Repeat("0", len(parts[2]))},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Authenticate(tc.key)
+			assert.ErrorIs(t, err, ErrInvalidKey)
+		})
+	}
+
+	expiresAt := now.Add(time.Hour)
+	_, expiring, err := s.Create("short", []string{ScopeProductsRead}, &expiresAt)
+	require.NoError(t, err)
+
+	*now = now.Add(time.Hour)
+
+	_, err = s.Authenticate(expiring)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestAuthenticateLastUsed(t *testing.T) {
+	s, now, path := newTestService(t)
+
+	a, plainA, err := s.Create("a", []string{ScopeProductsRead}, nil)
+	require.NoError(t, err)
+	b, plainB, err := s.Create("b", []string{ScopeProductsRead}, nil)
+	require.NoError(t, err)
+
+	// the first use is written at once
+	_, err = s.Authenticate(plainA)
+	require.NoError(t, err)
+
+	ks := storedKeys(t, path)
+	require.NotNil(t, ks[0].LastUsedAt)
+	assert.Equal(t, testNow, *ks[0].LastUsedAt)
+
+	// later uses wait for the interval, then are written together
+	*now = now.Add(time.Second)
+
+	_, err = s.Authenticate(plainB)
+	require.NoError(t, err)
+	assert.Nil(t, storedKeys(t, path)[1].LastUsedAt)
+
+	*now = now.Add(lastUsedInterval)
+
+	_, err = s.Authenticate(plainA)
+	require.NoError(t, err)
+
+	ks = storedKeys(t, path)
+	assert.Equal(t, a.Id, ks[0].Id)
+	assert.Equal(t, *now, *ks[0].LastUsedAt)
+	assert.Equal(t, b.Id, ks[1].Id)
+	assert.Equal(t, testNow.Add(time.Second), *ks[1].LastUsedAt)
+}
+
+func TestRevoke(t *testing.T) {
+	s, _, _ := newTestService(t)
+
+	k, plain, err := s.Create("ci", []string{ScopeProductsRead}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Revoke(k.Id))
+	// revoking twice is a no-op
+	require.NoError(t, s.Revoke(k.Id))
+
+	_, err = s.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	ok, err := s.HasActive()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, s.Revoke(99), ErrNotFound)
+}
+
+func TestRotate(t *testing.T) {
+	s, now, _ := newTestService(t)
+
+	old, oldPlain, err := s.Create("ci", []string{ScopeProductsRead, ScopeOrdersWrite}, nil)
+	require.NoError(t, err)
+
+	k, plain, err := s.Rotate(old.Id, time.Hour)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, old.Id, k.Id)
+	assert.NotEqual(t, oldPlain, plain)
+	assert.Equal(t, old.Name, k.Name)
+	assert.Equal(t, old.Scopes, k.Scopes)
+
+	// both keys work during the grace period
+	_, err = s.Authenticate(oldPlain)
+	assert.NoError(t, err)
+	_, err = s.Authenticate(plain)
+	assert.NoError(t, err)
+
+	*now = now.Add(time.Hour)
+
+	_, err = s.Authenticate(oldPlain)
+	assert.ErrorIs(t, err, ErrExpired)
+	_, err = s.Authenticate(plain)
+	assert.NoError(t, err)
+
+	// the expired key cannot be rotated again
+	_, _, err = s.Rotate(old.Id, time.Hour)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestRotateErrors(t *testing.T) {
+	s, now, _ := newTestService(t)
+
+	expiresAt := now.Add(time.Minute)
+	expiring, _, err := s.Create("short", []string{ScopeProductsRead}, &expiresAt)
+	require.NoError(t, err)
+
+	// keys expiring within the grace period keep their expiration
+	k, _, err := s.Rotate(expiring.Id, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, *k.ExpiresAt)
+
+	old, err := s.GetById(expiring.Id)
+	require.NoError(t, err)
+	assert.Equal(t, expiresAt, *old.ExpiresAt)
+
+	_, _, err = s.Rotate(k.Id, -time.Second)
+	assert.ErrorIs(t, err, ErrInvalidData)
+
+	_, _, err = s.Rotate(k.Id, MaxGracePeriod+time.Second)
+	assert.ErrorIs(t, err, ErrInvalidData)
+
+	require.NoError(t, s.Revoke(k.Id))
+
+	_, _, err = s.Rotate(k.Id, time.Hour)
+	assert.ErrorIs(t, err, ErrRevoked)
+
+	_, _, err = s.Rotate(99, time.Hour)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,14 @@
+package attribute
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidData = errors.New("invalid attribute data")
+	ErrNotFound    = errors.New("unable to find attribute")
+
+	ErrDuplicatedName = errors.New("duplicated attribute name")
+	ErrUnknown        = errors.New("unknown attribute")
+	ErrMissing        = errors.New("missing required attribute")
+)
@@ -0,0 +1,78 @@
+package attribute
+
+import (
+	"os"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type AttributeRepository interface {
+	All() ([]domain.Attribute, error)
+	Get(string) (domain.Attribute, error)
+	Create(domain.Attribute) error
+	Delete(string) error
+}
+
+type repository struct {
+	Attributes []domain.Attribute `json:"attributes"`
+}
+
+func NewRepository() (AttributeRepository, error) {
+	r := &repository{}
+
+	err := storage.ReadFile(os.Getenv("ATTRIBUTES_FILENAME"), &r.Attributes)
+	if err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+func (r *repository) All() ([]domain.Attribute, error) {
+	return r.Attributes, nil
+}
+
+func (r *repository) Get(name string) (domain.Attribute, error) {
+	for _, a := range r.Attributes {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+
+	return domain.Attribute{}, ErrNotFound
+}
+
+func (r *repository) Create(a domain.Attribute) error {
+	for _, attribute := range r.Attributes {
+		if attribute.Name == a.Name {
+			return ErrDuplicatedName
+		}
+	}
+
+	r.Attributes = append(r.Attributes, a)
+
+	err := storage.WriteFile(os.Getenv("ATTRIBUTES_FILENAME"), &r.Attributes)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) Delete(name string) error {
+	for i, attribute := range r.Attributes {
+		if attribute.Name == name {
+			r.Attributes = append(r.Attributes[:i], r.Attributes[i+1:]...)
+
+			err := storage.WriteFile(os.Getenv("ATTRIBUTES_FILENAME"), &r.Attributes)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
@@ -0,0 +1,122 @@
+package attribute
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/go-playground/validator"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+type AttributeService interface {
+	All() ([]domain.Attribute, error)
+	Get(string) (domain.Attribute, error)
+	Create(domain.Attribute) error
+	Delete(string) error
+	Validate(map[string]any) (map[string]any, error)
+	Match(map[string]any, map[string]string) (bool, error)
+}
+
+type service struct {
+	repo AttributeRepository
+}
+
+func NewService(r AttributeRepository) AttributeService {
+	return &service{
+		repo: r,
+	}
+}
+
+func (s *service) All() ([]domain.Attribute, error) {
+	return s.repo.All()
+}
+
+func (s *service) Get(name string) (domain.Attribute, error) {
+	return s.repo.Get(name)
+}
+
+func (s *service) Create(a domain.Attribute) error {
+	if err := validator.New().Struct(&a); err != nil {
+		return ErrInvalidData
+	}
+
+	if a.Pattern != "" {
+		if _, err := regexp.Compile(a.Pattern); err != nil {
+			return ErrInvalidData
+		}
+	}
+
+	return s.repo.Create(a)
+}
+
+func (s *service) Delete(name string) error {
+	return s.repo.Delete(name)
+}
+
+// Validate checks product attribute values against the defined attributes
+// and returns them converted to their types. Null values are dropped.
+func (s *service) Validate(values map[string]any) (map[string]any, error) {
+	attributes, err := s.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(attributes))
+	parsed := make(map[string]any, len(values))
+
+	for _, a := range attributes {
+		known[a.Name] = true
+
+		raw, ok := values[a.Name]
+		if !ok || raw == nil {
+			if a.Required {
+				return nil, fmt.Errorf("%w: %s", ErrMissing, a.Name)
+			}
+			continue
+		}
+
+		v, err := a.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed[a.Name] = v
+	}
+
+	for name := range values {
+		if !known[name] {
+			return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Match reports whether product attribute values equal all the filters,
+// which are parsed according to the attribute types.
+func (s *service) Match(values map[string]any, filters map[string]string) (bool, error) {
+	for name, filter := range filters {
+		a, err := s.repo.Get(name)
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", ErrUnknown, name)
+		}
+
+		want, err := a.ParseString(filter)
+		if err != nil {
+			return false, err
+		}
+
+		got, ok := values[name]
+		if !ok {
+			return false, nil
+		}
+
+		// values read from storage are not typed yet
+		got, err = a.Parse(got)
+		if err != nil || got != want {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
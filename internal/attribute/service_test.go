@@ -0,0 +1,157 @@
+package attribute
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+func bound(f float64) *float64 {
+	return &f
+}
+
+func newTestService(t *testing.T) AttributeService {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "attributes.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Attribute{
+		{Name: "color", Type: domain.AttributeString, Required: true, Enum: []string{"red", "blue"}},
+		{Name: "weight", Type: domain.AttributeFloat, Min: bound(0), Max: bound(100)},
+		{Name: "pieces", Type: domain.AttributeInt, Min: bound(1)},
+		{Name: "fragile", Type: domain.AttributeBool},
+		{Name: "harvest", Type: domain.AttributeDate},
+		{Name: "batch", Type: domain.AttributeString, Pattern: "^B[0-9]+$", Max: bound(5)},
+	}))
+	t.Setenv("ATTRIBUTES_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	return NewService(repo)
+}
+
+func TestCreate(t *testing.T) {
+	s := newTestService(t)
+
+	require.NoError(t, s.Create(domain.Attribute{Name: "origin", Type: domain.AttributeString}))
+
+	// attributes are stored
+	repo, err := NewRepository()
+	require.NoError(t, err)
+	_, err = repo.Get("origin")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		attribute domain.Attribute
+		err       error
+	}{
+		{"duplicated name", domain.Attribute{Name: "color", Type: domain.AttributeString}, ErrDuplicatedName},
+		{"missing name", domain.Attribute{Type: domain.AttributeString}, ErrInvalidData},
+		{"invalid name", domain.Attribute{Name: "shelf life", Type: domain.AttributeString}, ErrInvalidData},
+		{"unknown type", domain.Attribute{Name: "size", Type: "list"}, ErrInvalidData},
+		{"invalid pattern", domain.Attribute{Name: "size", Type: domain.AttributeString, Pattern: "("}, ErrInvalidData},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.ErrorIs(t, s.Create(test.attribute), test.err)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := newTestService(t)
+
+	require.NoError(t, s.Delete("batch"))
+	_, err := s.Get("batch")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, s.Delete("batch"), ErrNotFound)
+}
+
+func TestValidate(t *testing.T) {
+	s := newTestService(t)
+
+	// values are converted to their types, and nulls are dropped
+	values, err := s.Validate(map[string]any{
+		"color":   "red",
+		"weight":  2.5,
+		"pieces":  float64(3),
+		"fragile": true,
+		"harvest": "20/01/2030",
+		"batch":   nil,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"color":   "red",
+		"weight":  2.5,
+		"pieces":  int64(3),
+		"fragile": true,
+		"harvest": "20/01/2030",
+	}, values)
+
+	tests := []struct {
+		name   string
+		values map[string]any
+		err    error
+	}{
+		{"missing required", map[string]any{"weight": 1.0}, ErrMissing},
+		{"null required", map[string]any{"color": nil}, ErrMissing},
+		{"unknown", map[string]any{"color": "red", "size": "M"}, ErrUnknown},
+		{"not in enum", map[string]any{"color": "green"}, domain.ErrAttributeValue},
+		{"wrong type", map[string]any{"color": "red", "fragile": "yes"}, domain.ErrAttributeValue},
+		{"not an integer", map[string]any{"color": "red", "pieces": 1.5}, domain.ErrAttributeValue},
+		{"below min", map[string]any{"color": "red", "weight": -1.0}, domain.ErrAttributeValue},
+		{"above max", map[string]any{"color": "red", "weight": 101.0}, domain.ErrAttributeValue},
+		{"invalid date", map[string]any{"color": "red", "harvest": "2030-01-20"}, domain.ErrAttributeValue},
+		{"not matching", map[string]any{"color": "red", "batch": "C12"}, domain.ErrAttributeValue},
+		// min and max bound the length of strings
+		{"too long", map[string]any{"color": "red", "batch": "B12345"}, domain.ErrAttributeValue},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := s.Validate(test.values)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s := newTestService(t)
+
+	// values read from storage are decoded as float64
+	values := map[string]any{"color": "red", "pieces": float64(3), "fragile": true}
+
+	tests := []struct {
+		name    string
+		filters map[string]string
+		match   bool
+		err     error
+	}{
+		{"no filters", nil, true, nil},
+		{"all equal", map[string]string{"color": "red", "pieces": "3", "fragile": "true"}, true, nil},
+		{"different value", map[string]string{"pieces": "4"}, false, nil},
+		{"missing value", map[string]string{"weight": "1"}, false, nil},
+		{"unknown attribute", map[string]string{"size": "M"}, false, ErrUnknown},
+		{"invalid filter", map[string]string{"fragile": "maybe"}, false, domain.ErrAttributeValue},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := s.Match(values, test.filters)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.match, ok)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type AttributeType string
+
+const (
+	AttributeString AttributeType = "string"
+	AttributeInt    AttributeType = "int"
+	AttributeFloat  AttributeType = "float"
+	AttributeBool   AttributeType = "bool"
+	AttributeDate   AttributeType = "date"
+)
+
+var ErrAttributeValue = errors.New("invalid attribute value")
+
+// Attribute defines a custom product attribute. Min and Max bound numeric
+// values, or the length of string values.
+type Attribute struct {
+	Name     string        `json:"name" validate:"required,alphanum"`
+	Type     AttributeType `json:"type" validate:"required,oneof=string int float bool date"`
+	Required bool          `json:"required"`
+	Min      *float64      `json:"min,omitempty"`
+	Max      *float64      `json:"max,omitempty"`
+	Pattern  string        `json:"pattern,omitempty"`
+	Enum     []string      `json:"enum,omitempty"`
+}
+
+// Parse converts a raw value (as decoded from JSON) into the attribute type
+// and checks it against the attribute constraints.
+func (a *Attribute) Parse(value any) (any, error) {
+	var v any
+
+	switch a.Type {
+	case AttributeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, a.errorf("expected string")
+		}
+		v = s
+	case AttributeInt:
+		f, ok := toFloat(value)
+		if !ok || f != math.Trunc(f) {
+			return nil, a.errorf("expected integer")
+		}
+		v = int64(f)
+	case AttributeFloat:
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, a.errorf("expected number")
+		}
+		v = f
+	case AttributeBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, a.errorf("expected boolean")
+		}
+		v = b
+	case AttributeDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, a.errorf("expected date")
+		}
+		if _, err := time.Parse("02/01/2006", s); err != nil {
+			return nil, a.errorf("expected date with format DD/MM/YYYY")
+		}
+		v = s
+	default:
+		return nil, a.errorf("unknown type %s", a.Type)
+	}
+
+	if err := a.check(v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// ParseString converts a string, e.g. a query parameter, into the attribute
+// type.
+func (a *Attribute) ParseString(s string) (any, error) {
+	switch a.Type {
+	case AttributeInt, AttributeFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, a.errorf("expected number")
+		}
+		return a.Parse(f)
+	case AttributeBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, a.errorf("expected boolean")
+		}
+		return a.Parse(b)
+	default:
+		return a.Parse(s)
+	}
+}
+
+func (a *Attribute) check(v any) error {
+	var n float64
+
+	switch t := v.(type) {
+	case string:
+		n = float64(len(t))
+	case int64:
+		n = float64(t)
+	case float64:
+		n = t
+	}
+
+	if a.Min != nil && n < *a.Min {
+		return a.errorf("lower than %v", *a.Min)
+	}
+
+	if a.Max != nil && n > *a.Max {
+		return a.errorf("greater than %v", *a.Max)
+	}
+
+	if a.Pattern != "" {
+		s, ok := v.(string)
+		if ok {
+			matched, err := regexp.MatchString(a.Pattern, s)
+			if err != nil || !matched {
+				return a.errorf("does not match %s", a.Pattern)
+			}
+		}
+	}
+
+	if len(a.Enum) > 0 {
+		s := fmt.Sprint(v)
+		for _, e := range a.Enum {
+			if e == s {
+				return nil
+			}
+		}
+		return a.errorf("not one of %v", a.Enum)
+	}
+
+	return nil
+}
+
+func (a *Attribute) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s: %s", ErrAttributeValue, a.Name, fmt.Sprintf(format, args...))
+}
+
+func toFloat(value any) (float64, bool) {
+	switch t := value.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	}
+
+	return 0, false
+}
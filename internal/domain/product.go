@@ -6,13 +6,19 @@ import (
 )
 
 type Product struct {
-	Id          int     `json:"id"`
-	Name        string  `json:"name" validate:"required"`
-	Quantity    int     `json:"quantity" validate:"required,gte=1"`
-	CodeValue   string  `json:"code_value" validate:"required"`
-	IsPublished bool    `json:"is_published"`
-	Expiration  string  `json:"expiration" validate:"required"`
-	Price       float64 `json:"price" validate:"required,gte=0"`
+	Id          int            `json:"id"`
+	Name        string         `json:"name" validate:"required"`
+	Quantity    int            `json:"quantity" validate:"required,gte=1"`
+	CodeValue   string         `json:"code_value" validate:"required"`
+	IsPublished bool           `json:"is_published"`
+	Expiration  string         `json:"expiration" validate:"required"`
+	Price       float64        `json:"price" validate:"required,gte=0"`
+	Variants    []Variant      `json:"variants,omitempty" validate:"dive"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	Media       []Media        `json:"media,omitempty"`
+
+	ReorderPoint    int `json:"reorder_point,omitempty" validate:"gte=0"`
+	ReorderQuantity int `json:"reorder_quantity,omitempty" validate:"gte=0"`
 }
 
 func (p *Product) IsExpirationValid() bool {
@@ -24,6 +30,16 @@ func (p *Product) IsExpirationValid() bool {
 	return !expDate.Before(time.Now())
 }
 
+func (p *Product) Variant(codeValue string) (Variant, bool) {
+	for _, v := range p.Variants {
+		if v.CodeValue == codeValue {
+			return v, true
+		}
+	}
+
+	return Variant{}, false
+}
+
 func (p *Product) ToDDMMYYYY() error {
 	expDate, err := time.Parse("02/01/2006", p.Expiration)
 	if err != nil {
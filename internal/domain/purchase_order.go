@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderOpen      PurchaseOrderStatus = "open"
+	PurchaseOrderReceived  PurchaseOrderStatus = "received"
+	PurchaseOrderCancelled PurchaseOrderStatus = "cancelled"
+)
+
+type PurchaseOrder struct {
+	Id         int                 `json:"id"`
+	SupplierId int                 `json:"supplier_id" validate:"required"`
+	Status     PurchaseOrderStatus `json:"status"`
+	Lines      []PurchaseOrderLine `json:"lines" validate:"required,min=1,dive"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ReceivedAt *time.Time          `json:"received_at,omitempty"`
+}
+
+type PurchaseOrderLine struct {
+	ProductId int     `json:"product_id" validate:"required"`
+	Quantity  int     `json:"quantity" validate:"required,gte=1"`
+	CostPrice float64 `json:"cost_price" validate:"gte=0"`
+}
+
+// Margin compares an order line cost against the product sale price.
+type Margin struct {
+	ProductId int     `json:"product_id"`
+	Quantity  int     `json:"quantity"`
+	CostPrice float64 `json:"cost_price"`
+	Price     float64 `json:"price"`
+	Margin    float64 `json:"margin"`
+	Percent   float64 `json:"margin_percent"`
+}
+
+func NewMargin(l PurchaseOrderLine, price float64) Margin {
+	m := Margin{
+		ProductId: l.ProductId,
+		Quantity:  l.Quantity,
+		CostPrice: l.CostPrice,
+		Price:     price,
+		Margin:    (price - l.CostPrice) * float64(l.Quantity),
+	}
+
+	if price > 0 {
+		m.Percent = (price - l.CostPrice) / price * 100
+	}
+
+	return m
+}
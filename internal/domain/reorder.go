@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// StockMovement records a change in a product quantity. Negative deltas are
+// stock leaving the catalog, e.g. sales.
+type StockMovement struct {
+	ProductId int       `json:"product_id"`
+	Delta     int       `json:"delta"`
+	At        time.Time `json:"at"`
+}
+
+type ReorderSuggestion struct {
+	ProductId         int     `json:"product_id"`
+	Name              string  `json:"name"`
+	Quantity          int     `json:"quantity"`
+	ReorderPoint      int     `json:"reorder_point"`
+	ReorderQuantity   int     `json:"reorder_quantity"`
+	DailyVelocity     float64 `json:"daily_velocity"`
+	LeadTimeDays      int     `json:"lead_time_days"`
+	SuggestedQuantity int     `json:"suggested_quantity"`
+}
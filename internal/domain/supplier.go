@@ -0,0 +1,26 @@
+package domain
+
+type Supplier struct {
+	Id       int               `json:"id"`
+	Name     string            `json:"name" validate:"required"`
+	Email    string            `json:"email" validate:"omitempty,email"`
+	Phone    string            `json:"phone"`
+	Products []SupplierProduct `json:"products,omitempty" validate:"dive"`
+}
+
+// SupplierProduct links a product to a supplier that can restock it.
+type SupplierProduct struct {
+	ProductId    int     `json:"product_id" validate:"required"`
+	CostPrice    float64 `json:"cost_price" validate:"required,gte=0"`
+	LeadTimeDays int     `json:"lead_time_days" validate:"gte=0"`
+}
+
+func (s *Supplier) Product(productId int) (SupplierProduct, bool) {
+	for _, sp := range s.Products {
+		if sp.ProductId == productId {
+			return sp, true
+		}
+	}
+
+	return SupplierProduct{}, false
+}
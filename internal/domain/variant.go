@@ -0,0 +1,14 @@
+package domain
+
+type Variant struct {
+	CodeValue  string            `json:"code_value" validate:"required"`
+	Price      float64           `json:"price" validate:"required,gte=0"`
+	Quantity   int               `json:"quantity" validate:"gte=0"`
+	Attributes VariantAttributes `json:"attributes"`
+}
+
+type VariantAttributes struct {
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+	Pack  int    `json:"pack,omitempty" validate:"gte=0"`
+}
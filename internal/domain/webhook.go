@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	Id        int       `json:"id"`
+	URL       string    `json:"url" validate:"required,url"`
+	Events    []string  `json:"events" validate:"required,min=1"`
+	Secret    string    `json:"secret" validate:"required,min=16"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed reports whether the webhook receives the event.
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead deliveries ran out of attempts, and are only sent again
+	// when redelivered.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Delivery is an event queued for a webhook, with the log of its attempts.
+type Delivery struct {
+	Id        int               `json:"id"`
+	WebhookId int               `json:"webhook_id"`
+	EventId   string            `json:"event_id"`
+	Event     string            `json:"event"`
+	Payload   json.RawMessage   `json:"payload"`
+	Status    DeliveryStatus    `json:"status"`
+	Attempts  []DeliveryAttempt `json:"attempts"`
+	// Failures counts the failed attempts since the delivery was queued or
+	// redelivered.
+	Failures      int        `json:"failures"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Due reports whether the delivery should be attempted at now.
+func (d *Delivery) Due(now time.Time) bool {
+	return d.Status == DeliveryPending && (d.NextAttemptAt == nil || !now.Before(*d.NextAttemptAt))
+}
@@ -0,0 +1,16 @@
+package media
+
+import (
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("unable to find media")
+	ErrUpload   = errors.New("unable to upload media")
+
+	ErrTooLarge        = errors.New("media file too large")
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrInvalidKey      = errors.New("invalid media key")
+	ErrNoThumbnail     = errors.New("unable to generate thumbnail for media type")
+	ErrImageTooLarge   = errors.New("image dimensions too large")
+)
@@ -0,0 +1,44 @@
+package media
+
+import "strings"
+
+type Option func(*service)
+
+func BaseURL(u string) Option {
+	return func(s *service) {
+		if u == "" {
+			return
+		}
+
+		if !strings.HasSuffix(u, "/") {
+			u += "/"
+		}
+
+		s.baseURL = u
+	}
+}
+
+func MaxSize(n int64) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxSize = n
+		}
+	}
+}
+
+func ThumbnailSize(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.thumbnailSize = n
+		}
+	}
+}
+
+// MaxDimension rejects images with a side over n pixels.
+func MaxDimension(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxDimension = n
+		}
+	}
+}
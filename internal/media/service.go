@@ -0,0 +1,169 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// ProductMedia attaches media metadata to products.
+type ProductMedia interface {
+	CreateMedia(int, domain.Media) error
+	DeleteMedia(int, string) (domain.Media, error)
+}
+
+type MediaService interface {
+	Upload(int, string, []byte) (domain.Media, error)
+	Get(string) ([]byte, string, error)
+	Delete(int, string) error
+	MaxSize() int64
+}
+
+var extensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type service struct {
+	store         Store
+	products      ProductMedia
+	baseURL       string
+	maxSize       int64
+	thumbnailSize int
+	maxDimension  int
+}
+
+func NewService(s Store, p ProductMedia, ops ...Option) MediaService {
+	svc := &service{
+		store:         s,
+		products:      p,
+		baseURL:       "/media/",
+		maxSize:       5 << 20,
+		thumbnailSize: 256,
+		maxDimension:  8192,
+	}
+
+	for _, op := range ops {
+		op(svc)
+	}
+
+	return svc
+}
+
+func (s *service) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *service) Upload(productId int, filename string, data []byte) (domain.Media, error) {
+	if int64(len(data)) > s.maxSize {
+		return domain.Media{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+
+	ext, ok := extensions[contentType]
+	if !ok {
+		return domain.Media{}, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+
+	id, err := newId()
+	if err != nil {
+		return domain.Media{}, fmt.Errorf("%w: %s", ErrUpload, err.Error())
+	}
+
+	m := domain.Media{
+		Id:          id,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	key := fmt.Sprintf("%d/%s%s", productId, id, ext)
+
+	// thumbnail
+	var thumbKey string
+
+	thumb, thumbType, err := thumbnail(data, contentType, s.thumbnailSize, s.maxDimension)
+	switch {
+	case err == nil:
+		thumbKey = fmt.Sprintf("%d/%s_thumb%s", productId, id, extensions[thumbType])
+	case errors.Is(err, ErrNoThumbnail):
+	case errors.Is(err, ErrImageTooLarge):
+		return domain.Media{}, fmt.Errorf("%w: %s", ErrTooLarge, err.Error())
+	default:
+		// the content looked like an image but could not be decoded
+		return domain.Media{}, fmt.Errorf("%w: %s", ErrUnsupportedType, err.Error())
+	}
+
+	err = s.store.Put(key, data)
+	if err != nil {
+		return domain.Media{}, fmt.Errorf("%w: %s", ErrUpload, err.Error())
+	}
+	m.URL = s.baseURL + key
+
+	if thumbKey != "" {
+		err = s.store.Put(thumbKey, thumb)
+		if err != nil {
+			_ = s.store.Delete(key)
+			return domain.Media{}, fmt.Errorf("%w: %s", ErrUpload, err.Error())
+		}
+		m.ThumbnailURL = s.baseURL + thumbKey
+	}
+
+	err = s.products.CreateMedia(productId, m)
+	if err != nil {
+		s.remove(m)
+		return domain.Media{}, err
+	}
+
+	return m, nil
+}
+
+func (s *service) Get(key string) ([]byte, string, error) {
+	data, err := s.store.Get(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, http.DetectContentType(data), nil
+}
+
+func (s *service) Delete(productId int, mediaId string) error {
+	m, err := s.products.DeleteMedia(productId, mediaId)
+	if err != nil {
+		return err
+	}
+
+	s.remove(m)
+
+	return nil
+}
+
+func (s *service) remove(m domain.Media) {
+	if m.URL != "" {
+		_ = s.store.Delete(strings.TrimPrefix(m.URL, s.baseURL))
+	}
+
+	if m.ThumbnailURL != "" {
+		_ = s.store.Delete(strings.TrimPrefix(m.ThumbnailURL, s.baseURL))
+	}
+}
+
+func newId() (string, error) {
+	b := make([]byte, 12)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
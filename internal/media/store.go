@@ -0,0 +1,92 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store keeps media files by key, e.g. "12/3f9a0c.png".
+type Store interface {
+	Put(string, []byte) error
+	Get(string) ([]byte, error)
+	Delete(string) error
+}
+
+type diskStore struct {
+	dir string
+}
+
+func NewDiskStore(dir string) Store {
+	if dir == "" {
+		dir = "media"
+	}
+
+	return &diskStore{
+		dir: dir,
+	}
+}
+
+func (d *diskStore) Put(key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("[media.Put] %w", err)
+	}
+
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("[media.Put] %w", err)
+	}
+
+	return nil
+}
+
+func (d *diskStore) Get(key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("[media.Get] %w", err)
+	}
+
+	return data, nil
+}
+
+func (d *diskStore) Delete(key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("[media.Delete] %w", err)
+	}
+
+	return nil
+}
+
+// path resolves a key inside the store directory, rejecting keys that
+// would escape it.
+func (d *diskStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || clean == "/" {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(d.dir, clean), nil
+}
@@ -0,0 +1,76 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.White)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	data := encodePNG(t, 400, 200)
+
+	thumb, contentType, err := thumbnail(data, "image/png", 256, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	img, err := png.Decode(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 256, 128), img.Bounds())
+}
+
+func TestThumbnailJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 100, 300)), nil))
+
+	thumb, contentType, err := thumbnail(buf.Bytes(), "image/jpeg", 30, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.Width)
+	assert.Equal(t, 30, cfg.Height)
+}
+
+func TestThumbnailMaxDimension(t *testing.T) {
+	tests := []struct {
+		name string
+		w, h int
+	}{
+		{"too wide", 65, 10},
+		{"too high", 10, 65},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := thumbnail(encodePNG(t, tc.w, tc.h), "image/png", 32, 64)
+			assert.ErrorIs(t, err, ErrImageTooLarge)
+		})
+	}
+
+	_, _, err := thumbnail(encodePNG(t, 64, 64), "image/png", 32, 64)
+	assert.NoError(t, err)
+}
+
+func TestThumbnailUnsupported(t *testing.T) {
+	_, _, err := thumbnail([]byte("%PDF-1.4"), "application/pdf", 32, 64)
+	assert.ErrorIs(t, err, ErrNoThumbnail)
+}
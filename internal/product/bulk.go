@@ -0,0 +1,59 @@
+package product
+
+import "gituhb.com/juajosserand/goweb/internal/domain"
+
+// Mode tells how a bulk write applies its operations.
+type Mode int
+
+const (
+	// Atomic batches apply none of the operations when any fails.
+	Atomic Mode = iota
+	// BestEffort batches apply the operations that do not fail.
+	BestEffort
+	// DryRun batches check the operations like BestEffort ones, without
+	// applying any.
+	DryRun
+)
+
+// Operation types of a bulk write.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Operation is an item of a bulk write. Product holds the id of the product
+// to update or delete.
+type Operation struct {
+	Type    string
+	Product domain.Product
+}
+
+// Result is the outcome of an Operation: the id of the created, updated or
+// deleted product, or the error that left it out of the batch.
+type Result struct {
+	Type string
+	Id   int
+	Err  error
+}
+
+// failed reports whether any of the results is an error.
+func failed(results []Result) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// abort fails the successful results with ErrBulkAborted, as an atomic
+// batch with errors applies none of its operations.
+func abort(results []Result, ops []Operation) {
+	for i, r := range results {
+		if r.Err == nil {
+			results[i] = Result{Type: ops[i].Type, Id: ops[i].Product.Id, Err: ErrBulkAborted}
+		}
+	}
+}
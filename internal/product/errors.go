@@ -16,4 +16,11 @@ var (
 	ErrInvalidConsumerPriceList = errors.New("invalid list of product ids")
 	ErrNoStock                  = errors.New("no enough stock for product")
 	ErrNotPublished             = errors.New("not published product")
+
+	ErrVariantNotFound   = errors.New("unable to find product variant")
+	ErrInvalidAttributes = errors.New("invalid product attributes")
+	ErrMediaNotFound     = errors.New("unable to find product media")
+
+	ErrUnknownOperation = errors.New("unknown bulk operation")
+	ErrBulkAborted      = errors.New("not applied, another operation of the batch failed")
 )
@@ -0,0 +1,15 @@
+package product
+
+type Option func(*service)
+
+func WithAttributes(a AttributeValidator) Option {
+	return func(s *service) {
+		s.attributes = a
+	}
+}
+
+func WithStockRecorder(r StockRecorder) Option {
+	return func(s *service) {
+		s.stock = r
+	}
+}
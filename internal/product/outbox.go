@@ -0,0 +1,376 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/outbox"
+)
+
+const (
+	EventCreated = "product.created"
+	EventUpdated = "product.updated"
+	EventDeleted = "product.deleted"
+)
+
+// Outbox stages the event of each write, and commits it with the stored
+// state or aborts it after the write, e.g. an outbox.Store.
+type Outbox interface {
+	Stage(string, any) (uint64, error)
+	Commit(uint64, any) error
+	Abort(uint64) error
+	StageAll([]outbox.Draft) ([]uint64, error)
+	CommitAll(map[uint64]any) error
+	AbortAll([]uint64) error
+	Staged() []outbox.Event
+}
+
+// Deleted is the data of EventDeleted.
+type Deleted struct {
+	Id int `json:"id"`
+}
+
+// intent is the staged data of an event. It tells on recovery whether the
+// write was stored: the created code value exists, the deleted id does
+// not, or the updated product differs from Before.
+type intent struct {
+	Id        int             `json:"id,omitempty"`
+	CodeValue string          `json:"code_value,omitempty"`
+	Before    *domain.Product `json:"before,omitempty"`
+}
+
+// outboxRepository holds mu from the staging of an event to its commit or
+// abort, so that concurrent writes are not compared with the state stored
+// by each other.
+type outboxRepository struct {
+	ProductRepository
+	outbox Outbox
+	mu     sync.Mutex
+}
+
+// NewOutboxRepository records an event in o with each write of r, so that
+// events are relayed for stored writes only: EventCreated and EventUpdated
+// hold the stored product, and EventDeleted its id.
+func NewOutboxRepository(r ProductRepository, o Outbox) ProductRepository {
+	return &outboxRepository{
+		ProductRepository: r,
+		outbox:            o,
+	}
+}
+
+func (r *outboxRepository) Create(p domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.write(EventCreated, intent{CodeValue: p.CodeValue}, func() error {
+		return r.ProductRepository.Create(p)
+	})
+}
+
+func (r *outboxRepository) Update(p domain.Product) error {
+	return r.update(p.Id, func() error {
+		return r.ProductRepository.Update(p)
+	})
+}
+
+func (r *outboxRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.write(EventDeleted, intent{Id: id}, func() error {
+		return r.ProductRepository.Delete(id)
+	})
+}
+
+// Bulk records an event with each applied operation. The events of the
+// batch are staged, then committed and aborted, with a write of the outbox
+// each.
+func (r *outboxRepository) Bulk(ops []Operation, mode Mode) ([]Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// dry runs write nothing
+	if mode == DryRun {
+		return r.ProductRepository.Bulk(ops, mode)
+	}
+
+	var (
+		drafts  []outbox.Draft
+		intents []intent
+		staged  []int
+	)
+
+	for i, op := range ops {
+		var in intent
+
+		typ := EventUpdated
+		switch op.Type {
+		case OpCreate:
+			typ, in = EventCreated, intent{CodeValue: op.Product.CodeValue}
+		case OpDelete:
+			typ, in = EventDeleted, intent{Id: op.Product.Id}
+		case OpUpdate:
+			p, err := r.ProductRepository.GetById(op.Product.Id)
+			if err != nil {
+				// fails in the batch too
+				continue
+			}
+
+			before, err := snapshot(p)
+			if err != nil {
+				return nil, err
+			}
+			in = intent{Id: op.Product.Id, Before: before}
+		default:
+			continue
+		}
+
+		drafts = append(drafts, outbox.Draft{Type: typ, Data: in})
+		intents = append(intents, in)
+		staged = append(staged, i)
+	}
+
+	var eventIds []uint64
+	if len(drafts) > 0 {
+		ids, err := r.outbox.StageAll(drafts)
+		if err != nil {
+			return nil, err
+		}
+		eventIds = ids
+	}
+
+	results, err := r.ProductRepository.Bulk(ops, mode)
+	if err != nil {
+		if len(eventIds) > 0 {
+			if abortErr := r.outbox.AbortAll(eventIds); abortErr != nil {
+				log.Println(fmt.Errorf("[product.outbox] error: %w", abortErr))
+			}
+		}
+		return nil, err
+	}
+
+	commits := make(map[uint64]any)
+	var aborts []uint64
+
+	for j, i := range staged {
+		if results[i].Err == nil {
+			if data, ok := stored(r.ProductRepository, drafts[j].Type, intents[j]); ok {
+				commits[eventIds[j]] = data
+				continue
+			}
+		}
+		aborts = append(aborts, eventIds[j])
+	}
+
+	if len(commits) > 0 {
+		if err := r.outbox.CommitAll(commits); err != nil {
+			log.Println(fmt.Errorf("[product.outbox] error: %w", err))
+		}
+	}
+
+	if len(aborts) > 0 {
+		if err := r.outbox.AbortAll(aborts); err != nil {
+			log.Println(fmt.Errorf("[product.outbox] error: %w", err))
+		}
+	}
+
+	return results, nil
+}
+
+func (r *outboxRepository) CreateVariant(id int, v domain.Variant) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.CreateVariant(id, v)
+	})
+}
+
+func (r *outboxRepository) UpdateVariant(id int, codeValue string, v domain.Variant) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.UpdateVariant(id, codeValue, v)
+	})
+}
+
+func (r *outboxRepository) DeleteVariant(id int, codeValue string) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.DeleteVariant(id, codeValue)
+	})
+}
+
+func (r *outboxRepository) CreateMedia(id int, m domain.Media) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.CreateMedia(id, m)
+	})
+}
+
+func (r *outboxRepository) DeleteMedia(id int, mediaId string) (domain.Media, error) {
+	var m domain.Media
+
+	err := r.update(id, func() (err error) {
+		m, err = r.ProductRepository.DeleteMedia(id, mediaId)
+		return err
+	})
+
+	return m, err
+}
+
+func (r *outboxRepository) AddStock(id int, quantity int) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.AddStock(id, quantity)
+	})
+}
+
+func (r *outboxRepository) SetReorder(id int, point int, quantity int) error {
+	return r.update(id, func() error {
+		return r.ProductRepository.SetReorder(id, point, quantity)
+	})
+}
+
+// update records EventUpdated with the write of an existing product.
+func (r *outboxRepository) update(id int, fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, err := r.ProductRepository.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	before, err := snapshot(p)
+	if err != nil {
+		return err
+	}
+
+	return r.write(EventUpdated, intent{Id: id, Before: before}, fn)
+}
+
+// snapshot returns a deep copy of the product. Products returned by a
+// repository may share their variants, attributes and media with the
+// stored ones, which some writes change in place.
+func snapshot(p domain.Product) (*domain.Product, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("[product.snapshot] %w", err)
+	}
+
+	var c domain.Product
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, fmt.Errorf("[product.snapshot] %w", err)
+	}
+
+	return &c, nil
+}
+
+// write stages the event, runs the write and commits the event with the
+// stored state, or aborts it when the write fails. An event failing to
+// commit after the write is left staged, and resolved by Recover. It is
+// called with mu held.
+func (r *outboxRepository) write(typ string, in intent, fn func() error) error {
+	eventId, err := r.outbox.Stage(typ, in)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	if err != nil {
+		if abortErr := r.outbox.Abort(eventId); abortErr != nil {
+			log.Println(fmt.Errorf("[product.outbox] error: %w", abortErr))
+		}
+		return err
+	}
+
+	// writes leaving the product as it was have no event
+	if data, ok := stored(r.ProductRepository, typ, in); ok {
+		err = r.outbox.Commit(eventId, data)
+	} else {
+		err = r.outbox.Abort(eventId)
+	}
+
+	if err != nil {
+		log.Println(fmt.Errorf("[product.outbox] error: %w", err))
+	}
+
+	return nil
+}
+
+// Recover resolves the events staged by writes interrupted by a crash,
+// committing those whose write was stored and aborting the others.
+func Recover(r ProductRepository, o Outbox) error {
+	for _, e := range o.Staged() {
+		var in intent
+
+		err := json.Unmarshal(e.Data, &in)
+		if err != nil {
+			return fmt.Errorf("[product.Recover] event %d: %w", e.Id, err)
+		}
+
+		if data, ok := stored(r, e.Type, in); ok {
+			err = o.Commit(e.Id, data)
+		} else {
+			err = o.Abort(e.Id)
+		}
+
+		if err != nil {
+			return fmt.Errorf("[product.Recover] event %d: %w", e.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// stored returns the data of the event when its write is stored in r.
+func stored(r ProductRepository, typ string, in intent) (any, bool) {
+	switch typ {
+	case EventCreated:
+		ps, err := r.All()
+		if err != nil {
+			return nil, false
+		}
+
+		for _, p := range ps {
+			if p.CodeValue == in.CodeValue {
+				return p, true
+			}
+		}
+	case EventUpdated:
+		p, err := r.GetById(in.Id)
+		if err != nil || in.Before == nil {
+			return nil, false
+		}
+
+		// compared as json, like the before state was staged
+		after, _ := json.Marshal(p)
+		before, _ := json.Marshal(in.Before)
+		if !bytes.Equal(after, before) {
+			return p, true
+		}
+	case EventDeleted:
+		_, err := r.GetById(in.Id)
+		if errors.Is(err, ErrNotFound) {
+			return Deleted{Id: in.Id}, true
+		}
+	}
+
+	return nil, false
+}
+
+// DecodeEvent returns the data of a product event as a domain.Product, or
+// Deleted for EventDeleted.
+func DecodeEvent(e outbox.Event) (any, error) {
+	switch e.Type {
+	case EventCreated, EventUpdated:
+		var p domain.Product
+		err := json.Unmarshal(e.Data, &p)
+		return p, err
+	case EventDeleted:
+		var d Deleted
+		err := json.Unmarshal(e.Data, &d)
+		return d, err
+	}
+
+	return nil, fmt.Errorf("[product.DecodeEvent] unknown event type %s", e.Type)
+}
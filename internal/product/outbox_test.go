@@ -0,0 +1,280 @@
+package product
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/outbox"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// memoryRepository stores the products in memory, failing the writes
+// while fail is set.
+type memoryRepository struct {
+	ProductRepository
+	ps   map[int]domain.Product
+	fail bool
+}
+
+func (r *memoryRepository) All() ([]domain.Product, error) {
+	var ps []domain.Product
+	for _, p := range r.ps {
+		ps = append(ps, p)
+	}
+
+	return ps, nil
+}
+
+func (r *memoryRepository) GetById(id int) (domain.Product, error) {
+	p, ok := r.ps[id]
+	if !ok {
+		return domain.Product{}, ErrNotFound
+	}
+
+	return p, nil
+}
+
+func (r *memoryRepository) Update(p domain.Product) error {
+	if r.fail {
+		return storage.ErrWriteFile
+	}
+
+	r.ps[p.Id] = p
+
+	return nil
+}
+
+func (r *memoryRepository) Delete(id int) error {
+	if r.fail {
+		return storage.ErrWriteFile
+	}
+
+	delete(r.ps, id)
+
+	return nil
+}
+
+func relayed(t *testing.T, s *outbox.Store) []outbox.Event {
+	t.Helper()
+
+	var es []outbox.Event
+	r := outbox.NewRelay(s, outbox.WithSink("test", sinkFunc(func(e outbox.Event) error {
+		es = append(es, e)
+		return nil
+	})))
+	r.Relay()
+
+	return es
+}
+
+type sinkFunc func(outbox.Event) error
+
+func (f sinkFunc) Publish(e outbox.Event) error {
+	return f(e)
+}
+
+func TestOutboxRepository(t *testing.T) {
+	s, err := outbox.NewStore("")
+	require.NoError(t, err)
+
+	mem := &memoryRepository{ps: map[int]domain.Product{
+		1: {Id: 1, Name: "A", Quantity: 1},
+		2: {Id: 2, Name: "B", Quantity: 1},
+	}}
+	r := NewOutboxRepository(mem, s)
+
+	// failed writes have no event
+	mem.fail = true
+	assert.ErrorIs(t, r.Update(domain.Product{Id: 1, Name: "A", Quantity: 2}), storage.ErrWriteFile)
+	assert.ErrorIs(t, r.Delete(2), storage.ErrWriteFile)
+	assert.Empty(t, s.Staged())
+	assert.Empty(t, relayed(t, s))
+
+	// and neither have writes changing nothing
+	mem.fail = false
+	require.NoError(t, r.Update(domain.Product{Id: 1, Name: "A", Quantity: 1}))
+	require.NoError(t, r.Update(domain.Product{Id: 1, Name: "A", Quantity: 2}))
+	require.NoError(t, r.Delete(2))
+
+	es := relayed(t, s)
+	require.Len(t, es, 2)
+
+	data, err := DecodeEvent(es[0])
+	require.NoError(t, err)
+	assert.Equal(t, EventUpdated, es[0].Type)
+	assert.Equal(t, domain.Product{Id: 1, Name: "A", Quantity: 2}, data)
+
+	data, err = DecodeEvent(es[1])
+	require.NoError(t, err)
+	assert.Equal(t, EventDeleted, es[1].Type)
+	assert.Equal(t, Deleted{Id: 2}, data)
+}
+
+func TestOutboxRepositoryConcurrent(t *testing.T) {
+	s, err := outbox.NewStore("")
+	require.NoError(t, err)
+
+	mem := &memoryRepository{ps: map[int]domain.Product{1: {Id: 1, Name: "A"}}}
+	r := NewOutboxRepository(mem, s)
+
+	// every update changes the product, and none is compared with the
+	// state stored by another
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(q int) {
+			defer wg.Done()
+			assert.NoError(t, r.Update(domain.Product{Id: 1, Name: "A", Quantity: q}))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Empty(t, s.Staged())
+	assert.Len(t, relayed(t, s), 20)
+}
+
+func TestRecover(t *testing.T) {
+	s, err := outbox.NewStore("")
+	require.NoError(t, err)
+
+	mem := &memoryRepository{ps: map[int]domain.Product{
+		1: {Id: 1, Name: "A", CodeValue: "A1", Quantity: 2},
+	}}
+
+	// writes interrupted after storing the product, or before
+	_, err = s.Stage(EventCreated, intent{CodeValue: "A1"})
+	require.NoError(t, err)
+	_, err = s.Stage(EventCreated, intent{CodeValue: "B2"})
+	require.NoError(t, err)
+	_, err = s.Stage(EventUpdated, intent{Id: 1, Before: &domain.Product{Id: 1, Name: "A", CodeValue: "A1", Quantity: 1}})
+	require.NoError(t, err)
+	_, err = s.Stage(EventUpdated, intent{Id: 1, Before: &domain.Product{Id: 1, Name: "A", CodeValue: "A1", Quantity: 2}})
+	require.NoError(t, err)
+	_, err = s.Stage(EventDeleted, intent{Id: 3})
+	require.NoError(t, err)
+	_, err = s.Stage(EventDeleted, intent{Id: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, Recover(mem, s))
+	assert.Empty(t, s.Staged())
+
+	var ids []uint64
+	for _, e := range relayed(t, s) {
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []uint64{1, 3, 5}, ids)
+}
+
+func TestOutboxRepositoryBulk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Product{
+		{Id: 1, Name: "A", CodeValue: "A1", Quantity: 1},
+		{Id: 2, Name: "B", CodeValue: "B2", Quantity: 1},
+	}))
+	t.Setenv("PRODUCTS_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	s, err := outbox.NewStore("")
+	require.NoError(t, err)
+	r := NewOutboxRepository(repo, s)
+
+	ops := []Operation{
+		{Type: OpCreate, Product: domain.Product{Name: "C", CodeValue: "C3", Quantity: 1}},
+		{Type: OpUpdate, Product: domain.Product{Id: 1, Name: "A", CodeValue: "A1", Quantity: 4}},
+		{Type: OpCreate, Product: domain.Product{Name: "D", CodeValue: "B2", Quantity: 1}},
+		{Type: OpDelete, Product: domain.Product{Id: 2}},
+	}
+
+	// dry runs apply none, and record no event
+	results, err := r.Bulk(ops, DryRun)
+	require.NoError(t, err)
+	assert.Equal(t, ErrDuplicatedCodeValue, results[2].Err)
+	assert.Empty(t, relayed(t, s))
+
+	// atomic batches with a failed operation apply none either
+	results, err = r.Bulk(ops, Atomic)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Type: OpCreate, Err: ErrBulkAborted},
+		{Type: OpUpdate, Id: 1, Err: ErrBulkAborted},
+		{Type: OpCreate, Err: ErrDuplicatedCodeValue},
+		{Type: OpDelete, Id: 2, Err: ErrBulkAborted},
+	}, results)
+	assert.Empty(t, s.Staged())
+	assert.Empty(t, relayed(t, s))
+
+	ps, _ := repo.All()
+	assert.Len(t, ps, 2)
+
+	// best effort batches apply the others, and record their events
+	results, err = r.Bulk(ops, BestEffort)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Type: OpCreate, Id: 3},
+		{Type: OpUpdate, Id: 1},
+		{Type: OpCreate, Err: ErrDuplicatedCodeValue},
+		{Type: OpDelete, Id: 2},
+	}, results)
+	assert.Empty(t, s.Staged())
+
+	var types []string
+	for _, e := range relayed(t, s) {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []string{EventCreated, EventUpdated, EventDeleted}, types)
+
+	var products []domain.Product
+	require.NoError(t, storage.ReadFile(path, &products))
+	require.Len(t, products, 2)
+	assert.Equal(t, 4, products[0].Quantity)
+	assert.Equal(t, "C3", products[1].CodeValue)
+}
+
+func TestOutboxRepositoryInPlaceWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Product{{
+		Id:        1,
+		Name:      "A",
+		CodeValue: "A1",
+		Quantity:  1,
+		Variants:  []domain.Variant{{CodeValue: "A1S", Price: 1, Quantity: 1}},
+		Media:     []domain.Media{{Id: "m1"}, {Id: "m2"}},
+	}}))
+	t.Setenv("PRODUCTS_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	s, err := outbox.NewStore("")
+	require.NoError(t, err)
+	r := NewOutboxRepository(repo, s)
+
+	// writes changing the variants and media of the stored product in
+	// place are still told apart from the staged state
+	require.NoError(t, r.UpdateVariant(1, "A1S", domain.Variant{CodeValue: "A1S", Price: 2, Quantity: 5}))
+	require.NoError(t, r.AddStock(1, 3))
+	require.NoError(t, r.CreateMedia(1, domain.Media{Id: "m3"}))
+	_, err = r.DeleteMedia(1, "m1")
+	require.NoError(t, err)
+
+	es := relayed(t, s)
+	require.Len(t, es, 4)
+
+	for _, e := range es {
+		assert.Equal(t, EventUpdated, e.Type)
+	}
+
+	data, err := DecodeEvent(es[0])
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, data.(domain.Product).Variants[0].Price)
+
+	data, err = DecodeEvent(es[3])
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Media{{Id: "m2"}, {Id: "m3"}}, data.(domain.Product).Media)
+}
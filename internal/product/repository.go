@@ -2,6 +2,7 @@ package product
 
 import (
 	"os"
+	"sync"
 
 	"gituhb.com/juajosserand/goweb/internal/domain"
 	"gituhb.com/juajosserand/goweb/pkg/storage"
@@ -14,20 +15,44 @@ type ProductRepository interface {
 	Create(domain.Product) error
 	Update(domain.Product) error
 	Delete(int) error
+	Bulk([]Operation, Mode) ([]Result, error)
+	GetVariant(string) (domain.Product, domain.Variant, error)
+	CreateVariant(int, domain.Variant) error
+	UpdateVariant(int, string, domain.Variant) error
+	DeleteVariant(int, string) error
+	CreateMedia(int, domain.Media) error
+	DeleteMedia(int, string) (domain.Media, error)
+	AddStock(int, int) error
+	SetReorder(int, int, int) error
 }
 
+// repository guards the products with mu, as the reorder evaluator reads
+// them in the background. Reads return copies, which writes do not change.
 type repository struct {
 	Products []domain.Product `json:"products"`
 	lastId   int
+	mu       sync.RWMutex
 }
 
+// NewRepository loads the products of the json, ndjson or csv file named by
+// PRODUCTS_FILENAME one at a time, so that large catalogs are not decoded
+// from a single buffer.
 func NewRepository() (ProductRepository, error) {
 	r := &repository{}
 
-	err := storage.ReadFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+	s, err := storage.ScanProducts(os.Getenv("PRODUCTS_FILENAME"))
 	if err != nil {
 		return r, err
 	}
+	defer s.Close()
+
+	for s.Scan() {
+		r.Products = append(r.Products, s.Product())
+	}
+
+	if err := s.Err(); err != nil {
+		return r, err
+	}
 
 	if len(r.Products) > 0 {
 		r.lastId = r.Products[len(r.Products)-1].Id
@@ -37,13 +62,24 @@ func NewRepository() (ProductRepository, error) {
 }
 
 func (r *repository) All() ([]domain.Product, error) {
-	return r.Products, nil
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]domain.Product, len(r.Products))
+	for i, p := range r.Products {
+		products[i] = clone(p)
+	}
+
+	return products, nil
 }
 
 func (r *repository) GetById(id int) (domain.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, p := range r.Products {
 		if p.Id == id {
-			return p, nil
+			return clone(p), nil
 		}
 	}
 
@@ -51,9 +87,12 @@ func (r *repository) GetById(id int) (domain.Product, error) {
 }
 
 func (r *repository) PriceGreaterThan(price float64) (products []domain.Product, err error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, p := range r.Products {
 		if p.Price > price {
-			products = append(products, p)
+			products = append(products, clone(p))
 		}
 	}
 
@@ -61,17 +100,15 @@ func (r *repository) PriceGreaterThan(price float64) (products []domain.Product,
 }
 
 func (r *repository) Create(p domain.Product) error {
-	for _, product := range r.Products {
-		if product.CodeValue == p.CodeValue {
-			return ErrDuplicatedCodeValue
-		}
-	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	r.lastId++
-	p.Id = r.lastId
-	r.Products = append(r.Products, p)
+	_, err := r.create(p)
+	if err != nil {
+		return err
+	}
 
-	err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+	err = storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
 	if err != nil {
 		return err
 	}
@@ -80,17 +117,185 @@ func (r *repository) Create(p domain.Product) error {
 }
 
 func (r *repository) Update(p domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.update(p)
+	if err != nil {
+		return err
+	}
+
+	err = storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *repository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.delete(id)
+	if err != nil {
+		return err
+	}
+
+	err = storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Bulk applies the operations in order to a copy of the products, and
+// stores it with a single write. Atomic batches with a failed operation
+// are not stored, and their other operations fail with ErrBulkAborted.
+// Dry runs are never stored. The error is only returned when the write
+// fails.
+func (r *repository) Bulk(ops []Operation, mode Mode) ([]Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := &repository{
+		Products: make([]domain.Product, len(r.Products)),
+		lastId:   r.lastId,
+	}
+	copy(batch.Products, r.Products)
+
+	results := make([]Result, len(ops))
+	applied := false
+
+	for i, op := range ops {
+		var err error
+
+		id := op.Product.Id
+		switch op.Type {
+		case OpCreate:
+			id, err = batch.create(op.Product)
+		case OpUpdate:
+			err = batch.update(op.Product)
+		case OpDelete:
+			err = batch.delete(id)
+		default:
+			err = ErrUnknownOperation
+		}
+
+		if err != nil {
+			results[i] = Result{Type: op.Type, Id: op.Product.Id, Err: err}
+			continue
+		}
+
+		results[i] = Result{Type: op.Type, Id: id}
+		applied = true
+	}
+
+	if mode == Atomic && failed(results) {
+		abort(results, ops)
+		return results, nil
+	}
+
+	if !applied || mode == DryRun {
+		return results, nil
+	}
+
+	err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &batch.Products)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Products, r.lastId = batch.Products, batch.lastId
+
+	return results, nil
+}
+
+// create adds the product without storing it, and returns its id.
+func (r *repository) create(p domain.Product) (int, error) {
+	if r.codeValueTaken(p.CodeValue, 0, "") {
+		return 0, ErrDuplicatedCodeValue
+	}
+
+	r.lastId++
+	p.Id = r.lastId
+	r.Products = append(r.Products, p)
+
+	return p.Id, nil
+}
+
+// update replaces the product without storing it.
+func (r *repository) update(p domain.Product) error {
 	for i, product := range r.Products {
 		if product.Id == p.Id {
 			// check code value
-			for _, pCheck := range r.Products {
-				if pCheck.CodeValue == p.CodeValue && p.CodeValue != product.CodeValue {
-					return ErrDuplicatedCodeValue
-				}
+			if r.codeValueTaken(p.CodeValue, p.Id, "") {
+				return ErrDuplicatedCodeValue
+			}
+
+			// keep variants, attributes and media when not provided
+			if p.Variants == nil {
+				p.Variants = product.Variants
+			}
+
+			if p.Attributes == nil {
+				p.Attributes = product.Attributes
+			}
+
+			if p.Media == nil {
+				p.Media = product.Media
 			}
 
+			// reorder settings have their own endpoint
+			p.ReorderPoint = product.ReorderPoint
+			p.ReorderQuantity = product.ReorderQuantity
+
 			r.Products[i] = p
 
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// delete removes the product without storing it.
+func (r *repository) delete(id int) error {
+	for i, product := range r.Products {
+		if product.Id == id {
+			r.Products = append(r.Products[:i], r.Products[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) GetVariant(codeValue string) (domain.Product, domain.Variant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.Products {
+		if v, ok := p.Variant(codeValue); ok {
+			return clone(p), v, nil
+		}
+	}
+
+	return domain.Product{}, domain.Variant{}, ErrVariantNotFound
+}
+
+func (r *repository) CreateVariant(id int, v domain.Variant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			if r.codeValueTaken(v.CodeValue, 0, "") {
+				return ErrDuplicatedCodeValue
+			}
+
+			r.Products[i].Variants = append(r.Products[i].Variants, v)
+
 			err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
 			if err != nil {
 				return err
@@ -103,10 +308,70 @@ func (r *repository) Update(p domain.Product) error {
 	return ErrNotFound
 }
 
-func (r *repository) Delete(id int) error {
+func (r *repository) UpdateVariant(id int, codeValue string, v domain.Variant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	for i, product := range r.Products {
 		if product.Id == id {
-			r.Products = append(r.Products[:i], r.Products[i+1:]...)
+			for j, variant := range product.Variants {
+				if variant.CodeValue == codeValue {
+					// check code value
+					if r.codeValueTaken(v.CodeValue, 0, codeValue) {
+						return ErrDuplicatedCodeValue
+					}
+
+					r.Products[i].Variants[j] = v
+
+					err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+					if err != nil {
+						return err
+					}
+
+					return nil
+				}
+			}
+
+			return ErrVariantNotFound
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) DeleteVariant(id int, codeValue string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			for j, variant := range product.Variants {
+				if variant.CodeValue == codeValue {
+					r.Products[i].Variants = append(product.Variants[:j], product.Variants[j+1:]...)
+
+					err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+					if err != nil {
+						return err
+					}
+
+					return nil
+				}
+			}
+
+			return ErrVariantNotFound
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) CreateMedia(id int, m domain.Media) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			r.Products[i].Media = append(r.Products[i].Media, m)
 
 			err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
 			if err != nil {
@@ -119,3 +384,115 @@ func (r *repository) Delete(id int) error {
 
 	return ErrNotFound
 }
+
+func (r *repository) DeleteMedia(id int, mediaId string) (domain.Media, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			for j, m := range product.Media {
+				if m.Id == mediaId {
+					r.Products[i].Media = append(product.Media[:j], product.Media[j+1:]...)
+
+					err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+					if err != nil {
+						return domain.Media{}, err
+					}
+
+					return m, nil
+				}
+			}
+
+			return domain.Media{}, ErrMediaNotFound
+		}
+	}
+
+	return domain.Media{}, ErrNotFound
+}
+
+func (r *repository) AddStock(id int, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			if product.Quantity+quantity < 0 {
+				return ErrNoStock
+			}
+
+			r.Products[i].Quantity += quantity
+
+			err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) SetReorder(id int, point int, quantity int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, product := range r.Products {
+		if product.Id == id {
+			r.Products[i].ReorderPoint = point
+			r.Products[i].ReorderQuantity = quantity
+
+			err := storage.WriteFile(os.Getenv("PRODUCTS_FILENAME"), &r.Products)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// codeValueTaken reports whether a code value is already used by a product
+// or a variant in the catalog. The product with the given id and the variant
+// with the given code value are skipped, so they can keep their own code.
+func (r *repository) codeValueTaken(codeValue string, productId int, variantCodeValue string) bool {
+	for _, p := range r.Products {
+		if p.Id != productId && p.CodeValue == codeValue {
+			return true
+		}
+
+		for _, v := range p.Variants {
+			if v.CodeValue != variantCodeValue && v.CodeValue == codeValue {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// clone copies the product with its variants, attributes and media, which
+// writes change in place.
+func clone(p domain.Product) domain.Product {
+	if p.Variants != nil {
+		p.Variants = append([]domain.Variant{}, p.Variants...)
+	}
+
+	if p.Media != nil {
+		p.Media = append([]domain.Media{}, p.Media...)
+	}
+
+	if p.Attributes != nil {
+		attributes := make(map[string]any, len(p.Attributes))
+		for k, v := range p.Attributes {
+			attributes[k] = v
+		}
+		p.Attributes = attributes
+	}
+
+	return p
+}
@@ -0,0 +1,103 @@
+package product
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+func newTestRepository(t *testing.T) ProductRepository {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Product{
+		{
+			Id:         1,
+			Name:       "A",
+			CodeValue:  "A1",
+			Quantity:   1,
+			Variants:   []domain.Variant{{CodeValue: "A1S", Price: 1, Quantity: 1}},
+			Attributes: map[string]any{"color": "red"},
+			Media:      []domain.Media{{Id: "m1"}},
+		},
+		{Id: 2, Name: "B", CodeValue: "B2", Quantity: 1},
+	}))
+	t.Setenv("PRODUCTS_FILENAME", path)
+
+	r, err := NewRepository()
+	require.NoError(t, err)
+
+	return r
+}
+
+func TestRepositoryCopies(t *testing.T) {
+	r := newTestRepository(t)
+
+	p, err := r.GetById(1)
+	require.NoError(t, err)
+	all, err := r.All()
+	require.NoError(t, err)
+
+	// writes leave the products already read as they were
+	require.NoError(t, r.UpdateVariant(1, "A1S", domain.Variant{CodeValue: "A1S", Price: 2, Quantity: 5}))
+	_, err = r.DeleteMedia(1, "m1")
+	require.NoError(t, err)
+	require.NoError(t, r.Delete(1))
+
+	assert.Equal(t, 1.0, p.Variants[0].Price)
+	assert.Equal(t, "m1", p.Media[0].Id)
+	require.Len(t, all, 2)
+	assert.Equal(t, 1, all[0].Id)
+	assert.Equal(t, 1.0, all[0].Variants[0].Price)
+
+	// and so do changes to them
+	all[1].Name = "C"
+	p, err = r.GetById(2)
+	require.NoError(t, err)
+	assert.Equal(t, "B", p.Name)
+}
+
+func TestRepositoryConcurrent(t *testing.T) {
+	r := newTestRepository(t)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// reads in the background, like the reorder evaluator
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			ps, err := r.All()
+			assert.NoError(t, err)
+
+			for _, p := range ps {
+				for _, v := range p.Variants {
+					_ = v.Quantity
+				}
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			assert.NoError(t, r.AddStock(2, 1))
+			assert.NoError(t, r.UpdateVariant(1, "A1S", domain.Variant{CodeValue: "A1S", Price: 1, Quantity: i}))
+		}
+		assert.NoError(t, r.Delete(2))
+	}()
+
+	wg.Wait()
+
+	ps, err := r.All()
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+	assert.Equal(t, 49, ps[0].Variants[0].Quantity)
+}
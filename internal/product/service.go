@@ -2,6 +2,7 @@ package product
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/go-playground/validator"
 	"gituhb.com/juajosserand/goweb/internal/domain"
@@ -11,20 +12,51 @@ type ProductService interface {
 	All() ([]domain.Product, error)
 	GetById(int) (domain.Product, error)
 	PriceGreaterThan(float64) ([]domain.Product, error)
-	Create(string, int, string, bool, string, float64) error
-	Update(int, string, int, string, bool, string, float64) error
+	Search(float64, map[string]string) ([]domain.Product, error)
+	Create(string, int, string, bool, string, float64, map[string]any) error
+	Update(int, string, int, string, bool, string, float64, map[string]any) error
 	Delete(int) error
-	CustomerPrice(map[int]int) (float64, []domain.Product, error)
+	Bulk([]Operation, Mode) ([]Result, error)
+	Import([]domain.Product, bool) ([]Result, error)
+	CustomerPrice(map[int]int, map[string]int) (float64, []domain.Product, []domain.Variant, error)
+	GetVariant(string) (domain.Product, domain.Variant, error)
+	CreateVariant(int, string, float64, int, domain.VariantAttributes) error
+	UpdateVariant(int, string, string, float64, int, domain.VariantAttributes) error
+	DeleteVariant(int, string) error
+	CreateMedia(int, domain.Media) error
+	DeleteMedia(int, string) (domain.Media, error)
+	AddStock(int, int) error
+	SetReorder(int, int, int) error
+}
+
+// StockRecorder records product quantity changes.
+type StockRecorder interface {
+	Record(int, int) error
+}
+
+// AttributeValidator checks custom attribute values against their
+// definitions.
+type AttributeValidator interface {
+	Validate(map[string]any) (map[string]any, error)
+	Match(map[string]any, map[string]string) (bool, error)
 }
 
 type service struct {
-	repo ProductRepository
+	repo       ProductRepository
+	attributes AttributeValidator
+	stock      StockRecorder
 }
 
-func NewService(r ProductRepository) ProductService {
-	return &service{
+func NewService(r ProductRepository, ops ...Option) ProductService {
+	s := &service{
 		repo: r,
 	}
+
+	for _, op := range ops {
+		op(s)
+	}
+
+	return s
 }
 
 func (s *service) All() ([]domain.Product, error) {
@@ -48,7 +80,33 @@ func (s *service) PriceGreaterThan(p float64) ([]domain.Product, error) {
 	return s.repo.PriceGreaterThan(p)
 }
 
-func (s *service) Create(name string, quantity int, codeValue string, isPublished bool, expiration string, price float64) error {
+func (s *service) Search(p float64, attributes map[string]string) ([]domain.Product, error) {
+	products, err := s.PriceGreaterThan(p)
+	if err != nil {
+		return []domain.Product{}, err
+	}
+
+	if len(attributes) == 0 || s.attributes == nil {
+		return products, nil
+	}
+
+	var matches []domain.Product
+
+	for _, product := range products {
+		ok, err := s.attributes.Match(product.Attributes, attributes)
+		if err != nil {
+			return []domain.Product{}, fmt.Errorf("%w: %s", ErrInvalidAttributes, err.Error())
+		}
+
+		if ok {
+			matches = append(matches, product)
+		}
+	}
+
+	return matches, nil
+}
+
+func (s *service) Create(name string, quantity int, codeValue string, isPublished bool, expiration string, price float64, attributes map[string]any) error {
 	p := domain.Product{
 		Name:        name,
 		Quantity:    quantity,
@@ -56,14 +114,11 @@ func (s *service) Create(name string, quantity int, codeValue string, isPublishe
 		IsPublished: isPublished,
 		Expiration:  expiration,
 		Price:       price,
+		Attributes:  attributes,
 	}
 
-	if !p.IsExpirationValid() {
-		return ErrInvalidData
-	}
-
-	if err := p.ToDDMMYYYY(); err != nil {
-		return ErrInvalidData
+	if err := s.prepare(&p, false); err != nil {
+		return err
 	}
 
 	if err := s.repo.Create(p); err != nil {
@@ -73,7 +128,7 @@ func (s *service) Create(name string, quantity int, codeValue string, isPublishe
 	return nil
 }
 
-func (s *service) Update(id int, name string, quantity int, codeValue string, isPublished bool, expiration string, price float64) error {
+func (s *service) Update(id int, name string, quantity int, codeValue string, isPublished bool, expiration string, price float64, attributes map[string]any) error {
 	p := domain.Product{
 		Id:          id,
 		Name:        name,
@@ -82,19 +137,18 @@ func (s *service) Update(id int, name string, quantity int, codeValue string, is
 		IsPublished: isPublished,
 		Expiration:  expiration,
 		Price:       price,
+		Attributes:  attributes,
 	}
 
-	if err := validator.New().Struct(&p); err != nil {
-		return ErrInvalidData
-	}
-
-	if !p.IsExpirationValid() {
-		return ErrInvalidData
+	err := s.prepare(&p, true)
+	if err != nil {
+		return err
 	}
 
-	err := p.ToDDMMYYYY()
+	// previous quantity, to record the stock movement
+	previous, err := s.repo.GetById(id)
 	if err != nil {
-		return ErrInvalidData
+		return err
 	}
 
 	err = s.repo.Update(p)
@@ -102,6 +156,8 @@ func (s *service) Update(id int, name string, quantity int, codeValue string, is
 		return err
 	}
 
+	s.recordStock(id, p.Quantity-previous.Quantity)
+
 	return nil
 }
 
@@ -109,23 +165,132 @@ func (s *service) Delete(id int) error {
 	return s.repo.Delete(id)
 }
 
-func (s *service) CustomerPrice(quantities map[int]int) (total float64, products []domain.Product, err error) {
+// Bulk validates the operations like Create, Update and Delete, and applies
+// the valid ones with a single write. Atomic batches with an invalid
+// operation are not applied. The error is only returned when the write
+// fails.
+func (s *service) Bulk(ops []Operation, mode Mode) ([]Result, error) {
+	results := make([]Result, len(ops))
+	valid := make([]Operation, 0, len(ops))
+	indexes := make([]int, 0, len(ops))
+
+	// previous quantities, to record the stock movements
+	quantities := make(map[int]int)
+
+	for i, op := range ops {
+		var err error
+
+		switch op.Type {
+		case OpCreate:
+			err = s.prepare(&op.Product, false)
+		case OpUpdate:
+			err = s.prepare(&op.Product, true)
+			if p, getErr := s.repo.GetById(op.Product.Id); getErr == nil {
+				quantities[p.Id] = p.Quantity
+			}
+		case OpDelete:
+		default:
+			err = ErrUnknownOperation
+		}
+
+		if err != nil {
+			results[i] = Result{Type: op.Type, Id: op.Product.Id, Err: err}
+			continue
+		}
+
+		valid = append(valid, op)
+		indexes = append(indexes, i)
+	}
+
+	if mode == Atomic && failed(results) {
+		abort(results, ops)
+		return results, nil
+	}
+
+	applied, err := s.repo.Bulk(valid, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, r := range applied {
+		results[indexes[j]] = r
+
+		op := valid[j]
+		if op.Type != OpUpdate || r.Err != nil || mode == DryRun {
+			continue
+		}
+
+		s.recordStock(op.Product.Id, op.Product.Quantity-quantities[op.Product.Id])
+		quantities[op.Product.Id] = op.Product.Quantity
+	}
+
+	return results, nil
+}
+
+// Import upserts the products by code value with a single write, creating
+// the ones with a new code value and updating the others. Products failing
+// the rules of Create or Update are rejected, the others applied. Dry runs
+// check the products without applying any.
+func (s *service) Import(ps []domain.Product, dryRun bool) ([]Result, error) {
+	products, err := s.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(products))
+	for _, p := range products {
+		ids[p.CodeValue] = p.Id
+	}
+
+	ops := make([]Operation, len(ps))
+	for i, p := range ps {
+		// variants, media and reorder settings have their own routes
+		op := Operation{
+			Type: OpCreate,
+			Product: domain.Product{
+				Name:        p.Name,
+				Quantity:    p.Quantity,
+				CodeValue:   p.CodeValue,
+				IsPublished: p.IsPublished,
+				Expiration:  p.Expiration,
+				Price:       p.Price,
+				Attributes:  p.Attributes,
+			},
+		}
+
+		if id, ok := ids[p.CodeValue]; ok {
+			op.Type = OpUpdate
+			op.Product.Id = id
+		}
+
+		ops[i] = op
+	}
+
+	mode := BestEffort
+	if dryRun {
+		mode = DryRun
+	}
+
+	return s.Bulk(ops, mode)
+}
+
+func (s *service) CustomerPrice(quantities map[int]int, variantQuantities map[string]int) (total float64, products []domain.Product, variants []domain.Variant, err error) {
 	var numProducts int
 
 	for id, q := range quantities {
 		p, err := s.repo.GetById(id)
 		if err != nil {
-			return total, products, err
+			return total, products, variants, err
 		}
 
 		if q > p.Quantity {
 			err = fmt.Errorf("%w: %s", ErrNoStock, p.Name)
-			return total, products, err
+			return total, products, variants, err
 		}
 
 		if !p.IsPublished {
 			err = fmt.Errorf("%w: %s", ErrNoStock, p.Name)
-			return total, products, err
+			return total, products, variants, err
 		}
 
 		products = append(products, p)
@@ -133,6 +298,27 @@ func (s *service) CustomerPrice(quantities map[int]int) (total float64, products
 		total += p.Price * float64(q)
 	}
 
+	for code, q := range variantQuantities {
+		p, v, err := s.repo.GetVariant(code)
+		if err != nil {
+			return total, products, variants, err
+		}
+
+		if q > v.Quantity {
+			err = fmt.Errorf("%w: %s (%s)", ErrNoStock, p.Name, v.CodeValue)
+			return total, products, variants, err
+		}
+
+		if !p.IsPublished {
+			err = fmt.Errorf("%w: %s", ErrNotPublished, p.Name)
+			return total, products, variants, err
+		}
+
+		variants = append(variants, v)
+		numProducts += q
+		total += v.Price * float64(q)
+	}
+
 	switch {
 	case numProducts < 10:
 		total *= 1.21
@@ -144,3 +330,118 @@ func (s *service) CustomerPrice(quantities map[int]int) (total float64, products
 
 	return
 }
+
+func (s *service) GetVariant(codeValue string) (domain.Product, domain.Variant, error) {
+	return s.repo.GetVariant(codeValue)
+}
+
+func (s *service) CreateVariant(id int, codeValue string, price float64, quantity int, attributes domain.VariantAttributes) error {
+	v := domain.Variant{
+		CodeValue:  codeValue,
+		Price:      price,
+		Quantity:   quantity,
+		Attributes: attributes,
+	}
+
+	if err := validator.New().Struct(&v); err != nil {
+		return ErrInvalidData
+	}
+
+	return s.repo.CreateVariant(id, v)
+}
+
+func (s *service) UpdateVariant(id int, codeValue string, newCodeValue string, price float64, quantity int, attributes domain.VariantAttributes) error {
+	v := domain.Variant{
+		CodeValue:  newCodeValue,
+		Price:      price,
+		Quantity:   quantity,
+		Attributes: attributes,
+	}
+
+	if err := validator.New().Struct(&v); err != nil {
+		return ErrInvalidData
+	}
+
+	return s.repo.UpdateVariant(id, codeValue, v)
+}
+
+func (s *service) DeleteVariant(id int, codeValue string) error {
+	return s.repo.DeleteVariant(id, codeValue)
+}
+
+func (s *service) CreateMedia(id int, m domain.Media) error {
+	return s.repo.CreateMedia(id, m)
+}
+
+func (s *service) DeleteMedia(id int, mediaId string) (domain.Media, error) {
+	return s.repo.DeleteMedia(id, mediaId)
+}
+
+func (s *service) AddStock(id int, quantity int) error {
+	err := s.repo.AddStock(id, quantity)
+	if err != nil {
+		return err
+	}
+
+	s.recordStock(id, quantity)
+
+	return nil
+}
+
+func (s *service) SetReorder(id int, point int, quantity int) error {
+	if point < 0 || quantity < 0 {
+		return ErrInvalidData
+	}
+
+	return s.repo.SetReorder(id, point, quantity)
+}
+
+func (s *service) recordStock(id int, delta int) {
+	if s.stock == nil || delta == 0 {
+		return
+	}
+
+	if err := s.stock.Record(id, delta); err != nil {
+		log.Println(fmt.Errorf("[product.recordStock] error: %w", err))
+	}
+}
+
+// prepare validates the product written by Create or, when update is set,
+// by Update, and formats its expiration.
+func (s *service) prepare(p *domain.Product, update bool) error {
+	if update {
+		if err := validator.New().Struct(p); err != nil {
+			return ErrInvalidData
+		}
+	}
+
+	if !p.IsExpirationValid() {
+		return ErrInvalidData
+	}
+
+	if err := p.ToDDMMYYYY(); err != nil {
+		return ErrInvalidData
+	}
+
+	// attributes are kept on update when not provided
+	if update && p.Attributes == nil {
+		return nil
+	}
+
+	return s.validateAttributes(p)
+}
+
+func (s *service) validateAttributes(p *domain.Product) error {
+	if s.attributes == nil {
+		return nil
+	}
+
+	attributes, err := s.attributes.Validate(p.Attributes)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAttributes, err.Error())
+	}
+
+	p.Attributes = attributes
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package product
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+func newVariantService(t *testing.T) (ProductService, ProductRepository) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Product{
+		{
+			Id:          1,
+			Name:        "Shirt",
+			CodeValue:   "SHIRT",
+			Quantity:    1,
+			IsPublished: true,
+			Price:       10,
+			Variants: []domain.Variant{
+				{CodeValue: "SHIRT-S", Price: 10, Quantity: 5, Attributes: domain.VariantAttributes{Size: "S"}},
+			},
+		},
+		{Id: 2, Name: "Hat", CodeValue: "HAT", Quantity: 1, Price: 5},
+	}))
+	t.Setenv("PRODUCTS_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	return NewService(repo), repo
+}
+
+func TestCreateVariant(t *testing.T) {
+	s, repo := newVariantService(t)
+
+	attributes := domain.VariantAttributes{Size: "M", Color: "red"}
+	require.NoError(t, s.CreateVariant(1, "SHIRT-M", 12, 3, attributes))
+
+	p, v, err := repo.GetVariant("SHIRT-M")
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Id)
+	assert.Equal(t, domain.Variant{CodeValue: "SHIRT-M", Price: 12, Quantity: 3, Attributes: attributes}, v)
+
+	// variants are stored with their product
+	reloaded, err := NewRepository()
+	require.NoError(t, err)
+	_, v, err = reloaded.GetVariant("SHIRT-M")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, v.Price)
+
+	tests := []struct {
+		name      string
+		id        int
+		codeValue string
+		price     float64
+		quantity  int
+		err       error
+	}{
+		{"product code value", 2, "SHIRT", 1, 1, ErrDuplicatedCodeValue},
+		{"variant code value of another product", 2, "SHIRT-S", 1, 1, ErrDuplicatedCodeValue},
+		{"missing code value", 1, "", 1, 1, ErrInvalidData},
+		{"negative quantity", 1, "SHIRT-L", 1, -1, ErrInvalidData},
+		{"missing product", 3, "SHIRT-L", 1, 1, ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.CreateVariant(test.id, test.codeValue, test.price, test.quantity, domain.VariantAttributes{})
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+
+	// product code values are checked against variants too
+	err = s.Create("Cap", 1, "SHIRT-M", false, "20/01/2030", 1, nil)
+	assert.ErrorIs(t, err, ErrDuplicatedCodeValue)
+}
+
+func TestUpdateVariant(t *testing.T) {
+	s, repo := newVariantService(t)
+	require.NoError(t, s.CreateVariant(1, "SHIRT-M", 12, 3, domain.VariantAttributes{}))
+
+	// a variant keeps its own code value
+	require.NoError(t, s.UpdateVariant(1, "SHIRT-S", "SHIRT-S", 11, 4, domain.VariantAttributes{Size: "S"}))
+	_, v, err := repo.GetVariant("SHIRT-S")
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, v.Price)
+	assert.Equal(t, 4, v.Quantity)
+
+	require.NoError(t, s.UpdateVariant(1, "SHIRT-S", "SHIRT-XS", 11, 4, domain.VariantAttributes{Size: "XS"}))
+	_, _, err = repo.GetVariant("SHIRT-S")
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+
+	assert.ErrorIs(t, s.UpdateVariant(1, "SHIRT-XS", "SHIRT-M", 1, 1, domain.VariantAttributes{}), ErrDuplicatedCodeValue)
+	assert.ErrorIs(t, s.UpdateVariant(1, "SHIRT-XS", "HAT", 1, 1, domain.VariantAttributes{}), ErrDuplicatedCodeValue)
+	assert.ErrorIs(t, s.UpdateVariant(1, "SHIRT-XS", "SHIRT-XS", 1, -1, domain.VariantAttributes{}), ErrInvalidData)
+	assert.ErrorIs(t, s.UpdateVariant(2, "SHIRT-XS", "SHIRT-XS", 1, 1, domain.VariantAttributes{}), ErrVariantNotFound)
+	assert.ErrorIs(t, s.UpdateVariant(3, "SHIRT-XS", "SHIRT-XS", 1, 1, domain.VariantAttributes{}), ErrNotFound)
+}
+
+func TestDeleteVariant(t *testing.T) {
+	s, repo := newVariantService(t)
+
+	require.NoError(t, s.DeleteVariant(1, "SHIRT-S"))
+	_, _, err := repo.GetVariant("SHIRT-S")
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+
+	// the code value is free again
+	require.NoError(t, s.CreateVariant(2, "SHIRT-S", 1, 1, domain.VariantAttributes{}))
+
+	assert.ErrorIs(t, s.DeleteVariant(1, "SHIRT-S"), ErrVariantNotFound)
+	assert.ErrorIs(t, s.DeleteVariant(3, "SHIRT-S"), ErrNotFound)
+}
+
+func TestCustomerPriceVariants(t *testing.T) {
+	s, _ := newVariantService(t)
+
+	total, products, variants, err := s.CustomerPrice(map[int]int{1: 1}, map[string]int{"SHIRT-S": 2})
+	require.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Len(t, variants, 1)
+	// three items are charged the 21% surcharge
+	assert.InDelta(t, 30*1.21, total, 1e-9)
+
+	_, _, _, err = s.CustomerPrice(nil, map[string]int{"SHIRT-S": 6})
+	assert.ErrorIs(t, err, ErrNoStock)
+
+	_, _, _, err = s.CustomerPrice(nil, map[string]int{"NONE": 1})
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+
+	require.NoError(t, s.CreateVariant(2, "HAT-L", 5, 1, domain.VariantAttributes{}))
+	_, _, _, err = s.CustomerPrice(nil, map[string]int{"HAT-L": 1})
+	assert.ErrorIs(t, err, ErrNotPublished)
+}
@@ -0,0 +1,16 @@
+package purchase
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidData = errors.New("invalid purchase order data")
+	ErrCreation    = errors.New("unable to create purchase order")
+	ErrNotFound    = errors.New("unable to find purchase order")
+
+	ErrInvalidId        = errors.New("invalid purchase order id")
+	ErrNotOpen          = errors.New("purchase order is not open")
+	ErrProductNotLinked = errors.New("product not supplied by supplier")
+	ErrReceive          = errors.New("unable to receive purchase order")
+)
@@ -0,0 +1,79 @@
+package purchase
+
+import (
+	"os"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type PurchaseOrderRepository interface {
+	All() ([]domain.PurchaseOrder, error)
+	GetById(int) (domain.PurchaseOrder, error)
+	Create(domain.PurchaseOrder) (domain.PurchaseOrder, error)
+	Update(domain.PurchaseOrder) error
+}
+
+type repository struct {
+	Orders []domain.PurchaseOrder `json:"purchase_orders"`
+	lastId int
+}
+
+func NewRepository() (PurchaseOrderRepository, error) {
+	r := &repository{}
+
+	err := storage.ReadFile(os.Getenv("PURCHASE_ORDERS_FILENAME"), &r.Orders)
+	if err != nil {
+		return r, err
+	}
+
+	if len(r.Orders) > 0 {
+		r.lastId = r.Orders[len(r.Orders)-1].Id
+	}
+
+	return r, nil
+}
+
+func (r *repository) All() ([]domain.PurchaseOrder, error) {
+	return r.Orders, nil
+}
+
+func (r *repository) GetById(id int) (domain.PurchaseOrder, error) {
+	for _, o := range r.Orders {
+		if o.Id == id {
+			return o, nil
+		}
+	}
+
+	return domain.PurchaseOrder{}, ErrNotFound
+}
+
+func (r *repository) Create(o domain.PurchaseOrder) (domain.PurchaseOrder, error) {
+	r.lastId++
+	o.Id = r.lastId
+	r.Orders = append(r.Orders, o)
+
+	err := storage.WriteFile(os.Getenv("PURCHASE_ORDERS_FILENAME"), &r.Orders)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	return o, nil
+}
+
+func (r *repository) Update(o domain.PurchaseOrder) error {
+	for i, order := range r.Orders {
+		if order.Id == o.Id {
+			r.Orders[i] = o
+
+			err := storage.WriteFile(os.Getenv("PURCHASE_ORDERS_FILENAME"), &r.Orders)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
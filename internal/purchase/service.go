@@ -0,0 +1,190 @@
+package purchase
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// SupplierFinder looks up suppliers and the products they supply.
+type SupplierFinder interface {
+	GetById(int) (domain.Supplier, error)
+}
+
+// ProductStock reads products and adds received stock to them.
+type ProductStock interface {
+	GetById(int) (domain.Product, error)
+	AddStock(int, int) error
+}
+
+type PurchaseOrderService interface {
+	All() ([]domain.PurchaseOrder, error)
+	GetById(int) (domain.PurchaseOrder, error)
+	Create(int, []domain.PurchaseOrderLine) (domain.PurchaseOrder, error)
+	Receive(int) (domain.PurchaseOrder, []domain.Margin, error)
+	Cancel(int) error
+	Margins(int) ([]domain.Margin, error)
+}
+
+type service struct {
+	mu sync.Mutex
+
+	repo      PurchaseOrderRepository
+	suppliers SupplierFinder
+	products  ProductStock
+}
+
+func NewService(r PurchaseOrderRepository, s SupplierFinder, p ProductStock) PurchaseOrderService {
+	return &service{
+		repo:      r,
+		suppliers: s,
+		products:  p,
+	}
+}
+
+func (s *service) All() ([]domain.PurchaseOrder, error) {
+	return s.repo.All()
+}
+
+func (s *service) GetById(id int) (domain.PurchaseOrder, error) {
+	return s.repo.GetById(id)
+}
+
+// Create opens a purchase order. Lines without a cost price take the one
+// agreed with the supplier for the product.
+func (s *service) Create(supplierId int, lines []domain.PurchaseOrderLine) (domain.PurchaseOrder, error) {
+	o := domain.PurchaseOrder{
+		SupplierId: supplierId,
+		Status:     domain.PurchaseOrderOpen,
+		Lines:      lines,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := validator.New().Struct(&o); err != nil {
+		return domain.PurchaseOrder{}, ErrInvalidData
+	}
+
+	supplier, err := s.suppliers.GetById(supplierId)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	for i, l := range o.Lines {
+		sp, ok := supplier.Product(l.ProductId)
+		if !ok {
+			return domain.PurchaseOrder{}, fmt.Errorf("%w: %d", ErrProductNotLinked, l.ProductId)
+		}
+
+		if l.CostPrice == 0 {
+			o.Lines[i].CostPrice = sp.CostPrice
+		}
+	}
+
+	return s.repo.Create(o)
+}
+
+// Receive adds the ordered quantities to the products stock and returns the
+// margin of every line against the product sale price. The order is marked
+// received before the stock changes, and reopened with the stock already
+// added taken back if a line fails, so a retry never adds it twice.
+func (s *service) Receive(id int) (domain.PurchaseOrder, []domain.Margin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, err := s.repo.GetById(id)
+	if err != nil {
+		return domain.PurchaseOrder{}, nil, err
+	}
+
+	if o.Status != domain.PurchaseOrderOpen {
+		return domain.PurchaseOrder{}, nil, ErrNotOpen
+	}
+
+	// check every product before touching stock
+	margins, err := s.margins(o)
+	if err != nil {
+		return domain.PurchaseOrder{}, nil, err
+	}
+
+	now := time.Now().UTC()
+	o.Status = domain.PurchaseOrderReceived
+	o.ReceivedAt = &now
+
+	err = s.repo.Update(o)
+	if err != nil {
+		return domain.PurchaseOrder{}, nil, err
+	}
+
+	for i, l := range o.Lines {
+		err = s.products.AddStock(l.ProductId, l.Quantity)
+		if err != nil {
+			s.rollback(o, o.Lines[:i])
+			return domain.PurchaseOrder{}, nil, fmt.Errorf("%w: %s", ErrReceive, err.Error())
+		}
+	}
+
+	return o, margins, nil
+}
+
+// rollback takes back the stock added for the received lines and reopens the
+// order.
+func (s *service) rollback(o domain.PurchaseOrder, received []domain.PurchaseOrderLine) {
+	for _, l := range received {
+		if err := s.products.AddStock(l.ProductId, -l.Quantity); err != nil {
+			log.Println(fmt.Errorf("[purchase.rollback] error: %w", err))
+		}
+	}
+
+	o.Status = domain.PurchaseOrderOpen
+	o.ReceivedAt = nil
+
+	if err := s.repo.Update(o); err != nil {
+		log.Println(fmt.Errorf("[purchase.rollback] error: %w", err))
+	}
+}
+
+func (s *service) Cancel(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	if o.Status != domain.PurchaseOrderOpen {
+		return ErrNotOpen
+	}
+
+	o.Status = domain.PurchaseOrderCancelled
+
+	return s.repo.Update(o)
+}
+
+func (s *service) Margins(id int) ([]domain.Margin, error) {
+	o, err := s.repo.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.margins(o)
+}
+
+func (s *service) margins(o domain.PurchaseOrder) ([]domain.Margin, error) {
+	margins := make([]domain.Margin, 0, len(o.Lines))
+
+	for _, l := range o.Lines {
+		p, err := s.products.GetById(l.ProductId)
+		if err != nil {
+			return nil, err
+		}
+
+		margins = append(margins, domain.NewMargin(l, p.Price))
+	}
+
+	return margins, nil
+}
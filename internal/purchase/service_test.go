@@ -0,0 +1,139 @@
+package purchase
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+var errStock = errors.New("stock write failed")
+
+// memoryStock keeps the products quantities in memory, failing the stock
+// additions to the product in fail.
+type memoryStock struct {
+	mu         sync.Mutex
+	quantities map[int]int
+	fail       int
+}
+
+func (m *memoryStock) GetById(id int) (domain.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q, ok := m.quantities[id]
+	if !ok {
+		return domain.Product{}, errors.New("product not found")
+	}
+
+	return domain.Product{Id: id, Quantity: q, Price: 10}, nil
+}
+
+func (m *memoryStock) AddStock(id int, quantity int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id == m.fail {
+		return errStock
+	}
+
+	m.quantities[id] += quantity
+
+	return nil
+}
+
+func newOrderService(t *testing.T, stock *memoryStock) (PurchaseOrderService, int) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "purchase_orders.json")
+	require.NoError(t, storage.WriteFile(path, []domain.PurchaseOrder{{
+		Id:         1,
+		SupplierId: 1,
+		Status:     domain.PurchaseOrderOpen,
+		Lines: []domain.PurchaseOrderLine{
+			{ProductId: 1, Quantity: 5, CostPrice: 4},
+			{ProductId: 2, Quantity: 3, CostPrice: 6},
+		},
+	}}))
+	t.Setenv("PURCHASE_ORDERS_FILENAME", path)
+
+	repo, err := NewRepository()
+	require.NoError(t, err)
+
+	return NewService(repo, nil, stock), 1
+}
+
+func TestReceive(t *testing.T) {
+	stock := &memoryStock{quantities: map[int]int{1: 1, 2: 0}}
+	s, id := newOrderService(t, stock)
+
+	o, margins, err := s.Receive(id)
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.PurchaseOrderReceived, o.Status)
+	assert.NotNil(t, o.ReceivedAt)
+	assert.Len(t, margins, 2)
+	assert.Equal(t, map[int]int{1: 6, 2: 3}, stock.quantities)
+
+	_, _, err = s.Receive(id)
+	assert.ErrorIs(t, err, ErrNotOpen)
+	assert.Equal(t, map[int]int{1: 6, 2: 3}, stock.quantities)
+}
+
+func TestReceiveConcurrent(t *testing.T) {
+	stock := &memoryStock{quantities: map[int]int{1: 0, 2: 0}}
+	s, id := newOrderService(t, stock)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = s.Receive(id)
+		}(i)
+	}
+	wg.Wait()
+
+	received := 0
+	for _, err := range errs {
+		if err == nil {
+			received++
+			continue
+		}
+
+		assert.ErrorIs(t, err, ErrNotOpen)
+	}
+
+	assert.Equal(t, 1, received)
+	assert.Equal(t, map[int]int{1: 5, 2: 3}, stock.quantities)
+}
+
+func TestReceivePartialFailure(t *testing.T) {
+	stock := &memoryStock{quantities: map[int]int{1: 1, 2: 0}, fail: 2}
+	s, id := newOrderService(t, stock)
+
+	_, _, err := s.Receive(id)
+	assert.ErrorIs(t, err, ErrReceive)
+
+	// the first line stock is taken back and the order stays open
+	assert.Equal(t, map[int]int{1: 1, 2: 0}, stock.quantities)
+
+	o, err := s.GetById(id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PurchaseOrderOpen, o.Status)
+	assert.Nil(t, o.ReceivedAt)
+
+	// a retry adds the stock once
+	stock.fail = 0
+
+	_, _, err = s.Receive(id)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{1: 6, 2: 3}, stock.quantities)
+}
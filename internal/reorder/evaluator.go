@@ -0,0 +1,106 @@
+package reorder
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// Notifier receives the reorder suggestions found by the evaluator.
+type Notifier interface {
+	Notify(domain.ReorderSuggestion) error
+}
+
+type logNotifier struct{}
+
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+func (logNotifier) Notify(s domain.ReorderSuggestion) error {
+	log.Printf("reorder: product %d (%s) has %d units, reorder point %d, suggested order %d\n",
+		s.ProductId, s.Name, s.Quantity, s.ReorderPoint, s.SuggestedQuantity)
+	return nil
+}
+
+// Evaluator periodically checks the reorder report and notifies once per
+// product each time it falls below its reorder point.
+type Evaluator struct {
+	svc      ReorderService
+	notifier Notifier
+	interval time.Duration
+	notified map[int]bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewEvaluator(s ReorderService, n Notifier, interval time.Duration) *Evaluator {
+	return &Evaluator{
+		svc:      s,
+		notifier: n,
+		interval: interval,
+		notified: make(map[int]bool),
+		done:     make(chan struct{}),
+	}
+}
+
+func (e *Evaluator) Start() {
+	e.wg.Add(1)
+
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.Evaluate()
+			case <-e.done:
+				return
+			}
+		}
+	}()
+}
+
+func (e *Evaluator) Stop() {
+	close(e.done)
+	e.wg.Wait()
+}
+
+func (e *Evaluator) Evaluate() {
+	suggestions, err := e.svc.Report()
+	if err != nil {
+		log.Println(fmt.Errorf("[reorder.Evaluate] error: %w", err))
+		return
+	}
+
+	below := make(map[int]bool, len(suggestions))
+
+	for _, s := range suggestions {
+		below[s.ProductId] = true
+
+		if e.notified[s.ProductId] {
+			continue
+		}
+
+		err := e.notifier.Notify(s)
+		if err != nil {
+			log.Println(fmt.Errorf("[reorder.Evaluate] error: %w", err))
+			continue
+		}
+
+		e.notified[s.ProductId] = true
+	}
+
+	// products restocked since the last evaluation can notify again
+	for id := range e.notified {
+		if !below[id] {
+			delete(e.notified, id)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package reorder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+type stubReport []domain.ReorderSuggestion
+
+func (r *stubReport) Report() ([]domain.ReorderSuggestion, error) {
+	return *r, nil
+}
+
+// recorder keeps the notified product ids, failing while fail is set.
+type recorder struct {
+	ids  []int
+	fail bool
+}
+
+func (r *recorder) Notify(s domain.ReorderSuggestion) error {
+	if r.fail {
+		return errors.New("unavailable")
+	}
+
+	r.ids = append(r.ids, s.ProductId)
+
+	return nil
+}
+
+func TestEvaluate(t *testing.T) {
+	report := &stubReport{{ProductId: 1}, {ProductId: 2}}
+	n := &recorder{}
+	e := NewEvaluator(report, n, time.Hour)
+
+	e.Evaluate()
+	assert.Equal(t, []int{1, 2}, n.ids)
+
+	// products still below their point are notified once
+	e.Evaluate()
+	assert.Equal(t, []int{1, 2}, n.ids)
+
+	// restocked products notify again when they fall below
+	*report = stubReport{{ProductId: 2}}
+	e.Evaluate()
+	*report = stubReport{{ProductId: 1}, {ProductId: 2}}
+	e.Evaluate()
+	assert.Equal(t, []int{1, 2, 1}, n.ids)
+}
+
+func TestEvaluateNotifyFailure(t *testing.T) {
+	report := &stubReport{{ProductId: 1}}
+	n := &recorder{fail: true}
+	e := NewEvaluator(report, n, time.Hour)
+
+	e.Evaluate()
+	assert.Empty(t, n.ids)
+
+	// failed notifications are retried
+	n.fail = false
+	e.Evaluate()
+	assert.Equal(t, []int{1}, n.ids)
+}
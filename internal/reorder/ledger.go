@@ -0,0 +1,63 @@
+package reorder
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// Ledger stores stock movements, used to compute sales velocity.
+type Ledger interface {
+	Record(int, int) error
+	Since(time.Time) ([]domain.StockMovement, error)
+}
+
+type ledger struct {
+	mu        sync.Mutex
+	Movements []domain.StockMovement `json:"movements"`
+}
+
+func NewLedger() (Ledger, error) {
+	l := &ledger{}
+
+	err := storage.ReadFile(os.Getenv("MOVEMENTS_FILENAME"), &l.Movements)
+	if err != nil {
+		return l, err
+	}
+
+	return l, nil
+}
+
+func (l *ledger) Record(productId int, delta int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.Movements = append(l.Movements, domain.StockMovement{
+		ProductId: productId,
+		Delta:     delta,
+		At:        time.Now().UTC(),
+	})
+
+	err := storage.WriteFile(os.Getenv("MOVEMENTS_FILENAME"), &l.Movements)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (l *ledger) Since(t time.Time) (movements []domain.StockMovement, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, m := range l.Movements {
+		if !m.At.Before(t) {
+			movements = append(movements, m)
+		}
+	}
+
+	return movements, nil
+}
@@ -0,0 +1,23 @@
+package reorder
+
+import "time"
+
+type Option func(*service)
+
+// Window sets how far back stock movements count towards sales velocity.
+func Window(d time.Duration) Option {
+	return func(s *service) {
+		if d > 0 {
+			s.window = d
+		}
+	}
+}
+
+// DefaultLeadTime is used for products without suppliers.
+func DefaultLeadTime(days int) Option {
+	return func(s *service) {
+		if days >= 0 {
+			s.defaultLeadTime = days
+		}
+	}
+}
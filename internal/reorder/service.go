@@ -0,0 +1,137 @@
+package reorder
+
+import (
+	"math"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// ProductLister lists catalog products.
+type ProductLister interface {
+	All() ([]domain.Product, error)
+}
+
+// SupplierLookup finds the suppliers of a product.
+type SupplierLookup interface {
+	ForProduct(int) ([]domain.Supplier, error)
+}
+
+type ReorderService interface {
+	Report() ([]domain.ReorderSuggestion, error)
+}
+
+type service struct {
+	products        ProductLister
+	suppliers       SupplierLookup
+	ledger          Ledger
+	window          time.Duration
+	defaultLeadTime int
+}
+
+func NewService(p ProductLister, s SupplierLookup, l Ledger, ops ...Option) ReorderService {
+	svc := &service{
+		products:        p,
+		suppliers:       s,
+		ledger:          l,
+		window:          30 * 24 * time.Hour,
+		defaultLeadTime: 7,
+	}
+
+	for _, op := range ops {
+		op(svc)
+	}
+
+	return svc
+}
+
+// Report lists the products at or below their reorder point. The suggested
+// quantity covers the expected sales during the supplier lead time on top of
+// the reorder point, and is never lower than the product reorder quantity.
+func (s *service) Report() ([]domain.ReorderSuggestion, error) {
+	products, err := s.products.All()
+	if err != nil {
+		return nil, err
+	}
+
+	velocity, err := s.velocity()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []domain.ReorderSuggestion{}
+
+	for _, p := range products {
+		if p.ReorderPoint == 0 || p.Quantity > p.ReorderPoint {
+			continue
+		}
+
+		leadTime, err := s.leadTime(p.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		suggested := int(math.Ceil(velocity[p.Id]*float64(leadTime))) + p.ReorderPoint - p.Quantity
+		if suggested < p.ReorderQuantity {
+			suggested = p.ReorderQuantity
+		}
+
+		suggestions = append(suggestions, domain.ReorderSuggestion{
+			ProductId:         p.Id,
+			Name:              p.Name,
+			Quantity:          p.Quantity,
+			ReorderPoint:      p.ReorderPoint,
+			ReorderQuantity:   p.ReorderQuantity,
+			DailyVelocity:     velocity[p.Id],
+			LeadTimeDays:      leadTime,
+			SuggestedQuantity: suggested,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// velocity returns the units sold per day of every product in the window.
+func (s *service) velocity() (map[int]float64, error) {
+	movements, err := s.ledger.Since(time.Now().Add(-s.window))
+	if err != nil {
+		return nil, err
+	}
+
+	days := s.window.Hours() / 24
+	velocity := make(map[int]float64)
+
+	for _, m := range movements {
+		if m.Delta < 0 {
+			velocity[m.ProductId] += float64(-m.Delta) / days
+		}
+	}
+
+	return velocity, nil
+}
+
+// leadTime returns the shortest lead time among the product suppliers.
+func (s *service) leadTime(productId int) (int, error) {
+	if s.suppliers == nil {
+		return s.defaultLeadTime, nil
+	}
+
+	suppliers, err := s.suppliers.ForProduct(productId)
+	if err != nil {
+		return 0, err
+	}
+
+	leadTime := -1
+	for _, supplier := range suppliers {
+		sp, _ := supplier.Product(productId)
+		if leadTime < 0 || sp.LeadTimeDays < leadTime {
+			leadTime = sp.LeadTimeDays
+		}
+	}
+
+	if leadTime < 0 {
+		return s.defaultLeadTime, nil
+	}
+
+	return leadTime, nil
+}
@@ -0,0 +1,128 @@
+package reorder
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type stubProducts []domain.Product
+
+func (p stubProducts) All() ([]domain.Product, error) {
+	return p, nil
+}
+
+type stubSuppliers map[int][]domain.Supplier
+
+func (s stubSuppliers) ForProduct(id int) ([]domain.Supplier, error) {
+	return s[id], nil
+}
+
+func newTestLedger(t *testing.T, movements []domain.StockMovement) Ledger {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "movements.json")
+	require.NoError(t, storage.WriteFile(path, movements))
+	t.Setenv("MOVEMENTS_FILENAME", path)
+
+	l, err := NewLedger()
+	require.NoError(t, err)
+
+	return l
+}
+
+func TestReport(t *testing.T) {
+	now := time.Now().UTC()
+	day := 24 * time.Hour
+
+	l := newTestLedger(t, []domain.StockMovement{
+		{ProductId: 1, Delta: -12, At: now.Add(-2 * day)},
+		{ProductId: 1, Delta: -8, At: now.Add(-5 * day)},
+		// restocks and movements out of the window do not count
+		{ProductId: 1, Delta: 50, At: now.Add(-3 * day)},
+		{ProductId: 1, Delta: -100, At: now.Add(-20 * day)},
+	})
+
+	products := stubProducts{
+		{Id: 1, Name: "Low", Quantity: 2, ReorderPoint: 5, ReorderQuantity: 3},
+		{Id: 2, Name: "Stocked", Quantity: 10, ReorderPoint: 5, ReorderQuantity: 3},
+		{Id: 3, Name: "Untracked", Quantity: 0},
+		{Id: 4, Name: "At point", Quantity: 5, ReorderPoint: 5, ReorderQuantity: 20},
+	}
+
+	suppliers := stubSuppliers{
+		1: {
+			{Id: 1, Products: []domain.SupplierProduct{{ProductId: 1, LeadTimeDays: 4}}},
+			{Id: 2, Products: []domain.SupplierProduct{{ProductId: 1, LeadTimeDays: 3}}},
+		},
+	}
+
+	s := NewService(products, suppliers, l, Window(10*day), DefaultLeadTime(2))
+
+	suggestions, err := s.Report()
+	require.NoError(t, err)
+
+	assert.Equal(t, []domain.ReorderSuggestion{
+		{
+			ProductId:       1,
+			Name:            "Low",
+			Quantity:        2,
+			ReorderPoint:    5,
+			ReorderQuantity: 3,
+			// 20 units sold in 10 days
+			DailyVelocity: 2,
+			// the fastest supplier
+			LeadTimeDays: 3,
+			// 6 units sold during the lead time, plus 3 up to the point
+			SuggestedQuantity: 9,
+		},
+		{
+			ProductId:       4,
+			Name:            "At point",
+			Quantity:        5,
+			ReorderPoint:    5,
+			ReorderQuantity: 20,
+			LeadTimeDays:    2,
+			// never below the reorder quantity
+			SuggestedQuantity: 20,
+		},
+	}, suggestions)
+}
+
+func TestReportEmpty(t *testing.T) {
+	l := newTestLedger(t, nil)
+	s := NewService(stubProducts{{Id: 1, Quantity: 10, ReorderPoint: 5}}, nil, l)
+
+	suggestions, err := s.Report()
+	require.NoError(t, err)
+	assert.NotNil(t, suggestions)
+	assert.Empty(t, suggestions)
+}
+
+func TestLedger(t *testing.T) {
+	l := newTestLedger(t, nil)
+
+	start := time.Now().UTC()
+	require.NoError(t, l.Record(1, -2))
+	require.NoError(t, l.Record(2, 5))
+
+	// movements are stored
+	reloaded, err := NewLedger()
+	require.NoError(t, err)
+
+	movements, err := reloaded.Since(start)
+	require.NoError(t, err)
+	require.Len(t, movements, 2)
+	assert.Equal(t, 1, movements[0].ProductId)
+	assert.Equal(t, -2, movements[0].Delta)
+	assert.Equal(t, 2, movements[1].ProductId)
+
+	movements, err = reloaded.Since(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, movements)
+}
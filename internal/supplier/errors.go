@@ -0,0 +1,16 @@
+package supplier
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidData = errors.New("invalid supplier data")
+	ErrCreation    = errors.New("unable to create supplier")
+	ErrDeletion    = errors.New("unable to delete supplier")
+	ErrNotFound    = errors.New("unable to find supplier")
+
+	ErrInvalidId        = errors.New("invalid supplier id")
+	ErrProductNotLinked = errors.New("product not supplied by supplier")
+	ErrProductNotFound  = errors.New("unable to find product")
+)
@@ -0,0 +1,97 @@
+package supplier
+
+import (
+	"os"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type SupplierRepository interface {
+	All() ([]domain.Supplier, error)
+	GetById(int) (domain.Supplier, error)
+	Create(domain.Supplier) (domain.Supplier, error)
+	Update(domain.Supplier) error
+	Delete(int) error
+}
+
+type repository struct {
+	Suppliers []domain.Supplier `json:"suppliers"`
+	lastId    int
+}
+
+func NewRepository() (SupplierRepository, error) {
+	r := &repository{}
+
+	err := storage.ReadFile(os.Getenv("SUPPLIERS_FILENAME"), &r.Suppliers)
+	if err != nil {
+		return r, err
+	}
+
+	if len(r.Suppliers) > 0 {
+		r.lastId = r.Suppliers[len(r.Suppliers)-1].Id
+	}
+
+	return r, nil
+}
+
+func (r *repository) All() ([]domain.Supplier, error) {
+	return r.Suppliers, nil
+}
+
+func (r *repository) GetById(id int) (domain.Supplier, error) {
+	for _, s := range r.Suppliers {
+		if s.Id == id {
+			return s, nil
+		}
+	}
+
+	return domain.Supplier{}, ErrNotFound
+}
+
+func (r *repository) Create(s domain.Supplier) (domain.Supplier, error) {
+	r.lastId++
+	s.Id = r.lastId
+	r.Suppliers = append(r.Suppliers, s)
+
+	err := storage.WriteFile(os.Getenv("SUPPLIERS_FILENAME"), &r.Suppliers)
+	if err != nil {
+		return domain.Supplier{}, err
+	}
+
+	return s, nil
+}
+
+func (r *repository) Update(s domain.Supplier) error {
+	for i, supplier := range r.Suppliers {
+		if supplier.Id == s.Id {
+			r.Suppliers[i] = s
+
+			err := storage.WriteFile(os.Getenv("SUPPLIERS_FILENAME"), &r.Suppliers)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (r *repository) Delete(id int) error {
+	for i, supplier := range r.Suppliers {
+		if supplier.Id == id {
+			r.Suppliers = append(r.Suppliers[:i], r.Suppliers[i+1:]...)
+
+			err := storage.WriteFile(os.Getenv("SUPPLIERS_FILENAME"), &r.Suppliers)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
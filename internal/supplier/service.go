@@ -0,0 +1,142 @@
+package supplier
+
+import (
+	"github.com/go-playground/validator"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// ProductFinder looks up catalog products.
+type ProductFinder interface {
+	GetById(int) (domain.Product, error)
+}
+
+type SupplierService interface {
+	All() ([]domain.Supplier, error)
+	GetById(int) (domain.Supplier, error)
+	ForProduct(int) ([]domain.Supplier, error)
+	Create(string, string, string) (domain.Supplier, error)
+	Update(int, string, string, string) error
+	Delete(int) error
+	LinkProduct(int, int, float64, int) error
+	UnlinkProduct(int, int) error
+}
+
+type service struct {
+	repo     SupplierRepository
+	products ProductFinder
+}
+
+func NewService(r SupplierRepository, p ProductFinder) SupplierService {
+	return &service{
+		repo:     r,
+		products: p,
+	}
+}
+
+func (s *service) All() ([]domain.Supplier, error) {
+	return s.repo.All()
+}
+
+func (s *service) GetById(id int) (domain.Supplier, error) {
+	return s.repo.GetById(id)
+}
+
+func (s *service) ForProduct(productId int) (suppliers []domain.Supplier, err error) {
+	all, err := s.repo.All()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, supplier := range all {
+		if _, ok := supplier.Product(productId); ok {
+			suppliers = append(suppliers, supplier)
+		}
+	}
+
+	return suppliers, nil
+}
+
+func (s *service) Create(name string, email string, phone string) (domain.Supplier, error) {
+	supplier := domain.Supplier{
+		Name:  name,
+		Email: email,
+		Phone: phone,
+	}
+
+	if err := validator.New().Struct(&supplier); err != nil {
+		return domain.Supplier{}, ErrInvalidData
+	}
+
+	return s.repo.Create(supplier)
+}
+
+func (s *service) Update(id int, name string, email string, phone string) error {
+	supplier, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	supplier.Name = name
+	supplier.Email = email
+	supplier.Phone = phone
+
+	if err := validator.New().Struct(&supplier); err != nil {
+		return ErrInvalidData
+	}
+
+	return s.repo.Update(supplier)
+}
+
+func (s *service) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+// LinkProduct links a product to a supplier, or updates the cost price and
+// lead time of an existing link.
+func (s *service) LinkProduct(id int, productId int, costPrice float64, leadTimeDays int) error {
+	sp := domain.SupplierProduct{
+		ProductId:    productId,
+		CostPrice:    costPrice,
+		LeadTimeDays: leadTimeDays,
+	}
+
+	if err := validator.New().Struct(&sp); err != nil {
+		return ErrInvalidData
+	}
+
+	supplier, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.products.GetById(productId); err != nil {
+		return ErrProductNotFound
+	}
+
+	for i, p := range supplier.Products {
+		if p.ProductId == productId {
+			supplier.Products[i] = sp
+			return s.repo.Update(supplier)
+		}
+	}
+
+	supplier.Products = append(supplier.Products, sp)
+
+	return s.repo.Update(supplier)
+}
+
+func (s *service) UnlinkProduct(id int, productId int) error {
+	supplier, err := s.repo.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range supplier.Products {
+		if p.ProductId == productId {
+			supplier.Products = append(supplier.Products[:i], supplier.Products[i+1:]...)
+			return s.repo.Update(supplier)
+		}
+	}
+
+	return ErrProductNotLinked
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// Dispatcher periodically sends the due deliveries of the service.
+type Dispatcher struct {
+	svc      WebhookService
+	interval time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewDispatcher(s WebhookService, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		svc:      s,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				d.svc.Dispatch()
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for the deliveries being sent. The pending ones are sent
+// after a restart.
+func (d *Dispatcher) Stop() {
+	close(d.done)
+	d.wg.Wait()
+}
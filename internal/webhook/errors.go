@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"errors"
+)
+
+var (
+	ErrInvalidData = errors.New("invalid webhook data")
+	ErrCreation    = errors.New("unable to create webhook")
+	ErrNotFound    = errors.New("unable to find webhook")
+
+	ErrInvalidId        = errors.New("invalid webhook id")
+	ErrInvalidEvent     = errors.New("invalid webhook event")
+	ErrDeliveryNotFound = errors.New("unable to find delivery")
+	ErrDeliveryPending  = errors.New("delivery is still pending")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature is too old")
+	ErrUnexpectedStatus = errors.New("unexpected status code")
+
+	ErrForbiddenDestination = errors.New("webhook destination not allowed")
+)
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option func(*service)
+
+// Events restricts the events webhooks can subscribe to.
+func Events(names ...string) Option {
+	return func(s *service) {
+		s.events = make(map[string]bool, len(names))
+		for _, name := range names {
+			s.events[name] = true
+		}
+	}
+}
+
+// MaxAttempts dead-letters deliveries after n failed attempts.
+func MaxAttempts(n int) Option {
+	return func(s *service) {
+		if n > 0 {
+			s.maxAttempts = n
+		}
+	}
+}
+
+// Backoff waits base after the first failed attempt, doubling after each
+// one up to max.
+func Backoff(base time.Duration, max time.Duration) Option {
+	return func(s *service) {
+		if base > 0 {
+			s.backoffBase = base
+		}
+
+		if max >= base {
+			s.backoffMax = max
+		}
+	}
+}
+
+// Client sends the deliveries, e.g. with a custom timeout or transport. It
+// replaces the default client, which refuses redirects and non public
+// destinations.
+func Client(c *http.Client) Option {
+	return func(s *service) {
+		if c != nil {
+			s.client = c
+		}
+	}
+}
+
+// AllowPrivateNetworks lets the default client deliver to loopback, private
+// and link-local addresses, e.g. for local development.
+func AllowPrivateNetworks() Option {
+	return func(s *service) {
+		s.private = true
+	}
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"os"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+// maxDeliveries bounds the delivery log. The oldest completed deliveries,
+// succeeded or dead, are dropped first, pending ones are kept.
+const maxDeliveries = 10000
+
+// DeliveryQueue persists the deliveries, so that pending ones survive a
+// restart.
+type DeliveryQueue interface {
+	All() ([]domain.Delivery, error)
+	GetById(int) (domain.Delivery, error)
+	HasEvent(string) (bool, error)
+	Create([]domain.Delivery) error
+	Update(domain.Delivery) error
+}
+
+type queue struct {
+	Deliveries []domain.Delivery `json:"deliveries"`
+	lastId     int
+	max        int
+
+	// deliveries kept per event id
+	events map[string]int
+}
+
+func NewQueue() (DeliveryQueue, error) {
+	q := &queue{
+		max:    maxDeliveries,
+		events: make(map[string]int),
+	}
+
+	err := storage.ReadFile(os.Getenv("WEBHOOK_DELIVERIES_FILENAME"), &q.Deliveries)
+	if err != nil {
+		return q, err
+	}
+
+	for _, d := range q.Deliveries {
+		q.events[d.EventId]++
+	}
+
+	if len(q.Deliveries) > 0 {
+		q.lastId = q.Deliveries[len(q.Deliveries)-1].Id
+	}
+
+	return q, nil
+}
+
+func (q *queue) All() ([]domain.Delivery, error) {
+	return q.Deliveries, nil
+}
+
+func (q *queue) GetById(id int) (domain.Delivery, error) {
+	for _, d := range q.Deliveries {
+		if d.Id == id {
+			return d, nil
+		}
+	}
+
+	return domain.Delivery{}, ErrDeliveryNotFound
+}
+
+// HasEvent reports whether deliveries of the event are kept.
+func (q *queue) HasEvent(id string) (bool, error) {
+	return q.events[id] > 0, nil
+}
+
+// Create queues the deliveries of an event with a single write.
+func (q *queue) Create(ds []domain.Delivery) error {
+	for _, d := range ds {
+		q.lastId++
+		d.Id = q.lastId
+		q.Deliveries = append(q.Deliveries, d)
+		q.events[d.EventId]++
+	}
+
+	q.prune()
+
+	return storage.WriteFile(os.Getenv("WEBHOOK_DELIVERIES_FILENAME"), &q.Deliveries)
+}
+
+func (q *queue) Update(d domain.Delivery) error {
+	for i, delivery := range q.Deliveries {
+		if delivery.Id == d.Id {
+			q.Deliveries[i] = d
+
+			err := storage.WriteFile(os.Getenv("WEBHOOK_DELIVERIES_FILENAME"), &q.Deliveries)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrDeliveryNotFound
+}
+
+// prune drops the oldest completed deliveries over the max.
+func (q *queue) prune() {
+	excess := len(q.Deliveries) - q.max
+	if excess <= 0 {
+		return
+	}
+
+	kept := q.Deliveries[:0]
+	for _, d := range q.Deliveries {
+		if excess > 0 && d.Status != domain.DeliveryPending {
+			excess--
+
+			q.events[d.EventId]--
+			if q.events[d.EventId] <= 0 {
+				delete(q.events, d.EventId)
+			}
+			continue
+		}
+		kept = append(kept, d)
+	}
+
+	q.Deliveries = kept
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+func newTestQueue(t *testing.T, max int) *queue {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "webhook_deliveries.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o644))
+	t.Setenv("WEBHOOK_DELIVERIES_FILENAME", path)
+
+	q, err := NewQueue()
+	require.NoError(t, err)
+
+	q.(*queue).max = max
+
+	return q.(*queue)
+}
+
+func TestQueuePrune(t *testing.T) {
+	q := newTestQueue(t, 3)
+
+	for _, d := range []domain.Delivery{
+		{EventId: "e1", Status: domain.DeliveryPending},
+		{EventId: "e2", Status: domain.DeliverySucceeded},
+		{EventId: "e3", Status: domain.DeliveryDead},
+	} {
+		require.NoError(t, q.Create([]domain.Delivery{d}))
+	}
+
+	require.NoError(t, q.Create([]domain.Delivery{
+		{EventId: "e4", Status: domain.DeliveryPending},
+		{EventId: "e4", Status: domain.DeliveryPending},
+	}))
+
+	// the oldest completed deliveries go first, pending ones are kept
+	ds, err := q.All()
+	require.NoError(t, err)
+
+	var events []string
+	for _, d := range ds {
+		events = append(events, d.EventId)
+	}
+	assert.Equal(t, []string{"e1", "e4", "e4"}, events)
+
+	for id, kept := range map[string]bool{"e1": true, "e2": false, "e3": false, "e4": true} {
+		ok, err := q.HasEvent(id)
+		require.NoError(t, err)
+		assert.Equal(t, kept, ok, id)
+	}
+}
+
+func TestQueueEventsReloaded(t *testing.T) {
+	q := newTestQueue(t, maxDeliveries)
+
+	require.NoError(t, q.Create([]domain.Delivery{{EventId: "e1", Status: domain.DeliveryPending}}))
+
+	reloaded, err := NewQueue()
+	require.NoError(t, err)
+
+	ok, err := reloaded.HasEvent("e1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = reloaded.HasEvent("e2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
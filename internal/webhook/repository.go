@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"os"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+type WebhookRepository interface {
+	All() ([]domain.Webhook, error)
+	GetById(int) (domain.Webhook, error)
+	Create(domain.Webhook) (domain.Webhook, error)
+	Delete(int) error
+}
+
+type repository struct {
+	Webhooks []domain.Webhook `json:"webhooks"`
+	lastId   int
+}
+
+func NewRepository() (WebhookRepository, error) {
+	r := &repository{}
+
+	err := storage.ReadFile(os.Getenv("WEBHOOKS_FILENAME"), &r.Webhooks)
+	if err != nil {
+		return r, err
+	}
+
+	if len(r.Webhooks) > 0 {
+		r.lastId = r.Webhooks[len(r.Webhooks)-1].Id
+	}
+
+	return r, nil
+}
+
+func (r *repository) All() ([]domain.Webhook, error) {
+	return r.Webhooks, nil
+}
+
+func (r *repository) GetById(id int) (domain.Webhook, error) {
+	for _, w := range r.Webhooks {
+		if w.Id == id {
+			return w, nil
+		}
+	}
+
+	return domain.Webhook{}, ErrNotFound
+}
+
+func (r *repository) Create(w domain.Webhook) (domain.Webhook, error) {
+	r.lastId++
+	w.Id = r.lastId
+	r.Webhooks = append(r.Webhooks, w)
+
+	err := storage.WriteFile(os.Getenv("WEBHOOKS_FILENAME"), &r.Webhooks)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+
+	return w, nil
+}
+
+func (r *repository) Delete(id int) error {
+	for i, w := range r.Webhooks {
+		if w.Id == id {
+			r.Webhooks = append(r.Webhooks[:i], r.Webhooks[i+1:]...)
+
+			err := storage.WriteFile(os.Getenv("WEBHOOKS_FILENAME"), &r.Webhooks)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
@@ -0,0 +1,390 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+const (
+	defaultMaxAttempts = 10
+	defaultBackoffBase = 10 * time.Second
+	defaultBackoffMax  = time.Hour
+	defaultTimeout     = 10 * time.Second
+
+	secretPrefix = "whsec_"
+
+	EventHeader = "X-Webhook-Event"
+	IdHeader    = "X-Webhook-Id"
+)
+
+type WebhookService interface {
+	All() ([]domain.Webhook, error)
+	GetById(int) (domain.Webhook, error)
+	Create(string, []string, string) (domain.Webhook, error)
+	Delete(int) error
+	Deliveries(int) ([]domain.Delivery, error)
+	Redeliver(int, int) error
+	Publish(string, any)
+	Queue(string, string, time.Time, any) error
+	Dispatch()
+}
+
+type service struct {
+	hooks   WebhookRepository
+	queue   DeliveryQueue
+	client  *http.Client
+	private bool
+	events  map[string]bool
+	mu      sync.Mutex
+	now     func() time.Time
+
+	maxAttempts int
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+func NewService(r WebhookRepository, q DeliveryQueue, ops ...Option) WebhookService {
+	s := &service{
+		hooks:       r,
+		queue:       q,
+		now:         time.Now,
+		maxAttempts: defaultMaxAttempts,
+		backoffBase: defaultBackoffBase,
+		backoffMax:  defaultBackoffMax,
+	}
+
+	for _, op := range ops {
+		op(s)
+	}
+
+	if s.client == nil {
+		s.client = newClient(defaultTimeout, s.private)
+	}
+
+	return s
+}
+
+func (s *service) All() ([]domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hooks.All()
+}
+
+func (s *service) GetById(id int) (domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hooks.GetById(id)
+}
+
+// Create subscribes the url to the events. A secret is generated when none
+// is given.
+func (s *service) Create(url string, events []string, secret string) (domain.Webhook, error) {
+	if secret == "" {
+		random, err := randomHex(24)
+		if err != nil {
+			return domain.Webhook{}, fmt.Errorf("%w: %s", ErrCreation, err.Error())
+		}
+		secret = secretPrefix + random
+	}
+
+	w := domain.Webhook{
+		URL:       url,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: s.now().UTC(),
+	}
+
+	if err := validator.New().Struct(&w); err != nil {
+		return domain.Webhook{}, ErrInvalidData
+	}
+
+	for _, e := range events {
+		if s.events != nil && !s.events[e] {
+			return domain.Webhook{}, fmt.Errorf("%w: %s", ErrInvalidEvent, e)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hooks.Create(w)
+}
+
+// Delete removes the webhook. Its pending deliveries are dead-lettered
+// when next attempted.
+func (s *service) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hooks.Delete(id)
+}
+
+// Deliveries returns the delivery log of the webhook, newest first.
+func (s *service) Deliveries(id int) ([]domain.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.hooks.GetById(id); err != nil {
+		return nil, err
+	}
+
+	all, err := s.queue.All()
+	if err != nil {
+		return nil, err
+	}
+
+	ds := []domain.Delivery{}
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].WebhookId == id {
+			ds = append(ds, all[i])
+		}
+	}
+
+	return ds, nil
+}
+
+// Redeliver queues a dead or succeeded delivery again, with a new round
+// of attempts.
+func (s *service) Redeliver(id int, deliveryId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.hooks.GetById(id); err != nil {
+		return err
+	}
+
+	d, err := s.queue.GetById(deliveryId)
+	if err != nil || d.WebhookId != id {
+		return ErrDeliveryNotFound
+	}
+
+	if d.Status == domain.DeliveryPending {
+		return ErrDeliveryPending
+	}
+
+	d.Status = domain.DeliveryPending
+	d.Failures = 0
+	d.NextAttemptAt = nil
+
+	return s.queue.Update(d)
+}
+
+// payload is the body of the deliveries.
+type payload struct {
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Publish queues a delivery of the event, happening now, for each
+// subscribed webhook.
+func (s *service) Publish(typ string, data any) {
+	id, err := randomHex(16)
+	if err == nil {
+		err = s.Queue(id, typ, s.now(), data)
+	}
+
+	if err != nil {
+		log.Println(fmt.Errorf("[webhook.Publish] error: %w", err))
+	}
+}
+
+// Queue queues a delivery of the event for each subscribed webhook, unless
+// the event id was already queued. The payload holds the time the event
+// happened at, which is earlier than now for events relayed late.
+func (s *service) Queue(id string, typ string, createdAt time.Time, data any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued, err := s.queue.HasEvent(id)
+	if err != nil || queued {
+		return err
+	}
+
+	hooks, err := s.hooks.All()
+	if err != nil {
+		return err
+	}
+
+	var ds []domain.Delivery
+
+	for _, w := range hooks {
+		if w.Subscribed(typ) {
+			ds = append(ds, domain.Delivery{WebhookId: w.Id})
+		}
+	}
+
+	if len(ds) == 0 {
+		return nil
+	}
+
+	now := s.now().UTC()
+
+	body, err := json.Marshal(payload{
+		Id:        id,
+		Type:      typ,
+		CreatedAt: createdAt.UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range ds {
+		ds[i].EventId = id
+		ds[i].Event = typ
+		ds[i].Payload = body
+		ds[i].Status = domain.DeliveryPending
+		ds[i].Attempts = []domain.DeliveryAttempt{}
+		ds[i].CreatedAt = now
+	}
+
+	return s.queue.Create(ds)
+}
+
+// Dispatch sends the due deliveries. Failed ones are retried with an
+// exponential backoff, and dead-lettered after the max attempts.
+func (s *service) Dispatch() {
+	s.mu.Lock()
+	all, err := s.queue.All()
+	if err != nil {
+		s.mu.Unlock()
+		log.Println(fmt.Errorf("[webhook.Dispatch] error: %w", err))
+		return
+	}
+
+	now := s.now()
+
+	var due []domain.Delivery
+	for _, d := range all {
+		if d.Due(now) {
+			due = append(due, d)
+		}
+	}
+	s.mu.Unlock()
+
+	// the lock is not held while sending
+	for _, d := range due {
+		s.mu.Lock()
+		w, err := s.hooks.GetById(d.WebhookId)
+		s.mu.Unlock()
+
+		var a domain.DeliveryAttempt
+		if err != nil {
+			a = domain.DeliveryAttempt{At: s.now().UTC(), Error: err.Error()}
+		} else {
+			a = s.send(w, d)
+		}
+
+		s.mu.Lock()
+		err = s.record(d.Id, a, err != nil)
+		s.mu.Unlock()
+
+		if err != nil {
+			log.Println(fmt.Errorf("[webhook.Dispatch] error: %w", err))
+		}
+	}
+}
+
+// send posts the payload of the delivery to the webhook.
+func (s *service) send(w domain.Webhook, d domain.Delivery) domain.DeliveryAttempt {
+	a := domain.DeliveryAttempt{At: s.now().UTC()}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "goweb-webhooks")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(IdHeader, d.EventId)
+	req.Header.Set(SignatureHeader, Sign(w.Secret, a.At, d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer res.Body.Close()
+
+	// drained, so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	a.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		a.Error = ErrUnexpectedStatus.Error()
+	}
+
+	return a
+}
+
+// record logs the attempt and schedules the next one, if any. Deliveries of
+// deleted webhooks are dead-lettered at once.
+func (s *service) record(id int, a domain.DeliveryAttempt, gone bool) error {
+	d, err := s.queue.GetById(id)
+	if err != nil {
+		return err
+	}
+
+	d.Attempts = append(d.Attempts, a)
+	d.NextAttemptAt = nil
+
+	switch {
+	case a.Error == "":
+		d.Status = domain.DeliverySucceeded
+	case gone:
+		d.Status = domain.DeliveryDead
+	default:
+		d.Failures++
+		if d.Failures >= s.maxAttempts {
+			d.Status = domain.DeliveryDead
+			break
+		}
+
+		next := a.At.Add(s.backoff(d.Failures))
+		d.NextAttemptAt = &next
+	}
+
+	return s.queue.Update(d)
+}
+
+// backoff is the wait after the given failed attempts.
+func (s *service) backoff(failures int) time.Duration {
+	d := s.backoffBase
+	for i := 1; i < failures && d < s.backoffMax; i++ {
+		d *= 2
+	}
+
+	if d > s.backoffMax {
+		d = s.backoffMax
+	}
+
+	return d
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"gituhb.com/juajosserand/goweb/pkg/outbox"
+)
+
+type sink struct {
+	svc WebhookService
+}
+
+// NewSink queues the deliveries of the relayed events. The outbox key is
+// the event id of the deliveries, so that an event is queued once, also
+// when the outbox ids restart with an in-memory store.
+func NewSink(s WebhookService) outbox.Sink {
+	return sink{
+		svc: s,
+	}
+}
+
+func (s sink) Publish(e outbox.Event) error {
+	return s.svc.Queue(e.Key, e.Type, e.CreatedAt, e.Data)
+}
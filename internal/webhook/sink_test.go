@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+	"gituhb.com/juajosserand/goweb/pkg/outbox"
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+func newTestSink(t *testing.T) (outbox.Sink, *queue) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	require.NoError(t, storage.WriteFile(path, []domain.Webhook{
+		{Id: 1, URL: "https://example.com/hook", Events: []string{"product.updated"}},
+	}))
+	t.Setenv("WEBHOOKS_FILENAME", path)
+
+	r, err := NewRepository()
+	require.NoError(t, err)
+
+	q := newTestQueue(t, maxDeliveries)
+
+	return NewSink(NewService(r, q)), q
+}
+
+func TestSinkAfterRestart(t *testing.T) {
+	sink, q := newTestSink(t)
+
+	// in-memory outboxes number their events from 1 on every start
+	for i := 0; i < 2; i++ {
+		s, err := outbox.NewStore("")
+		require.NoError(t, err)
+
+		id, err := s.Stage("product.updated", i)
+		require.NoError(t, err)
+		require.NoError(t, s.Commit(id, i))
+
+		relay := outbox.NewRelay(s, outbox.WithSink("webhooks", sink))
+		relay.Relay()
+	}
+
+	ds, err := q.All()
+	require.NoError(t, err)
+	require.Len(t, ds, 2)
+	assert.NotEqual(t, ds[0].EventId, ds[1].EventId)
+}
+
+func TestSinkEventTime(t *testing.T) {
+	sink, q := newTestSink(t)
+
+	// relayed late, e.g. after a failed attempt or a restart
+	happened := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, sink.Publish(outbox.Event{
+		Id:        1,
+		Key:       "k1",
+		Type:      "product.updated",
+		Data:      []byte(`{"id":1}`),
+		CreatedAt: happened,
+	}))
+
+	ds, err := q.All()
+	require.NoError(t, err)
+	require.Len(t, ds, 1)
+	assert.True(t, ds[0].CreatedAt.After(happened))
+
+	var body payload
+	require.NoError(t, json.Unmarshal(ds[0].Payload, &body))
+	assert.Equal(t, "k1", body.Id)
+	assert.True(t, happened.Equal(body.CreatedAt))
+}
@@ -0,0 +1,222 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the products api. Failed requests are retried with
+// exponential backoff on network errors, 429 and 502-504. POST requests are
+// sent with an Idempotency-Key, so that their retries are safe.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	token      string
+	apiKey     string
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func New(baseURL string, ops ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		http:       &http.Client{Timeout: 10 * time.Second},
+		retries:    3,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+
+	for _, op := range ops {
+		op(c)
+	}
+
+	return c
+}
+
+type envelope struct {
+	Data json.RawMessage `json:"data"`
+}
+
+// do sends the request, decoding the data of the response into out when
+// given. Any status in ok is a success.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any, ok ...int) error {
+	var payload []byte
+
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var idempotencyKey string
+	if method == http.MethodPost {
+		idempotencyKey = newIdempotencyKey()
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, u, payload, idempotencyKey)
+
+		if attempt < c.retries && retryable(res, err) {
+			wait := c.wait(attempt, res)
+			if res != nil {
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+				continue
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return decode(res, out, ok)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method string, u string, payload []byte, idempotencyKey string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.http.Do(req)
+}
+
+func decode(res *http.Response, out any, ok []int) error {
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range ok {
+		if res.StatusCode != status {
+			continue
+		}
+
+		if out == nil {
+			return nil
+		}
+
+		var env envelope
+		err = json.Unmarshal(data, &env)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnexpected, err.Error())
+		}
+
+		return json.Unmarshal(env.Data, out)
+	}
+
+	apiErr := &Error{Status: res.StatusCode}
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Code = strings.ToLower(http.StatusText(res.StatusCode))
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	apiErr.classify()
+
+	return apiErr
+}
+
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		// the caller gave up
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// wait returns the Retry-After of the response when sent, or the backoff of
+// the attempt with jitter.
+func (c *Client) wait(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s >= 0 {
+			return shorter(time.Duration(s)*time.Second, c.maxBackoff)
+		}
+	}
+
+	d := shorter(c.backoff<<attempt, c.maxBackoff)
+	if d <= 0 {
+		return 0
+	}
+
+	// up to half of the wait is random, so that clients spread out
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(d/2)+1))
+	if err != nil {
+		return d
+	}
+
+	return d/2 + time.Duration(jitter.Int64())
+}
+
+func shorter(a time.Duration, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
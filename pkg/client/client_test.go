@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/cmd/handler"
+	"gituhb.com/juajosserand/goweb/internal/product"
+	"gituhb.com/juajosserand/goweb/pkg/jwt"
+)
+
+const testSecret = "test-secret"
+
+func token(t *testing.T, role string) string {
+	token, err := jwt.SignHS256(map[string]any{
+		"sub":  "client-test",
+		"role": role,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}, []byte(testSecret))
+	require.NoError(t, err)
+
+	return token
+}
+
+// server runs the real product handlers on an empty catalog.
+func server(t *testing.T) *httptest.Server {
+	filename := filepath.Join(t.TempDir(), "products.json")
+	require.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+	t.Setenv("PRODUCTS_FILENAME", filename)
+
+	repo, err := product.NewRepository()
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	mux := gin.New()
+	mux.Use(handler.NewValidation(mux).Handle)
+	handler.NewProduct(mux, product.NewService(repo), handler.NewAuth(
+		jwt.NewVerifier(jwt.HS256Secret([]byte(testSecret))),
+		handler.DefaultPolicy(),
+		nil,
+	))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestClientProducts(t *testing.T) {
+	ctx := context.Background()
+	c := New(server(t).URL, BearerToken(token(t, "admin")))
+
+	err := c.Create(ctx, ProductRequest{
+		Name:        "Client Product",
+		Quantity:    10,
+		CodeValue:   "CLIENT1",
+		IsPublished: true,
+		Expiration:  "01/01/2030",
+		Price:       25,
+	})
+	require.NoError(t, err)
+
+	ps, err := c.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+	id := ps[0].Id
+
+	p, err := c.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "CLIENT1", p.CodeValue)
+
+	price := 30.0
+	err = c.Patch(ctx, id, ProductPatch{Price: &price})
+	require.NoError(t, err)
+
+	err = c.Update(ctx, id, ProductRequest{
+		Name:        "Client Product Renamed",
+		Quantity:    10,
+		CodeValue:   "CLIENT1",
+		IsPublished: true,
+		Expiration:  "01/01/2030",
+		Price:       30,
+	})
+	require.NoError(t, err)
+
+	priceGt := 29.0
+	ps, err = c.Search(ctx, SearchParams{PriceGt: &priceGt})
+	require.NoError(t, err)
+	require.Len(t, ps, 1)
+	assert.Equal(t, "Client Product Renamed", ps[0].Name)
+
+	cp, err := c.ConsumerPrice(ctx, []int{id, id}, nil)
+	require.NoError(t, err)
+	assert.Len(t, cp.Products, 1)
+	assert.Greater(t, cp.TotalPrice, 0.0)
+
+	err = c.Delete(ctx, id)
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, id)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.Status)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := server(t)
+
+	admin := New(srv.URL, BearerToken(token(t, "admin")))
+
+	err := admin.Create(ctx, ProductRequest{Name: "x"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	var apiErr *Error
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.NotEmpty(t, apiErr.Fields)
+	}
+
+	valid := ProductRequest{
+		Name:        "Client Product",
+		Quantity:    1,
+		CodeValue:   "CLIENT2",
+		IsPublished: true,
+		Expiration:  "01/01/2030",
+		Price:       1,
+	}
+
+	err = New(srv.URL).Create(ctx, valid)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	err = New(srv.URL, BearerToken(token(t, "viewer"))).Create(ctx, valid)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	require.NoError(t, admin.Create(ctx, valid))
+
+	err = admin.Create(ctx, valid)
+	assert.ErrorIs(t, err, ErrDuplicatedCodeValue)
+
+	err = admin.Delete(ctx, 999)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, Backoff(time.Millisecond, 10*time.Millisecond))
+
+	ps, err := c.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, -10)
+
+	_, err = New(srv.URL, Retries(1), Backoff(time.Millisecond, time.Millisecond)).List(context.Background())
+	assert.True(t, errors.Is(err, ErrServer))
+}
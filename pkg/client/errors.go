@@ -0,0 +1,106 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gituhb.com/juajosserand/goweb/internal/product"
+)
+
+// Errors returned by the api, matched by message so that callers can use
+// errors.Is against the same values as the server.
+var (
+	ErrInvalidData              = product.ErrInvalidData
+	ErrCreation                 = product.ErrCreation
+	ErrDeletion                 = product.ErrDeletion
+	ErrNotFound                 = product.ErrNotFound
+	ErrInvalidId                = product.ErrInvalidId
+	ErrInvalidPrice             = product.ErrInvalidPrice
+	ErrDuplicatedCodeValue      = product.ErrDuplicatedCodeValue
+	ErrInvalidConsumerPriceList = product.ErrInvalidConsumerPriceList
+	ErrNoStock                  = product.ErrNoStock
+	ErrNotPublished             = product.ErrNotPublished
+	ErrVariantNotFound          = product.ErrVariantNotFound
+	ErrInvalidAttributes        = product.ErrInvalidAttributes
+	ErrMediaNotFound            = product.ErrMediaNotFound
+)
+
+// Errors matched by status when the message is not a known one.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+	ErrUnexpected   = errors.New("unexpected response")
+)
+
+var knownErrors = []error{
+	ErrInvalidData,
+	ErrCreation,
+	ErrDeletion,
+	ErrNotFound,
+	ErrInvalidId,
+	ErrInvalidPrice,
+	ErrDuplicatedCodeValue,
+	ErrInvalidConsumerPriceList,
+	ErrNoStock,
+	ErrNotPublished,
+	ErrVariantNotFound,
+	ErrInvalidAttributes,
+	ErrMediaNotFound,
+}
+
+// FieldError describes an invalid field reported by the api.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the api. It unwraps to one of the Err
+// values.
+type Error struct {
+	Status  int          `json:"status"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"errors,omitempty"`
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// classify sets the error wrapped by e from its message or status.
+func (e *Error) classify() {
+	for _, known := range knownErrors {
+		if e.Message == known.Error() {
+			e.err = known
+			return
+		}
+	}
+
+	switch {
+	case e.Status == http.StatusUnauthorized:
+		e.err = ErrUnauthorized
+	case e.Status == http.StatusForbidden:
+		e.err = ErrForbidden
+	case e.Status == http.StatusTooManyRequests:
+		e.err = ErrRateLimited
+	case e.Status == http.StatusNotFound:
+		e.err = ErrNotFound
+	case e.Status >= http.StatusInternalServerError:
+		e.err = ErrServer
+	case e.Status >= http.StatusBadRequest:
+		e.err = ErrBadRequest
+	default:
+		e.err = ErrUnexpected
+	}
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+type Option func(*Client)
+
+func HTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.http = c
+	}
+}
+
+// BearerToken authenticates requests with a jwt.
+func BearerToken(token string) Option {
+	return func(cl *Client) {
+		cl.token = token
+	}
+}
+
+// APIKey authenticates requests with an api key, taking precedence over a
+// bearer token.
+func APIKey(key string) Option {
+	return func(cl *Client) {
+		cl.apiKey = key
+	}
+}
+
+// Retries sets how many times failed requests are retried.
+func Retries(n int) Option {
+	return func(cl *Client) {
+		cl.retries = n
+	}
+}
+
+// Backoff sets the first and the longest wait between retries. Waits
+// double on each retry, unless the server sends Retry-After.
+func Backoff(base time.Duration, max time.Duration) Option {
+	return func(cl *Client) {
+		cl.backoff = base
+		cl.maxBackoff = max
+	}
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+type (
+	Product           = domain.Product
+	Variant           = domain.Variant
+	VariantAttributes = domain.VariantAttributes
+	Media             = domain.Media
+	ReorderSuggestion = domain.ReorderSuggestion
+)
+
+// ProductRequest creates or replaces a product.
+type ProductRequest struct {
+	Name        string         `json:"name"`
+	Quantity    int            `json:"quantity"`
+	CodeValue   string         `json:"code_value"`
+	IsPublished bool           `json:"is_published"`
+	Expiration  string         `json:"expiration"`
+	Price       float64        `json:"price"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+}
+
+// ProductPatch updates the non nil fields of a product.
+type ProductPatch struct {
+	Name        *string        `json:"name,omitempty"`
+	Quantity    *int           `json:"quantity,omitempty"`
+	CodeValue   *string        `json:"code_value,omitempty"`
+	IsPublished *bool          `json:"is_published,omitempty"`
+	Expiration  *string        `json:"expiration,omitempty"`
+	Price       *float64       `json:"price,omitempty"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+}
+
+// VariantRequest creates or replaces a variant.
+type VariantRequest struct {
+	CodeValue  string            `json:"code_value"`
+	Price      float64           `json:"price"`
+	Quantity   int               `json:"quantity"`
+	Attributes VariantAttributes `json:"attributes"`
+}
+
+// SearchParams filters products. Either field is required.
+type SearchParams struct {
+	PriceGt    *float64
+	Attributes map[string]string
+}
+
+// ConsumerPrice is the price of a list of products and variants.
+type ConsumerPrice struct {
+	Products   []Product `json:"products"`
+	Variants   []Variant `json:"variants"`
+	TotalPrice float64   `json:"total_price"`
+}
+
+func (c *Client) List(ctx context.Context) ([]Product, error) {
+	var ps []Product
+
+	err := c.do(ctx, http.MethodGet, "/products/", nil, nil, &ps, http.StatusOK)
+
+	return ps, err
+}
+
+func (c *Client) Get(ctx context.Context, id int) (Product, error) {
+	var p Product
+
+	// the api answers with 302 Found
+	err := c.do(ctx, http.MethodGet, productPath(id), nil, nil, &p, http.StatusOK, http.StatusFound)
+
+	return p, err
+}
+
+func (c *Client) Search(ctx context.Context, params SearchParams) ([]Product, error) {
+	query := url.Values{}
+
+	if params.PriceGt != nil {
+		query.Set("priceGt", strconv.FormatFloat(*params.PriceGt, 'f', -1, 64))
+	}
+
+	for k, v := range params.Attributes {
+		query.Set("attributes["+k+"]", v)
+	}
+
+	var ps []Product
+
+	err := c.do(ctx, http.MethodGet, "/products/search", query, nil, &ps, http.StatusOK)
+
+	return ps, err
+}
+
+// ConsumerPrice prices the products and variants, repeated once per unit.
+func (c *Client) ConsumerPrice(ctx context.Context, ids []int, variantCodes []string) (ConsumerPrice, error) {
+	query := url.Values{}
+
+	if len(ids) > 0 {
+		list := make([]string, 0, len(ids))
+		for _, id := range ids {
+			list = append(list, strconv.Itoa(id))
+		}
+		query.Set("list", "["+strings.Join(list, ",")+"]")
+	}
+
+	if len(variantCodes) > 0 {
+		query.Set("variants", "["+strings.Join(variantCodes, ",")+"]")
+	}
+
+	var cp ConsumerPrice
+
+	err := c.do(ctx, http.MethodGet, "/products/consumer_price", query, nil, &cp, http.StatusOK)
+
+	return cp, err
+}
+
+func (c *Client) Create(ctx context.Context, r ProductRequest) error {
+	return c.do(ctx, http.MethodPost, "/products/", nil, r, nil, http.StatusCreated)
+}
+
+func (c *Client) Update(ctx context.Context, id int, r ProductRequest) error {
+	return c.do(ctx, http.MethodPut, productPath(id), nil, r, nil, http.StatusNoContent)
+}
+
+func (c *Client) Patch(ctx context.Context, id int, p ProductPatch) error {
+	return c.do(ctx, http.MethodPatch, productPath(id), nil, p, nil, http.StatusNoContent)
+}
+
+func (c *Client) Delete(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, productPath(id), nil, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) CreateVariant(ctx context.Context, id int, r VariantRequest) error {
+	return c.do(ctx, http.MethodPost, productPath(id)+"/variants", nil, r, nil, http.StatusCreated)
+}
+
+func (c *Client) UpdateVariant(ctx context.Context, id int, code string, r VariantRequest) error {
+	return c.do(ctx, http.MethodPut, variantPath(id, code), nil, r, nil, http.StatusNoContent)
+}
+
+func (c *Client) DeleteVariant(ctx context.Context, id int, code string) error {
+	return c.do(ctx, http.MethodDelete, variantPath(id, code), nil, nil, nil, http.StatusNoContent)
+}
+
+func (c *Client) SetReorder(ctx context.Context, id int, point int, quantity int) error {
+	body := map[string]int{
+		"reorder_point":    point,
+		"reorder_quantity": quantity,
+	}
+
+	return c.do(ctx, http.MethodPut, productPath(id)+"/reorder", nil, body, nil, http.StatusNoContent)
+}
+
+func (c *Client) ReorderReport(ctx context.Context) ([]ReorderSuggestion, error) {
+	var rs []ReorderSuggestion
+
+	err := c.do(ctx, http.MethodGet, "/products/reorder", nil, nil, &rs, http.StatusOK)
+
+	return rs, err
+}
+
+func productPath(id int) string {
+	return fmt.Sprintf("/products/%d", id)
+}
+
+func variantPath(id int, code string) string {
+	return fmt.Sprintf("/products/%d/variants/%s", id, url.PathEscape(code))
+}
@@ -0,0 +1,200 @@
+package complexity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var (
+	ErrTooComplex    = errors.New("query is too complex")
+	ErrTooDeep       = errors.New("query is too deep")
+	ErrNoOperation   = errors.New("unable to find the query operation")
+	ErrFragmentCycle = errors.New("fragments spread each other")
+)
+
+// Rules price the fields of a query. Every field costs one, and fields
+// returning lists multiply the cost of their selections by their size.
+type Rules struct {
+	// ListSizes are the sizes of list fields by name.
+	ListSizes map[string]int
+	// SizeArg names the argument bounding the size of a list field, e.g.
+	// "first", which overrides ListSizes.
+	SizeArg string
+
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// Cost of a query operation.
+type Cost struct {
+	Complexity int
+	Depth      int
+}
+
+// Check measures the operation and returns ErrTooComplex or ErrTooDeep when
+// it is over the limits. Zero limits are not enforced.
+func (r Rules) Check(doc *ast.Document, operationName string, variables map[string]any) (Cost, error) {
+	c, err := r.Measure(doc, operationName, variables)
+	if err != nil {
+		return c, err
+	}
+
+	if r.MaxDepth > 0 && c.Depth > r.MaxDepth {
+		return c, fmt.Errorf("%w: depth %d exceeds %d", ErrTooDeep, c.Depth, r.MaxDepth)
+	}
+
+	if r.MaxComplexity > 0 && c.Complexity > r.MaxComplexity {
+		return c, fmt.Errorf("%w: complexity %d exceeds %d", ErrTooComplex, c.Complexity, r.MaxComplexity)
+	}
+
+	return c, nil
+}
+
+// Measure returns the cost of the named operation, or of the only one of
+// the document when name is empty.
+func (r Rules) Measure(doc *ast.Document, operationName string, variables map[string]any) (Cost, error) {
+	var op *ast.OperationDefinition
+	fragments := make(map[string]*ast.FragmentDefinition)
+
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" && op != nil {
+				return Cost{}, ErrNoOperation
+			}
+
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				op = d
+			}
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		}
+	}
+
+	if op == nil {
+		return Cost{}, ErrNoOperation
+	}
+
+	m := measurer{
+		rules:     r,
+		fragments: fragments,
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+
+	return m.selections(op.SelectionSet, 0)
+}
+
+type measurer struct {
+	rules     Rules
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	visiting  map[string]bool
+}
+
+func (m measurer) selections(set *ast.SelectionSet, depth int) (Cost, error) {
+	var total Cost
+
+	if set == nil {
+		return total, nil
+	}
+
+	for _, sel := range set.Selections {
+		var (
+			c   Cost
+			err error
+		)
+
+		switch s := sel.(type) {
+		case *ast.Field:
+			c, err = m.field(s, depth)
+		case *ast.InlineFragment:
+			c, err = m.selections(s.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			c, err = m.spread(s, depth)
+		}
+
+		if err != nil {
+			return Cost{}, err
+		}
+
+		total.Complexity += c.Complexity
+		if c.Depth > total.Depth {
+			total.Depth = c.Depth
+		}
+	}
+
+	return total, nil
+}
+
+func (m measurer) field(f *ast.Field, depth int) (Cost, error) {
+	children, err := m.selections(f.SelectionSet, depth+1)
+	if err != nil {
+		return Cost{}, err
+	}
+
+	return Cost{
+		Complexity: 1 + m.size(f)*children.Complexity,
+		Depth:      maxInt(depth+1, children.Depth),
+	}, nil
+}
+
+func (m measurer) spread(s *ast.FragmentSpread, depth int) (Cost, error) {
+	name := s.Name.Value
+
+	frag, ok := m.fragments[name]
+	if !ok {
+		// reported by the validation of the query
+		return Cost{}, nil
+	}
+
+	if m.visiting[name] {
+		return Cost{}, fmt.Errorf("%w: %s", ErrFragmentCycle, name)
+	}
+
+	m.visiting[name] = true
+	defer delete(m.visiting, name)
+
+	return m.selections(frag.SelectionSet, depth)
+}
+
+// size returns the size of list fields, and 1 for the other fields.
+func (m measurer) size(f *ast.Field) int {
+	size, ok := m.rules.ListSizes[f.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != m.rules.SizeArg {
+			continue
+		}
+
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n >= 0 {
+				size = n
+			}
+		case *ast.Variable:
+			switch n := m.variables[v.Name.Value].(type) {
+			case int:
+				size = maxInt(n, 0)
+			case float64:
+				size = maxInt(int(n), 0)
+			}
+		}
+	}
+
+	return size
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
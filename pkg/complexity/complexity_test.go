@@ -0,0 +1,78 @@
+package complexity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+var rules = Rules{
+	ListSizes:     map[string]int{"products": 20, "variants": 10},
+	SizeArg:       "first",
+	MaxComplexity: 100,
+	MaxDepth:      3,
+}
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		cost      Cost
+		err       error
+	}{
+		{
+			name:  "scalar fields",
+			query: `{ product(id: 1) { id name } }`,
+			cost:  Cost{Complexity: 3, Depth: 2},
+		},
+		{
+			name:  "default list size",
+			query: `{ products { id name } }`,
+			cost:  Cost{Complexity: 41, Depth: 2},
+		},
+		{
+			name:      "size argument",
+			query:     `query($n: Int) { products(first: $n) { id variants { price } } }`,
+			variables: map[string]any{"n": float64(2)},
+			cost:      Cost{Complexity: 25, Depth: 3},
+		},
+		{
+			name:  "fragments",
+			query: `{ product(id: 1) { ...f } } fragment f on Product { id ... on Product { name } }`,
+			cost:  Cost{Complexity: 3, Depth: 2},
+		},
+		{
+			name:  "too complex",
+			query: `{ products(first: 10) { variants { price } } }`,
+			cost:  Cost{Complexity: 111, Depth: 3},
+			err:   ErrTooComplex,
+		},
+		{
+			name:  "too deep",
+			query: `{ a { b { c { d } } } }`,
+			cost:  Cost{Complexity: 4, Depth: 4},
+			err:   ErrTooDeep,
+		},
+		{
+			name:  "fragment cycle",
+			query: `{ ...a } fragment a on Query { ...b } fragment b on Query { ...a }`,
+			err:   ErrFragmentCycle,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: test.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cost, err := rules.Check(doc, "", test.variables)
+			assert.True(t, errors.Is(err, test.err), err)
+			assert.Equal(t, test.cost, cost)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrGap is returned by Subscribe when events after the last id were
+// dropped from the buffer, so that subscribers reload their state.
+var ErrGap = errors.New("events after the last id are no longer buffered")
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before it is dropped.
+const subscriberBuffer = 64
+
+type Event struct {
+	Id   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Bus fans events out to subscribers and keeps the last ones in a ring
+// buffer, so that subscribers can resume after reconnecting.
+type Bus struct {
+	mu          sync.Mutex
+	ring        []Event
+	head        int
+	count       int
+	lastId      uint64
+	subscribers map[*Subscription]struct{}
+}
+
+func NewBus(size int) *Bus {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Bus{
+		ring:        make([]Event, size),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish buffers the event and sends it to the subscribers. Subscribers
+// too far behind are dropped, and their channel closed.
+func (b *Bus) Publish(typ string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastId++
+	e := Event{
+		Id:   b.lastId,
+		Type: typ,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+
+	b.ring[(b.head+b.count)%len(b.ring)] = e
+	if b.count < len(b.ring) {
+		b.count++
+	} else {
+		b.head = (b.head + 1) % len(b.ring)
+	}
+
+	for s := range b.subscribers {
+		select {
+		case s.ch <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Subscription receives the events published after it was created. Missed
+// holds the buffered events after the last id given to Subscribe.
+type Subscription struct {
+	C      <-chan Event
+	Missed []Event
+
+	bus *Bus
+	ch  chan Event
+}
+
+// Subscribe starts receiving events. Buffered events after lastId are
+// replayed in Missed, unless lastId is zero. ErrGap is returned with the
+// subscription when some of them were dropped.
+func (b *Bus) Subscribe(lastId uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	s := &Subscription{
+		C:   ch,
+		bus: b,
+		ch:  ch,
+	}
+	b.subscribers[s] = struct{}{}
+
+	if lastId == 0 {
+		return s, nil
+	}
+
+	var err error
+	if lastId > b.lastId || (b.count > 0 && lastId+1 < b.ring[b.head].Id) {
+		err = ErrGap
+	}
+
+	for i := 0; i < b.count; i++ {
+		e := b.ring[(b.head+i)%len(b.ring)]
+		if e.Id > lastId {
+			s.Missed = append(s.Missed, e)
+		}
+	}
+
+	return s, err
+}
+
+// Close stops the subscription and closes its channel.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	s.bus.drop(s)
+}
+
+func (b *Bus) drop(s *Subscription) {
+	if _, ok := b.subscribers[s]; !ok {
+		return
+	}
+
+	delete(b.subscribers, s)
+	close(s.ch)
+}
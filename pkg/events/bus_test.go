@@ -0,0 +1,79 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(es []Event) []uint64 {
+	var ids []uint64
+	for _, e := range es {
+		ids = append(ids, e.Id)
+	}
+
+	return ids
+}
+
+func TestSubscribe(t *testing.T) {
+	b := NewBus(3)
+	for i := 0; i < 5; i++ {
+		b.Publish("product.updated", i)
+	}
+
+	tests := []struct {
+		name   string
+		lastId uint64
+		missed []uint64
+		err    error
+	}{
+		{name: "no replay", lastId: 0},
+		{name: "buffered", lastId: 3, missed: []uint64{4, 5}},
+		{name: "oldest buffered", lastId: 2, missed: []uint64{3, 4, 5}},
+		{name: "up to date", lastId: 5},
+		{name: "dropped", lastId: 1, missed: []uint64{3, 4, 5}, err: ErrGap},
+		{name: "unknown", lastId: 9, err: ErrGap},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := b.Subscribe(test.lastId)
+			defer s.Close()
+
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.missed, ids(s.Missed))
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	b := NewBus(subscriberBuffer * 2)
+
+	s, _ := b.Subscribe(0)
+	b.Publish("product.created", 1)
+
+	e := <-s.C
+	assert.Equal(t, uint64(1), e.Id)
+	assert.Equal(t, "product.created", e.Type)
+	assert.Equal(t, 1, e.Data)
+
+	// lagging subscribers are dropped
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish("product.updated", i)
+	}
+
+	n := 0
+	for range s.C {
+		n++
+	}
+	assert.Equal(t, subscriberBuffer, n)
+
+	// and resume from the buffer
+	r, err := b.Subscribe(1 + uint64(n))
+	defer r.Close()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{uint64(subscriberBuffer) + 2}, ids(r.Missed))
+
+	s.Close()
+}
@@ -0,0 +1,11 @@
+package grpcserver
+
+import "net"
+
+type Option func(*server)
+
+func Port(p string) Option {
+	return func(s *server) {
+		s.addr = net.JoinHostPort("", p)
+	}
+}
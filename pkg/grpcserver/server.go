@@ -0,0 +1,74 @@
+package grpcserver
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ErrForcedStop is returned by Shutdown when calls were still running after
+// the timeout and had to be cancelled.
+var ErrForcedStop = errors.New("grpc server stopped before its calls finished")
+
+type server struct {
+	server  *grpc.Server
+	addr    string
+	timeout time.Duration
+	notify  chan error
+}
+
+// New serves the services registered on g.
+func New(g *grpc.Server, ops ...Option) *server {
+	s := &server{
+		server:  g,
+		addr:    ":9090",
+		timeout: 3 * time.Second,
+		notify:  make(chan error),
+	}
+
+	for _, op := range ops {
+		op(s)
+	}
+
+	s.run()
+
+	return s
+}
+
+func (s *server) run() {
+	go func() {
+		lis, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			s.notify <- err
+			close(s.notify)
+			return
+		}
+
+		s.notify <- s.server.Serve(lis)
+		close(s.notify)
+	}()
+}
+
+func (s *server) Notify() <-chan error {
+	return s.notify
+}
+
+// Shutdown waits for the running calls until the timeout, and then cancels
+// them.
+func (s *server) Shutdown() error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(s.timeout):
+		s.server.Stop()
+		return ErrForcedStop
+	}
+}
@@ -1,6 +1,9 @@
 package httpserver
 
-import "net"
+import (
+	"context"
+	"net"
+)
 
 type Option func(*server)
 
@@ -9,3 +12,10 @@ func Port(p string) Option {
 		s.server.Addr = net.JoinHostPort("", p)
 	}
 }
+
+// ConnContext modifies the context of the requests of each connection.
+func ConnContext(f func(context.Context, net.Conn) context.Context) Option {
+	return func(s *server) {
+		s.server.ConnContext = f
+	}
+}
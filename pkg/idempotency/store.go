@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInFlight = errors.New("request with the same idempotency key in progress")
+	ErrMismatch = errors.New("idempotency key reused with a different request")
+)
+
+// Response is a stored response to be replayed.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type record struct {
+	fingerprint string
+	done        bool
+	response    Response
+	expires     time.Time
+}
+
+// Store keeps the fingerprint and response of each key for a ttl. Records
+// are kept in memory and do not survive a restart.
+type Store struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	records map[string]*record
+	now     func() time.Time
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{
+		ttl:     ttl,
+		records: make(map[string]*record),
+		now:     time.Now,
+	}
+}
+
+// Begin claims the key for a request with the given fingerprint. It returns
+// the stored response and true when the request was already completed,
+// ErrInFlight when it is still in progress and ErrMismatch when the key was
+// used for a different request.
+func (s *Store) Begin(key string, fingerprint string) (Response, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	r, ok := s.records[key]
+	if !ok {
+		s.records[key] = &record{
+			fingerprint: fingerprint,
+			expires:     now.Add(s.ttl),
+		}
+		return Response{}, false, nil
+	}
+
+	if r.fingerprint != fingerprint {
+		return Response{}, false, ErrMismatch
+	}
+
+	if !r.done {
+		return Response{}, false, ErrInFlight
+	}
+
+	return r.response, true, nil
+}
+
+// Complete stores the response of the key.
+func (s *Store) Complete(key string, res Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok {
+		r.done = true
+		r.response = res
+		r.expires = s.now().Add(s.ttl)
+	}
+}
+
+// Release forgets the key, so that the request can be retried.
+func (s *Store) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+}
+
+// sweep drops the expired records. Records in progress expire a ttl after
+// they began, in case they were never completed nor released.
+func (s *Store) sweep(now time.Time) {
+	for k, r := range s.records {
+		if now.After(r.expires) {
+			delete(s.records, k)
+		}
+	}
+}
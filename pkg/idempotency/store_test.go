@@ -0,0 +1,106 @@
+package idempotency
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time source for the store.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestStore(ttl time.Duration) (*Store, *clock) {
+	c := &clock{t: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	s := NewStore(ttl)
+	s.now = c.now
+
+	return s, c
+}
+
+func TestStoreReplay(t *testing.T) {
+	s, _ := newTestStore(time.Hour)
+
+	_, replay, err := s.Begin("k", "f")
+	require.NoError(t, err)
+	assert.False(t, replay)
+
+	res := Response{Status: http.StatusCreated, Body: []byte(`{"id":1}`)}
+	s.Complete("k", res)
+
+	got, replay, err := s.Begin("k", "f")
+	require.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, res, got)
+}
+
+func TestStoreMismatch(t *testing.T) {
+	s, _ := newTestStore(time.Hour)
+
+	_, _, err := s.Begin("k", "f")
+	require.NoError(t, err)
+
+	_, _, err = s.Begin("k", "other")
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	s.Complete("k", Response{Status: http.StatusCreated})
+
+	_, _, err = s.Begin("k", "other")
+	assert.ErrorIs(t, err, ErrMismatch)
+}
+
+func TestStoreInFlight(t *testing.T) {
+	s, _ := newTestStore(time.Hour)
+
+	_, _, err := s.Begin("k", "f")
+	require.NoError(t, err)
+
+	_, _, err = s.Begin("k", "f")
+	assert.ErrorIs(t, err, ErrInFlight)
+
+	// released keys can be retried
+	s.Release("k")
+
+	_, replay, err := s.Begin("k", "f")
+	assert.NoError(t, err)
+	assert.False(t, replay)
+}
+
+func TestStoreExpiry(t *testing.T) {
+	s, c := newTestStore(time.Hour)
+
+	_, _, err := s.Begin("done", "f")
+	require.NoError(t, err)
+	s.Complete("done", Response{Status: http.StatusCreated})
+
+	// never completed nor released
+	_, _, err = s.Begin("stuck", "f")
+	require.NoError(t, err)
+
+	c.t = c.t.Add(time.Hour)
+
+	_, replay, err := s.Begin("done", "f")
+	require.NoError(t, err)
+	assert.True(t, replay)
+
+	_, _, err = s.Begin("stuck", "f")
+	assert.ErrorIs(t, err, ErrInFlight)
+
+	c.t = c.t.Add(time.Second)
+
+	_, replay, err = s.Begin("done", "f")
+	assert.NoError(t, err)
+	assert.False(t, replay)
+
+	_, _, err = s.Begin("stuck", "f")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Claims holds the registered claims of a token. Any other claim is kept in
+// Extra.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Extra     map[string]any
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	if err := d.Decode(&raw); err != nil {
+		return err
+	}
+
+	c.Extra = make(map[string]any)
+
+	for k, v := range raw {
+		switch k {
+		case "sub":
+			c.Subject, _ = v.(string)
+		case "iss":
+			c.Issuer, _ = v.(string)
+		case "aud":
+			switch aud := v.(type) {
+			case string:
+				c.Audience = []string{aud}
+			case []any:
+				for _, a := range aud {
+					if s, ok := a.(string); ok {
+						c.Audience = append(c.Audience, s)
+					}
+				}
+			}
+		case "exp":
+			c.ExpiresAt = numericDate(v)
+		case "nbf":
+			c.NotBefore = numericDate(v)
+		case "iat":
+			c.IssuedAt = numericDate(v)
+		default:
+			c.Extra[k] = v
+		}
+	}
+
+	return nil
+}
+
+// Strings returns a claim holding either a space separated string, like the
+// OAuth scope claim, or an array of strings.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Extra[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var ss []string
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				ss = append(ss, str)
+			}
+		}
+		return ss
+	}
+
+	return nil
+}
+
+func numericDate(v any) time.Time {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(f), 0)
+}
@@ -0,0 +1,17 @@
+package jwt
+
+import (
+	"errors"
+)
+
+var (
+	ErrMalformed   = errors.New("malformed token")
+	ErrAlgorithm   = errors.New("unsupported token algorithm")
+	ErrUnknownKey  = errors.New("unknown token signing key")
+	ErrSignature   = errors.New("invalid token signature")
+	ErrExpired     = errors.New("token is expired")
+	ErrNotYetValid = errors.New("token is not valid yet")
+	ErrAudience    = errors.New("invalid token audience")
+	ErrIssuer      = errors.New("invalid token issuer")
+	ErrInvalidJWKS = errors.New("invalid jwks file")
+)
@@ -0,0 +1,38 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"time"
+)
+
+type Option func(*Verifier)
+
+func HS256Secret(secret []byte) Option {
+	return func(v *Verifier) {
+		v.secret = secret
+	}
+}
+
+func RS256Keys(keys map[string]*rsa.PublicKey) Option {
+	return func(v *Verifier) {
+		v.keys = keys
+	}
+}
+
+func Audience(aud string) Option {
+	return func(v *Verifier) {
+		v.audience = aud
+	}
+}
+
+func Issuer(iss string) Option {
+	return func(v *Verifier) {
+		v.issuer = iss
+	}
+}
+
+func Leeway(d time.Duration) Option {
+	return func(v *Verifier) {
+		v.leeway = d
+	}
+}
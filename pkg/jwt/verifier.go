@@ -0,0 +1,183 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+type Verifier struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	audience string
+	issuer   string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+func NewVerifier(ops ...Option) *Verifier {
+	v := &Verifier{
+		leeway: 30 * time.Second,
+		now:    time.Now,
+	}
+
+	for _, op := range ops {
+		op(v)
+	}
+
+	return v
+}
+
+// Verify checks the token signature (HS256 or RS256) and its exp, nbf, aud
+// and iss claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformed
+	}
+
+	var h header
+
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch h.Alg {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return Claims{}, ErrAlgorithm
+		}
+
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return Claims{}, ErrSignature
+		}
+	case "RS256":
+		key, err := v.key(h.Kid)
+		if err != nil {
+			return Claims{}, err
+		}
+
+		digest := sha256.Sum256(signed)
+
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+		if err != nil {
+			return Claims{}, ErrSignature
+		}
+	default:
+		return Claims{}, ErrAlgorithm
+	}
+
+	var c Claims
+
+	err = decodeSegment(parts[1], &c)
+	if err != nil {
+		return Claims{}, ErrMalformed
+	}
+
+	err = v.validate(c)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	return c, nil
+}
+
+func (v *Verifier) validate(c Claims) error {
+	now := v.now()
+
+	if c.ExpiresAt.IsZero() || !now.Before(c.ExpiresAt.Add(v.leeway)) {
+		return ErrExpired
+	}
+
+	if !c.NotBefore.IsZero() && now.Add(v.leeway).Before(c.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return ErrIssuer
+	}
+
+	if v.audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.audience {
+				return nil
+			}
+		}
+
+		return ErrAudience
+	}
+
+	return nil
+}
+
+func (v *Verifier) key(kid string) (*rsa.PublicKey, error) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			return k, nil
+		}
+	}
+
+	k, ok := v.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return k, nil
+}
+
+func decodeSegment(seg string, dest any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// SignHS256 builds an HS256 token for the given claims.
+func SignHS256(claims map[string]any, secret []byte) (string, error) {
+	h, err := json.Marshal(header{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("[jwt.SignHS256] %w", err)
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("[jwt.SignHS256] %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// HasKeys reports whether the verifier can check any token.
+func (v *Verifier) HasKeys() bool {
+	return len(v.secret) > 0 || len(v.keys) > 0
+}
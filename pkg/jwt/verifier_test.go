@@ -0,0 +1,157 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecret = []byte("test-secret")
+
+func signRS256(t *testing.T, claims map[string]any, key *rsa.PrivateKey, kid string) string {
+	h, _ := json.Marshal(header{Alg: "RS256", Typ: "JWT", Kid: kid})
+	c, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyHS256(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		expected error
+	}{
+		{
+			"valid",
+			map[string]any{"sub": "alice", "aud": "goweb", "iss": "auth", "exp": now.Add(time.Hour).Unix()},
+			nil,
+		},
+		{
+			"expired",
+			map[string]any{"sub": "alice", "aud": "goweb", "iss": "auth", "exp": now.Add(-time.Hour).Unix()},
+			ErrExpired,
+		},
+		{
+			"missing exp",
+			map[string]any{"sub": "alice", "aud": "goweb", "iss": "auth"},
+			ErrExpired,
+		},
+		{
+			"not before",
+			map[string]any{"sub": "alice", "aud": "goweb", "iss": "auth", "exp": now.Add(2 * time.Hour).Unix(), "nbf": now.Add(time.Hour).Unix()},
+			ErrNotYetValid,
+		},
+		{
+			"audience",
+			map[string]any{"sub": "alice", "aud": []string{"other"}, "iss": "auth", "exp": now.Add(time.Hour).Unix()},
+			ErrAudience,
+		},
+		{
+			"issuer",
+			map[string]any{"sub": "alice", "aud": "goweb", "iss": "other", "exp": now.Add(time.Hour).Unix()},
+			ErrIssuer,
+		},
+	}
+
+	v := NewVerifier(HS256Secret(testSecret), Audience("goweb"), Issuer("auth"))
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := SignHS256(test.claims, testSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := v.Verify(token)
+
+			assert.ErrorIs(t, err, test.expected)
+			if test.expected == nil {
+				assert.Equal(t, "alice", c.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	claims := map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+
+	token, err := SignHS256(claims, []byte("other-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewVerifier(HS256Secret(testSecret)).Verify(token)
+	assert.ErrorIs(t, err, ErrSignature)
+
+	// hs256 is disabled without a secret
+	_, err = NewVerifier().Verify(token)
+	assert.ErrorIs(t, err, ErrAlgorithm)
+
+	_, err = NewVerifier(HS256Secret(testSecret)).Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks, _ := json.Marshal(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := LoadJWKS(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewVerifier(RS256Keys(keys))
+	claims := map[string]any{"sub": "bob", "exp": time.Now().Add(time.Hour).Unix()}
+
+	c, err := v.Verify(signRS256(t, claims, key, "k1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", c.Subject)
+
+	_, err = v.Verify(signRS256(t, claims, key, "k2"))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.Verify(signRS256(t, claims, other, "k1"))
+	assert.ErrorIs(t, err, ErrSignature)
+}
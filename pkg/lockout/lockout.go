@@ -0,0 +1,96 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	failures    int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// Tracker locks a key, e.g. a client ip, out after too many failures within
+// a time window.
+type Tracker struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	duration time.Duration
+	entries  map[string]*entry
+	now      func() time.Time
+}
+
+func New(max int, window time.Duration, duration time.Duration) *Tracker {
+	return &Tracker{
+		max:      max,
+		window:   window,
+		duration: duration,
+		entries:  make(map[string]*entry),
+		now:      time.Now,
+	}
+}
+
+// Locked returns the remaining lockout time of the key.
+func (t *Tracker) Locked(key string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return 0, false
+	}
+
+	remaining := e.lockedUntil.Sub(t.now())
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	return remaining, true
+}
+
+// Fail records a failure and reports whether it locked the key out.
+func (t *Tracker) Fail(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	e, ok := t.entries[key]
+	if !ok || now.Sub(e.first) > t.window {
+		e = &entry{first: now}
+		t.entries[key] = e
+	}
+
+	e.failures++
+
+	if e.failures >= t.max {
+		e.lockedUntil = now.Add(t.duration)
+		e.failures = 0
+		e.first = now
+		return true
+	}
+
+	return false
+}
+
+// Reset forgets the failures of the key.
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if e, ok := t.entries[key]; ok && !e.lockedUntil.After(t.now()) {
+		delete(t.entries, key)
+	}
+}
+
+// sweep drops entries that are neither locked nor within the window.
+func (t *Tracker) sweep(now time.Time) {
+	for k, e := range t.entries {
+		if now.Sub(e.first) > t.window && !e.lockedUntil.After(now) {
+			delete(t.entries, k)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a settable time source for the tracker.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestTracker(max int, window time.Duration, duration time.Duration) (*Tracker, *clock) {
+	c := &clock{t: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	t := New(max, window, duration)
+	t.now = c.now
+
+	return t, c
+}
+
+func TestThreshold(t *testing.T) {
+	tr, _ := newTestTracker(3, time.Minute, time.Hour)
+
+	assert.False(t, tr.Fail("ip"))
+	assert.False(t, tr.Fail("ip"))
+
+	_, locked := tr.Locked("ip")
+	assert.False(t, locked)
+
+	assert.True(t, tr.Fail("ip"))
+
+	remaining, locked := tr.Locked("ip")
+	assert.True(t, locked)
+	assert.Equal(t, time.Hour, remaining)
+
+	// keys are tracked apart
+	_, locked = tr.Locked("other")
+	assert.False(t, locked)
+}
+
+func TestWindowReset(t *testing.T) {
+	tr, c := newTestTracker(3, time.Minute, time.Hour)
+
+	assert.False(t, tr.Fail("ip"))
+	assert.False(t, tr.Fail("ip"))
+
+	// failures out of the window start over
+	c.t = c.t.Add(time.Minute + time.Second)
+
+	assert.False(t, tr.Fail("ip"))
+	assert.False(t, tr.Fail("ip"))
+	assert.True(t, tr.Fail("ip"))
+}
+
+func TestReset(t *testing.T) {
+	tr, _ := newTestTracker(3, time.Minute, time.Hour)
+
+	assert.False(t, tr.Fail("ip"))
+	assert.False(t, tr.Fail("ip"))
+
+	// a success forgets the failures
+	tr.Reset("ip")
+
+	assert.False(t, tr.Fail("ip"))
+	assert.False(t, tr.Fail("ip"))
+	assert.True(t, tr.Fail("ip"))
+
+	// but not a lockout
+	tr.Reset("ip")
+
+	_, locked := tr.Locked("ip")
+	assert.True(t, locked)
+}
+
+func TestUnlockAfterTimeout(t *testing.T) {
+	tr, c := newTestTracker(2, time.Minute, time.Hour)
+
+	assert.False(t, tr.Fail("ip"))
+	assert.True(t, tr.Fail("ip"))
+
+	c.t = c.t.Add(30 * time.Minute)
+
+	remaining, locked := tr.Locked("ip")
+	assert.True(t, locked)
+	assert.Equal(t, 30*time.Minute, remaining)
+
+	c.t = c.t.Add(30 * time.Minute)
+
+	_, locked = tr.Locked("ip")
+	assert.False(t, locked)
+
+	// the count starts over once unlocked
+	assert.False(t, tr.Fail("ip"))
+	assert.True(t, tr.Fail("ip"))
+}
@@ -0,0 +1,332 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrDrift = errors.New("routes and specs drifted")
+
+// Route is a registered route, with a gin style path such as
+// "/products/:id".
+type Route struct {
+	Method string
+	Path   string
+}
+
+func (r Route) key() string {
+	return r.Method + " " + r.Path
+}
+
+// Spec describes the operation of a route. Body and Response are values of
+// the types sent and received, or *Schema. Path parameters missing from
+// Params are documented as required strings.
+type Spec struct {
+	OperationId string
+	Summary     string
+	Tags        []string
+	Deprecated  bool
+
+	// Permission secures the operation with every security scheme, unless
+	// Anonymous also grants it to requests without credentials.
+	Permission string
+	Anonymous  bool
+
+	Params   []Parameter
+	Body     any
+	BodyType string
+	// PartialBody makes every property of the body optional, e.g. for
+	// PATCH requests.
+	PartialBody bool
+
+	// Status defaults to 200. A nil Response documents no content.
+	Status       int
+	Response     any
+	ResponseType string
+	// Negotiated are other content types of the success response, served
+	// by the Accept header and documented as strings.
+	Negotiated []string
+	// Raw responses are not wrapped in the envelope.
+	Raw bool
+
+	Errors []int
+}
+
+type Generator struct {
+	info      Info
+	envelope  string
+	errorBody any
+	security  map[string]SecurityScheme
+	schemas   *Schemas
+}
+
+type Option func(*Generator)
+
+// Envelope wraps json responses in an object holding the data under name.
+func Envelope(name string) Option {
+	return func(g *Generator) {
+		g.envelope = name
+	}
+}
+
+// ErrorBody documents error responses with the type of v.
+func ErrorBody(v any) Option {
+	return func(g *Generator) {
+		g.errorBody = v
+	}
+}
+
+// Security adds a security scheme used by operations with a permission.
+func Security(name string, s SecurityScheme) Option {
+	return func(g *Generator) {
+		g.security[name] = s
+	}
+}
+
+func New(info Info, ops ...Option) *Generator {
+	g := &Generator{
+		info:     info,
+		security: make(map[string]SecurityScheme),
+		schemas:  NewSchemas(),
+	}
+
+	for _, op := range ops {
+		op(g)
+	}
+
+	return g
+}
+
+// Schemas returns the schemas of the generated documents.
+func (g *Generator) Schemas() *Schemas {
+	return g.schemas
+}
+
+// Generate documents the routes with their specs, keyed by "METHOD /path".
+// Routes without spec are documented with a bare operation.
+func (g *Generator) Generate(routes []Route, specs map[string]Spec) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    g.info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	for _, r := range routes {
+		p := Path(r.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(PathItem)
+		}
+
+		doc.Paths[p][strings.ToLower(r.Method)] = g.operation(r, specs[r.key()])
+	}
+
+	doc.Components = Components{
+		Schemas:         g.schemas.Components(),
+		SecuritySchemes: g.security,
+	}
+
+	return doc
+}
+
+func (g *Generator) operation(r Route, s Spec) *Operation {
+	op := &Operation{
+		OperationId: s.OperationId,
+		Summary:     s.Summary,
+		Tags:        s.Tags,
+		Responses:   make(map[string]Response),
+		Permission:  s.Permission,
+		Deprecated:  s.Deprecated,
+	}
+
+	if op.OperationId == "" {
+		op.OperationId = operationId(r)
+	}
+
+	op.Parameters = append(op.Parameters, s.Params...)
+
+	for _, name := range pathParams(r.Path) {
+		if !hasParam(s.Params, name) {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	if s.Body != nil {
+		bodyType := s.BodyType
+		if bodyType == "" {
+			bodyType = "application/json"
+		}
+
+		sch := g.schemas.For(s.Body)
+		if s.PartialBody {
+			partial := *g.schemas.Resolve(sch)
+			partial.Required = nil
+			sch = &partial
+		}
+
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{bodyType: {Schema: sch}},
+		}
+	}
+
+	status := s.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	res := Response{Description: http.StatusText(status)}
+	if s.Response != nil {
+		contentType, mt := g.responseContent(s)
+		res.Content = map[string]MediaType{contentType: mt}
+
+		for _, t := range s.Negotiated {
+			res.Content[t] = MediaType{Schema: &Schema{Type: "string"}}
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = res
+
+	codes := s.Errors
+	if op.RequestBody != nil {
+		// json bodies are limited by the request validation
+		if _, ok := op.RequestBody.Content["application/json"]; ok {
+			codes = append(codes[:len(codes):len(codes)], http.StatusRequestEntityTooLarge)
+		}
+	}
+
+	for _, code := range codes {
+		res := Response{Description: http.StatusText(code)}
+		if g.errorBody != nil {
+			res.Content = map[string]MediaType{
+				"application/json": {Schema: g.schemas.For(g.errorBody)},
+			}
+		}
+		op.Responses[strconv.Itoa(code)] = res
+	}
+
+	if s.Permission != "" && len(g.security) > 0 {
+		names := make([]string, 0, len(g.security))
+		for name := range g.security {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			op.Security = append(op.Security, map[string][]string{name: {}})
+		}
+
+		if s.Anonymous {
+			op.Security = append(op.Security, map[string][]string{})
+		}
+	}
+
+	return op
+}
+
+// responseContent returns the content type and schema of the success
+// response, wrapped in the envelope for json.
+func (g *Generator) responseContent(s Spec) (string, MediaType) {
+	contentType := s.ResponseType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	sch := g.schemas.For(s.Response)
+
+	if contentType == "application/json" && !s.Raw && g.envelope != "" {
+		sch = &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{g.envelope: sch},
+			Required:   []string{g.envelope},
+		}
+	}
+
+	return contentType, MediaType{Schema: sch}
+}
+
+// Drift returns ErrDrift listing the routes without spec and the specs
+// without route.
+func Drift(routes []Route, specs map[string]Spec) error {
+	var problems []string
+
+	registered := make(map[string]bool)
+	for _, r := range routes {
+		registered[r.key()] = true
+
+		if _, ok := specs[r.key()]; !ok {
+			problems = append(problems, "undocumented route "+r.key())
+		}
+	}
+
+	for key := range specs {
+		if !registered[key] {
+			problems = append(problems, "unregistered spec "+key)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.Strings(problems)
+
+	return fmt.Errorf("%w: %s", ErrDrift, strings.Join(problems, ", "))
+}
+
+// Path converts a gin path to an OpenAPI one, e.g. "/products/:id" to
+// "/products/{id}".
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func pathParams(ginPath string) []string {
+	var names []string
+
+	for _, seg := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			names = append(names, seg[1:])
+		}
+	}
+
+	return names
+}
+
+func hasParam(params []Parameter, name string) bool {
+	for _, p := range params {
+		if p.In == "path" && p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// operationId derives an id from the route, e.g. "getProductsId" for
+// "GET /products/:id".
+func operationId(r Route) string {
+	id := strings.ToLower(r.Method)
+
+	for _, seg := range strings.Split(r.Path, "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		for _, part := range strings.Split(seg, "_") {
+			id += exported(part)
+		}
+	}
+
+	return id
+}
@@ -0,0 +1,349 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawType     = reflect.TypeOf(json.RawMessage{})
+	schemerType = reflect.TypeOf((*Schemer)(nil)).Elem()
+)
+
+// Schemer is implemented by types whose schema can't be told from their go
+// type, e.g. dates encoded as strings.
+type Schemer interface {
+	OpenAPISchema() *Schema
+}
+
+// Schemas builds schemas from go types. Named structs are registered as
+// components and referenced. Constraints are read from the `binding` and
+// `validate` tags used by gin and the validator.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// For returns the schema of the type of v, or v itself when it is a
+// *Schema.
+func (s *Schemas) For(v any) *Schema {
+	if sch, ok := v.(*Schema); ok {
+		return sch
+	}
+
+	return s.of(reflect.TypeOf(v))
+}
+
+// Components returns the registered struct schemas by name.
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// Resolve follows a component reference.
+func (s *Schemas) Resolve(sch *Schema) *Schema {
+	if sch == nil || sch.Ref == "" {
+		return sch
+	}
+
+	return s.components[strings.TrimPrefix(sch.Ref, refPrefix)]
+}
+
+const refPrefix = "#/components/schemas/"
+
+func (s *Schemas) of(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	// raw json is any value
+	if t == rawType {
+		return &Schema{}
+	}
+
+	if t.Kind() != reflect.Pointer && t.Implements(schemerType) {
+		sch := *reflect.Zero(t).Interface().(Schemer).OpenAPISchema()
+		return &sch
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		sch := s.of(t.Elem())
+		if sch.Ref == "" {
+			sch.Nullable = true
+		}
+		return sch
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// nil slices are encoded as null
+		return &Schema{Type: "array", Items: s.of(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object", AdditionalProperties: true, Nullable: true}
+		}
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		return s.structRef(t)
+	}
+
+	// interfaces and anything else accept any value
+	return &Schema{}
+}
+
+// structRef registers named structs as components, and inlines anonymous
+// ones.
+func (s *Schemas) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return s.structSchema(t)
+	}
+
+	if name, ok := s.names[t]; ok {
+		return &Schema{Ref: refPrefix + name}
+	}
+
+	name := s.name(t)
+	s.names[t] = name
+
+	// registered before the fields, so that recursive types terminate
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.structSchema(t)
+
+	return &Schema{Ref: refPrefix + name}
+}
+
+// name returns an exported, unique component name for the type.
+func (s *Schemas) name(t reflect.Type) string {
+	name := exported(t.Name())
+
+	if _, taken := s.components[name]; taken {
+		name = exported(path.Base(t.PkgPath())) + name
+	}
+
+	return name
+}
+
+func (s *Schemas) structSchema(t reflect.Type) *Schema {
+	sch := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				embedded := s.structSchema(ft)
+				for k, v := range embedded.Properties {
+					sch.Properties[k] = v
+				}
+				sch.Required = append(sch.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fs := s.of(f.Type)
+
+		var rules []string
+		for _, tag := range []string{"binding", "validate"} {
+			if v := f.Tag.Get(tag); v != "" {
+				rules = append(rules, strings.Split(v, ",")...)
+			}
+		}
+
+		if applyRules(fs, rules) {
+			sch.Required = append(sch.Required, name)
+		}
+
+		sch.Properties[name] = fs
+	}
+
+	return sch
+}
+
+// applyRules sets the constraints of the validator rules on the schema and
+// reports whether the value is required.
+func applyRules(sch *Schema, rules []string) bool {
+	var (
+		required  bool
+		alphanum  bool
+		uppercase bool
+		lowercase bool
+	)
+
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+
+		if name == "dive" {
+			if sch.Items != nil && sch.Items.Ref == "" {
+				applyRules(sch.Items, rules[i+1:])
+			}
+			break
+		}
+
+		// constraints on references would change the shared component
+		if sch.Ref != "" {
+			if name == "required" {
+				required = true
+			}
+			continue
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "gte", "min":
+			setMin(sch, param, false)
+		case "gt":
+			setMin(sch, param, true)
+		case "lte", "max":
+			setMax(sch, param, false)
+		case "lt":
+			setMax(sch, param, true)
+		case "len":
+			setMin(sch, param, false)
+			setMax(sch, param, false)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				sch.Enum = append(sch.Enum, enumValue(sch, v))
+			}
+		case "email":
+			sch.Format = "email"
+		case "url", "uri":
+			sch.Format = "uri"
+		case "datetime":
+			if param == "2006-01-02" {
+				sch.Format = "date"
+			}
+		case "numeric":
+			sch.Pattern = `^[-+]?[0-9]+(\.[0-9]+)?$`
+		case "alphanum":
+			alphanum = true
+		case "uppercase":
+			uppercase = true
+		case "lowercase":
+			lowercase = true
+		}
+	}
+
+	switch {
+	case alphanum && uppercase:
+		sch.Pattern = "^[A-Z0-9]*$"
+	case alphanum && lowercase:
+		sch.Pattern = "^[a-z0-9]*$"
+	case alphanum:
+		sch.Pattern = "^[a-zA-Z0-9]*$"
+	case uppercase:
+		sch.Pattern = "^[^a-z]*$"
+	case lowercase:
+		sch.Pattern = "^[^A-Z]*$"
+	}
+
+	return required
+}
+
+func setMin(sch *Schema, param string, exclusive bool) {
+	v, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch sch.Type {
+	case "string":
+		n := int(v)
+		sch.MinLength = &n
+	case "array":
+		n := int(v)
+		sch.MinItems = &n
+	case "integer", "number":
+		sch.Minimum = &v
+		sch.ExclusiveMinimum = exclusive
+	}
+}
+
+func setMax(sch *Schema, param string, exclusive bool) {
+	v, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch sch.Type {
+	case "string":
+		n := int(v)
+		sch.MaxLength = &n
+	case "array":
+		n := int(v)
+		sch.MaxItems = &n
+	case "integer", "number":
+		sch.Maximum = &v
+		sch.ExclusiveMaximum = exclusive
+	}
+}
+
+func enumValue(sch *Schema, v string) any {
+	if sch.Type == "integer" || sch.Type == "number" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+
+	return v
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+
+	return string(r)
+}
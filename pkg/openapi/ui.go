@@ -0,0 +1,15 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html"
+)
+
+//go:embed ui/index.html
+var uiPage []byte
+
+// UI returns the docs page, rendering the document served at specURL.
+func UI(specURL string) []byte {
+	return bytes.Replace(uiPage, []byte("SPEC_URL"), []byte(html.EscapeString(specURL)), 1)
+}
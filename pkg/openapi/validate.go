@@ -0,0 +1,396 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ValidationError describes a value that does not match its schema. Field
+// is a dotted path such as "body.lines[0].quantity" or "query.priceGt".
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// patterns caches compiled schema patterns.
+var patterns sync.Map
+
+// Operation returns the operation of a gin route, if documented.
+func (d *Document) Operation(method string, ginPath string) *Operation {
+	return d.Paths[Path(ginPath)][strings.ToLower(method)]
+}
+
+// RuleMaxBytes is the rule of a json body larger than the document
+// MaxBodySize.
+const RuleMaxBytes = "max_bytes"
+
+// ValidateRequest checks the parameters and json body of a request to the
+// gin route. Path parameter values are given by name. The body is restored
+// for the handlers.
+func (d *Document) ValidateRequest(r *http.Request, ginPath string, params map[string]string) []ValidationError {
+	op := d.Operation(r.Method, ginPath)
+	if op == nil {
+		return nil
+	}
+
+	var errs []ValidationError
+
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var (
+			value   string
+			present bool
+		)
+
+		switch p.In {
+		case "path":
+			value, present = params[p.Name]
+		case "query":
+			// deep objects are spread over several keys
+			if p.Style == "deepObject" {
+				continue
+			}
+			present = query.Has(p.Name)
+			value = query.Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		default:
+			continue
+		}
+
+		field := p.In + "." + p.Name
+
+		if !present {
+			if p.Required {
+				errs = append(errs, ValidationError{field, "required", "is required"})
+			}
+			continue
+		}
+
+		errs = append(errs, d.validateParam(p.Schema, value, field)...)
+	}
+
+	if op.RequestBody == nil {
+		return errs
+	}
+
+	mt, ok := op.RequestBody.Content["application/json"]
+	if !ok || !isJSON(r.Header.Get("Content-Type"), true) {
+		return errs
+	}
+
+	limit := d.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return append(errs, ValidationError{"body", RuleMaxBytes, fmt.Sprintf("is larger than %d bytes", limit)})
+		}
+
+		return append(errs, ValidationError{"body", "readable", err.Error()})
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			errs = append(errs, ValidationError{"body", "required", "is required"})
+		}
+		return errs
+	}
+
+	var v any
+	err = json.Unmarshal(body, &v)
+	if err != nil {
+		return append(errs, ValidationError{"body", "json", "is not valid json"})
+	}
+
+	return append(errs, d.Validate(mt.Schema, v, "body")...)
+}
+
+// ValidateResponse checks a response of the gin route against the
+// documented responses.
+func (d *Document) ValidateResponse(method string, ginPath string, status int, contentType string, body []byte) []ValidationError {
+	op := d.Operation(method, ginPath)
+	if op == nil {
+		return nil
+	}
+
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return []ValidationError{{"response", "status", fmt.Sprintf("status %d is not documented", status)}}
+	}
+
+	if len(res.Content) == 0 {
+		if len(body) > 0 {
+			return []ValidationError{{"response", "empty", "has a body but none is documented"}}
+		}
+		return nil
+	}
+
+	mt, ok := res.Content["application/json"]
+	if !ok || !isJSON(contentType, false) {
+		return nil
+	}
+
+	var v any
+	err := json.Unmarshal(body, &v)
+	if err != nil {
+		return []ValidationError{{"response", "json", "is not valid json"}}
+	}
+
+	return d.Validate(mt.Schema, v, "response")
+}
+
+// Validate checks a decoded json value against the schema.
+func (d *Document) Validate(sch *Schema, v any, field string) []ValidationError {
+	if sch == nil {
+		return nil
+	}
+
+	if sch.Ref != "" {
+		return d.Validate(d.Components.Schemas[strings.TrimPrefix(sch.Ref, refPrefix)], v, field)
+	}
+
+	if v == nil {
+		if sch.Nullable || sch.Type == "" {
+			return nil
+		}
+		return []ValidationError{{field, "type", "must be " + sch.Type + ", not null"}}
+	}
+
+	switch sch.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return []ValidationError{{field, "type", "must be an object"}}
+		}
+		return d.validateObject(sch, obj, field)
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return []ValidationError{{field, "type", "must be an array"}}
+		}
+		return d.validateArray(sch, arr, field)
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []ValidationError{{field, "type", "must be a string"}}
+		}
+		return validateString(sch, s, field)
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return []ValidationError{{field, "type", "must be a number"}}
+		}
+		if sch.Type == "integer" && n != math.Trunc(n) {
+			return []ValidationError{{field, "type", "must be an integer"}}
+		}
+		return validateNumber(sch, n, field)
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []ValidationError{{field, "type", "must be a boolean"}}
+		}
+	}
+
+	return validateEnum(sch, v, field)
+}
+
+func (d *Document) validateObject(sch *Schema, obj map[string]any, field string) []ValidationError {
+	var errs []ValidationError
+
+	for _, name := range sch.Required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, ValidationError{field + "." + name, "required", "is required"})
+		}
+	}
+
+	for name, value := range obj {
+		if ps, ok := sch.Properties[name]; ok {
+			errs = append(errs, d.Validate(ps, value, field+"."+name)...)
+			continue
+		}
+
+		switch ap := sch.AdditionalProperties.(type) {
+		case *Schema:
+			errs = append(errs, d.Validate(ap, value, field+"."+name)...)
+		case bool:
+			if !ap && sch.Properties != nil {
+				errs = append(errs, ValidationError{field + "." + name, "unknown", "is not allowed"})
+			}
+		}
+	}
+
+	return errs
+}
+
+func (d *Document) validateArray(sch *Schema, arr []any, field string) []ValidationError {
+	var errs []ValidationError
+
+	if sch.MinItems != nil && len(arr) < *sch.MinItems {
+		errs = append(errs, ValidationError{field, "minItems", fmt.Sprintf("must have at least %d items", *sch.MinItems)})
+	}
+
+	if sch.MaxItems != nil && len(arr) > *sch.MaxItems {
+		errs = append(errs, ValidationError{field, "maxItems", fmt.Sprintf("must have at most %d items", *sch.MaxItems)})
+	}
+
+	for i, item := range arr {
+		errs = append(errs, d.Validate(sch.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+	}
+
+	return errs
+}
+
+func validateString(sch *Schema, s string, field string) []ValidationError {
+	var errs []ValidationError
+
+	length := len([]rune(s))
+
+	if sch.MinLength != nil && length < *sch.MinLength {
+		errs = append(errs, ValidationError{field, "minLength", fmt.Sprintf("must have at least %d characters", *sch.MinLength)})
+	}
+
+	if sch.MaxLength != nil && length > *sch.MaxLength {
+		errs = append(errs, ValidationError{field, "maxLength", fmt.Sprintf("must have at most %d characters", *sch.MaxLength)})
+	}
+
+	if sch.Pattern != "" {
+		re, ok := patterns.Load(sch.Pattern)
+		if !ok {
+			compiled, err := regexp.Compile(sch.Pattern)
+			if err == nil {
+				re, _ = patterns.LoadOrStore(sch.Pattern, compiled)
+			}
+		}
+
+		if re != nil && !re.(*regexp.Regexp).MatchString(s) {
+			errs = append(errs, ValidationError{field, "pattern", "must match " + sch.Pattern})
+		}
+	}
+
+	switch sch.Format {
+	case "email":
+		if _, err := mail.ParseAddress(s); err != nil {
+			errs = append(errs, ValidationError{field, "format", "must be an email address"})
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			errs = append(errs, ValidationError{field, "format", "must be a date, e.g. 2006-01-02"})
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			errs = append(errs, ValidationError{field, "format", "must be an RFC 3339 date-time"})
+		}
+	}
+
+	return append(errs, validateEnum(sch, s, field)...)
+}
+
+func validateNumber(sch *Schema, n float64, field string) []ValidationError {
+	var errs []ValidationError
+
+	if sch.Minimum != nil {
+		if n < *sch.Minimum || (sch.ExclusiveMinimum && n == *sch.Minimum) {
+			rule := "minimum"
+			msg := fmt.Sprintf("must be at least %v", *sch.Minimum)
+			if sch.ExclusiveMinimum {
+				msg = fmt.Sprintf("must be greater than %v", *sch.Minimum)
+			}
+			errs = append(errs, ValidationError{field, rule, msg})
+		}
+	}
+
+	if sch.Maximum != nil {
+		if n > *sch.Maximum || (sch.ExclusiveMaximum && n == *sch.Maximum) {
+			rule := "maximum"
+			msg := fmt.Sprintf("must be at most %v", *sch.Maximum)
+			if sch.ExclusiveMaximum {
+				msg = fmt.Sprintf("must be less than %v", *sch.Maximum)
+			}
+			errs = append(errs, ValidationError{field, rule, msg})
+		}
+	}
+
+	return append(errs, validateEnum(sch, n, field)...)
+}
+
+func validateEnum(sch *Schema, v any, field string) []ValidationError {
+	if len(sch.Enum) == 0 {
+		return nil
+	}
+
+	for _, e := range sch.Enum {
+		if e == v {
+			return nil
+		}
+	}
+
+	values := make([]string, 0, len(sch.Enum))
+	for _, e := range sch.Enum {
+		values = append(values, fmt.Sprint(e))
+	}
+
+	return []ValidationError{{field, "enum", "must be one of " + strings.Join(values, ", ")}}
+}
+
+// validateParam converts a parameter value to the schema type before
+// checking it.
+func (d *Document) validateParam(sch *Schema, value string, field string) []ValidationError {
+	if sch == nil {
+		return nil
+	}
+
+	switch sch.Type {
+	case "integer", "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return []ValidationError{{field, "type", "must be a number"}}
+		}
+		return d.Validate(sch, n, field)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return []ValidationError{{field, "type", "must be a boolean"}}
+		}
+		return d.Validate(sch, b, field)
+	}
+
+	return d.Validate(sch, value, field)
+}
+
+// isJSON reports whether the content type is json. Requests without one
+// are decoded as json by the handlers.
+func isJSON(contentType string, emptyIsJSON bool) bool {
+	if contentType == "" {
+		return emptyIsJSON
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json"
+}
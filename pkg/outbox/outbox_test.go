@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sinkFunc func(Event) error
+
+func (f sinkFunc) Publish(e Event) error {
+	return f(e)
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	s, err := NewStore(path)
+	require.NoError(t, err)
+
+	first, err := s.Stage("product.updated", map[string]int{"id": 1})
+	require.NoError(t, err)
+	second, err := s.Stage("product.deleted", map[string]int{"id": 2})
+	require.NoError(t, err)
+	third, err := s.Stage("product.created", map[string]int{"id": 3})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Commit(first, map[string]int{"id": 1, "quantity": 5}))
+	require.NoError(t, s.Abort(second))
+	assert.ErrorIs(t, s.Abort(second), ErrNotFound)
+
+	// staged and committed events survive a restart, ids keep growing
+	s, err = NewStore(path)
+	require.NoError(t, err)
+
+	staged := s.Staged()
+	require.Len(t, staged, 1)
+	assert.Equal(t, third, staged[0].Id)
+
+	pending := s.pending("bus")
+	require.Len(t, pending, 1)
+	assert.Equal(t, first, pending[0].Id)
+	assert.JSONEq(t, `{"id":1,"quantity":5}`, string(pending[0].Data))
+
+	// keys are kept, and differ between events
+	assert.Len(t, pending[0].Key, 32)
+	assert.NotEqual(t, pending[0].Key, staged[0].Key)
+
+	next, err := s.Stage("product.created", nil)
+	require.NoError(t, err)
+	assert.Equal(t, third+1, next)
+}
+
+func TestStoreBatch(t *testing.T) {
+	s, err := NewStore("")
+	require.NoError(t, err)
+
+	ids, err := s.StageAll([]Draft{
+		{Type: "product.created", Data: 1},
+		{Type: "product.created", Data: 2},
+		{Type: "product.deleted", Data: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, ids)
+
+	require.NoError(t, s.CommitAll(map[uint64]any{1: "one", 3: "three"}))
+	require.NoError(t, s.AbortAll([]uint64{2}))
+	assert.ErrorIs(t, s.AbortAll([]uint64{2}), ErrNotFound)
+	assert.Empty(t, s.Staged())
+
+	pending := s.pending("bus")
+	require.Len(t, pending, 2)
+	assert.Equal(t, uint64(1), pending[0].Id)
+	assert.JSONEq(t, `"three"`, string(pending[1].Data))
+}
+
+func TestRelay(t *testing.T) {
+	s, err := NewStore("")
+	require.NoError(t, err)
+
+	var got []uint64
+	fail := true
+
+	r := NewRelay(s,
+		WithSink("ok", sinkFunc(func(e Event) error {
+			got = append(got, e.Id)
+			return nil
+		})),
+		WithSink("flaky", sinkFunc(func(e Event) error {
+			if fail {
+				return errors.New("unavailable")
+			}
+			return nil
+		})),
+	)
+
+	for i := 0; i < 2; i++ {
+		id, err := s.Stage("product.updated", i)
+		require.NoError(t, err)
+		require.NoError(t, s.Commit(id, i))
+	}
+
+	r.Relay()
+	assert.Equal(t, []uint64{1, 2}, got)
+	assert.Len(t, s.pending("flaky"), 2)
+
+	// the working sink does not get the events again
+	fail = false
+	r.Relay()
+	assert.Equal(t, []uint64{1, 2}, got)
+	assert.Empty(t, s.pending("flaky"))
+	assert.Empty(t, s.journal.Entries)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+
+	f, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, f.Publish(Event{Id: 1, Type: "product.created", Data: []byte(`{}`)}))
+	require.NoError(t, f.Publish(Event{Id: 2, Type: "product.updated", Data: []byte(`{}`)}))
+
+	// ids already in the file are skipped after a restart
+	f, err = NewFileSink(path)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), f.lastId)
+	require.NoError(t, f.Publish(Event{Id: 2, Type: "product.updated", Data: []byte(`{}`)}))
+	require.NoError(t, f.Publish(Event{Id: 3, Type: "product.deleted", Data: []byte(`{}`)}))
+	assert.Equal(t, uint64(3), f.lastId)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(b, []byte("\n")))
+}
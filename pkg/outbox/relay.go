@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultRetryInterval = 5 * time.Second
+
+// Sink receives the relayed events. An event is relayed to each sink once
+// it succeeds, and sinks that outlive a restart skip the ids already
+// received, so that each event reaches them exactly once.
+type Sink interface {
+	Publish(Event) error
+}
+
+// Relay publishes the committed events of a store to its sinks, in order.
+// A failing sink is retried without holding back the others.
+type Relay struct {
+	store    *Store
+	names    []string
+	sinks    map[string]Sink
+	interval time.Duration
+	mu       sync.Mutex
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+type Option func(*Relay)
+
+// WithSink relays the events to s. The name tracks the events relayed to
+// it, and must not change between restarts.
+func WithSink(name string, s Sink) Option {
+	return func(r *Relay) {
+		if _, ok := r.sinks[name]; !ok {
+			r.names = append(r.names, name)
+		}
+		r.sinks[name] = s
+	}
+}
+
+// RetryInterval retries failing sinks every d.
+func RetryInterval(d time.Duration) Option {
+	return func(r *Relay) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+func NewRelay(s *Store, ops ...Option) *Relay {
+	r := &Relay{
+		store:    s,
+		sinks:    make(map[string]Sink),
+		interval: defaultRetryInterval,
+		done:     make(chan struct{}),
+	}
+
+	for _, op := range ops {
+		op(r)
+	}
+
+	return r
+}
+
+// Start relays the events as they are committed, and retries the failed
+// ones periodically.
+func (r *Relay) Start() {
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		// left over from the last run
+		r.Relay()
+
+		for {
+			select {
+			case <-r.store.Committed():
+				r.Relay()
+			case <-ticker.C:
+				r.Relay()
+			case <-r.done:
+				return
+			}
+		}
+	}()
+}
+
+func (r *Relay) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+// Relay publishes the pending events of each sink. A sink stops at its
+// first failure, so that it gets the events in order.
+func (r *Relay) Relay() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.names {
+		for _, e := range r.store.pending(name) {
+			err := r.sinks[name].Publish(e)
+			if err != nil {
+				log.Println(fmt.Errorf("[outbox.Relay] sink %s, event %d: %w", name, e.Id, err))
+				break
+			}
+
+			err = r.store.delivered(e.Id, name, r.names)
+			if err != nil {
+				log.Println(fmt.Errorf("[outbox.Relay] sink %s, event %d: %w", name, e.Id, err))
+				break
+			}
+		}
+	}
+}
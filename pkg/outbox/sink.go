@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"gituhb.com/juajosserand/goweb/pkg/events"
+)
+
+type logSink struct{}
+
+// NewLogSink logs the events.
+func NewLogSink() Sink {
+	return logSink{}
+}
+
+func (logSink) Publish(e Event) error {
+	log.Printf("outbox: event %d %s %s\n", e.Id, e.Type, e.Data)
+	return nil
+}
+
+// FileSink appends the events to a file, one json object per line.
+type FileSink struct {
+	mu     sync.Mutex
+	path   string
+	lastId uint64
+}
+
+// NewFileSink appends to the file at path, skipping the events up to the
+// last one already in it.
+func NewFileSink(path string) (*FileSink, error) {
+	s := &FileSink{
+		path: path,
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[outbox.NewFileSink] %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.Id > s.lastId {
+			s.lastId = e.Id
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("[outbox.NewFileSink] %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *FileSink) Publish(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Id <= s.lastId {
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("[outbox.FileSink] %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("[outbox.FileSink] %w", err)
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		return fmt.Errorf("[outbox.FileSink] %w", err)
+	}
+
+	s.lastId = e.Id
+
+	return nil
+}
+
+// Decoder turns the data of an event back into its go type.
+type Decoder func(Event) (any, error)
+
+type busSink struct {
+	mu     sync.Mutex
+	bus    *events.Bus
+	decode Decoder
+	lastId uint64
+}
+
+// NewBusSink publishes the events on an in-process bus, with the data
+// decoded by decode.
+func NewBusSink(bus *events.Bus, decode Decoder) Sink {
+	return &busSink{
+		bus:    bus,
+		decode: decode,
+	}
+}
+
+func (s *busSink) Publish(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.Id <= s.lastId {
+		return nil
+	}
+
+	data, err := s.decode(e)
+	if err != nil {
+		return err
+	}
+
+	s.bus.Publish(e.Type, data)
+	s.lastId = e.Id
+
+	return nil
+}
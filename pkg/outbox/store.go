@@ -0,0 +1,347 @@
+package outbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gituhb.com/juajosserand/goweb/pkg/storage"
+)
+
+var ErrNotFound = errors.New("unable to find outbox event")
+
+type Status string
+
+const (
+	// Staged events belong to a write in progress, and are not relayed.
+	Staged Status = "staged"
+	// Committed events belong to a stored write, and are relayed.
+	Committed Status = "committed"
+)
+
+// Event ids order the events of a store, and restart at 1 with the
+// in-memory ones. Keys are random, so they identify the event across
+// restarts, e.g. for sinks storing what they received.
+type Event struct {
+	Id        uint64          `json:"id"`
+	Key       string          `json:"key"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type entry struct {
+	Event
+	Status Status `json:"status"`
+	// Delivered holds the sinks the event was relayed to.
+	Delivered []string `json:"delivered,omitempty"`
+}
+
+func (e *entry) delivered(sink string) bool {
+	for _, s := range e.Delivered {
+		if s == sink {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Store is a write-ahead log of events. Writers stage an event before a
+// write and commit or abort it after, so that only the events of stored
+// writes are relayed. Staged events left by a crash are resolved on start.
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	journal   journal
+	committed chan struct{}
+	now       func() time.Time
+}
+
+// journal is the content of the store file.
+type journal struct {
+	LastId  uint64  `json:"last_id"`
+	Entries []entry `json:"events"`
+}
+
+// NewStore opens the store at path, a json file created on the first
+// write. An empty path keeps the events in memory.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		committed: make(chan struct{}, 1),
+		now:       time.Now,
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+
+	err := storage.ReadFile(path, &s.journal)
+	if err != nil {
+		return s, err
+	}
+
+	// staged before events had keys
+	for i := range s.journal.Entries {
+		if s.journal.Entries[i].Key != "" {
+			continue
+		}
+
+		s.journal.Entries[i].Key, err = randomKey()
+		if err != nil {
+			return s, fmt.Errorf("[outbox.NewStore] %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Draft is an event to stage.
+type Draft struct {
+	Type string
+	Data any
+}
+
+// Stage adds the event of a write about to happen.
+func (s *Store) Stage(typ string, data any) (uint64, error) {
+	ids, err := s.StageAll([]Draft{{Type: typ, Data: data}})
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// StageAll adds the events of a batch of writes about to happen, with a
+// single write of the store.
+func (s *Store) StageAll(drafts []Draft) ([]uint64, error) {
+	raws := make([]json.RawMessage, len(drafts))
+	keys := make([]string, len(drafts))
+	for i, d := range drafts {
+		raw, err := json.Marshal(d.Data)
+		if err != nil {
+			return nil, fmt.Errorf("[outbox.Stage] %w", err)
+		}
+		raws[i] = raw
+
+		keys[i], err = randomKey()
+		if err != nil {
+			return nil, fmt.Errorf("[outbox.Stage] %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lastId, n := s.journal.LastId, len(s.journal.Entries)
+	ids := make([]uint64, len(drafts))
+
+	for i, d := range drafts {
+		s.journal.LastId++
+		s.journal.Entries = append(s.journal.Entries, entry{
+			Event: Event{
+				Id:        s.journal.LastId,
+				Key:       keys[i],
+				Type:      d.Type,
+				Data:      raws[i],
+				CreatedAt: s.now().UTC(),
+			},
+			Status: Staged,
+		})
+		ids[i] = s.journal.LastId
+	}
+
+	err := s.write()
+	if err != nil {
+		s.journal.LastId, s.journal.Entries = lastId, s.journal.Entries[:n]
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Commit marks the event of a stored write for relaying, replacing its data
+// with the stored state.
+func (s *Store) Commit(id uint64, data any) error {
+	return s.CommitAll(map[uint64]any{id: data})
+}
+
+// CommitAll commits the events of a batch of stored writes, given by id,
+// with a single write of the store.
+func (s *Store) CommitAll(data map[uint64]any) error {
+	raws := make(map[uint64]json.RawMessage, len(data))
+	for id, d := range data {
+		raw, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("[outbox.Commit] %w", err)
+		}
+		raws[id] = raw
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := make(map[int]entry, len(raws))
+	for id := range raws {
+		i, ok := s.find(id)
+		if !ok {
+			return ErrNotFound
+		}
+		prev[i] = s.journal.Entries[i]
+	}
+
+	for i, e := range prev {
+		s.journal.Entries[i].Data = raws[e.Id]
+		s.journal.Entries[i].Status = Committed
+	}
+
+	err := s.write()
+	if err != nil {
+		for i, e := range prev {
+			s.journal.Entries[i] = e
+		}
+		return err
+	}
+
+	select {
+	case s.committed <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Abort drops the event of a failed write.
+func (s *Store) Abort(id uint64) error {
+	return s.AbortAll([]uint64{id})
+}
+
+// AbortAll drops the events of a batch of failed writes, with a single
+// write of the store.
+func (s *Store) AbortAll(ids []uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	aborted := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := s.find(id); !ok {
+			return ErrNotFound
+		}
+		aborted[id] = true
+	}
+
+	entries := s.journal.Entries[:0:0]
+	for _, e := range s.journal.Entries {
+		if !aborted[e.Id] {
+			entries = append(entries, e)
+		}
+	}
+	s.journal.Entries = entries
+
+	return s.write()
+}
+
+// Staged returns the events of writes neither committed nor aborted.
+func (s *Store) Staged() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var es []Event
+	for _, e := range s.journal.Entries {
+		if e.Status == Staged {
+			es = append(es, e.Event)
+		}
+	}
+
+	return es
+}
+
+// Committed is signaled when events are committed.
+func (s *Store) Committed() <-chan struct{} {
+	return s.committed
+}
+
+// pending returns the committed events not yet relayed to the sink, in
+// order.
+func (s *Store) pending(sink string) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var es []Event
+	for _, e := range s.journal.Entries {
+		if e.Status == Committed && !e.delivered(sink) {
+			es = append(es, e.Event)
+		}
+	}
+
+	return es
+}
+
+// delivered records the event as relayed to the sink, and removes it once
+// relayed to every sink.
+func (s *Store) delivered(id uint64, sink string, sinks []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.find(id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	e := &s.journal.Entries[i]
+	if !e.delivered(sink) {
+		e.Delivered = append(e.Delivered, sink)
+	}
+
+	done := true
+	for _, name := range sinks {
+		if !e.delivered(name) {
+			done = false
+			break
+		}
+	}
+
+	if done {
+		s.journal.Entries = append(s.journal.Entries[:i], s.journal.Entries[i+1:]...)
+	}
+
+	return s.write()
+}
+
+func (s *Store) find(id uint64) (int, bool) {
+	i := sort.Search(len(s.journal.Entries), func(i int) bool {
+		return s.journal.Entries[i].Id >= id
+	})
+
+	return i, i < len(s.journal.Entries) && s.journal.Entries[i].Id == id
+}
+
+func randomKey() (string, error) {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (s *Store) write() error {
+	if s.path == "" {
+		return nil
+	}
+
+	return storage.WriteFile(s.path, &s.journal)
+}
@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrInvalidConfig = errors.New("invalid rate limit config")
+
+// Limit allows Requests per Period, with bursts up to Requests.
+type Limit struct {
+	Requests int           `json:"requests"`
+	Period   time.Duration `json:"-"`
+}
+
+func (l *Limit) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Requests int    `json:"requests"`
+		Period   string `json:"period"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	period, err := time.ParseDuration(raw.Period)
+	if err != nil {
+		return err
+	}
+
+	l.Requests = raw.Requests
+	l.Period = period
+
+	return nil
+}
+
+func (l Limit) valid() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+// Config holds the default limit, the limits of single routes keyed by
+// "METHOD /path", without version prefix, and the daily quotas of api keys
+// keyed by id. A zero daily quota means no quota.
+type Config struct {
+	Default    Limit            `json:"default"`
+	Routes     map[string]Limit `json:"routes"`
+	DailyQuota int              `json:"daily_quota"`
+	KeyQuotas  map[string]int   `json:"key_quotas"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err.Error())
+	}
+
+	var c Config
+
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, err.Error())
+	}
+
+	if !c.Default.valid() {
+		return nil, fmt.Errorf("%w: invalid default limit", ErrInvalidConfig)
+	}
+
+	for route, l := range c.Routes {
+		if !l.valid() {
+			return nil, fmt.Errorf("%w: invalid limit for %s", ErrInvalidConfig, route)
+		}
+	}
+
+	return &c, nil
+}
+
+// Route returns the limit of the route and true, or the default limit and
+// false when the route has none of its own.
+func (c *Config) Route(method string, path string) (Limit, bool) {
+	if l, ok := c.Routes[method+" "+path]; ok {
+		return l, true
+	}
+
+	return c.Default, false
+}
+
+// Quota returns the daily quota of the api key.
+func (c *Config) Quota(keyId int) int {
+	if q, ok := c.KeyQuotas[fmt.Sprint(keyId)]; ok {
+		return q
+	}
+
+	return c.DailyQuota
+}
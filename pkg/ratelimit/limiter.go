@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// fill adds the tokens earned since the last call.
+func (b *bucket) fill(now time.Time) {
+	rate := float64(b.limit.Requests) / b.limit.Period.Seconds()
+
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(b.limit.Requests) {
+		b.tokens = float64(b.limit.Requests)
+	}
+
+	b.last = now
+}
+
+// until returns the time needed to hold n tokens.
+func (b *bucket) until(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+
+	rate := float64(b.limit.Requests) / b.limit.Period.Seconds()
+
+	return time.Duration((n - b.tokens) / rate * float64(time.Second))
+}
+
+// Result describes the state of a bucket after a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter keeps a token bucket per key.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key, creating it full with
+// the given limit.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+
+	b.fill(now)
+
+	r := Result{Limit: limit.Requests}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = b.until(1)
+	}
+
+	r.Remaining = int(b.tokens)
+	r.Reset = b.until(float64(limit.Requests))
+
+	return r
+}
+
+// sweep drops the buckets that are full again.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for k, b := range l.buckets {
+		b.fill(now)
+		if b.tokens >= float64(b.limit.Requests) {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// clock is a settable time source for limiters and quotas.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestLimiter() (*Limiter, *clock) {
+	c := &clock{t: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	l := NewLimiter()
+	l.now = c.now
+
+	return l, c
+}
+
+func TestBurst(t *testing.T) {
+	l, _ := newTestLimiter()
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := 2; i >= 0; i-- {
+		r := l.Allow("k", limit)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Limit)
+		assert.Equal(t, i, r.Remaining)
+	}
+
+	r := l.Allow("k", limit)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	// one token every 20s
+	assert.Equal(t, 20*time.Second, r.RetryAfter)
+	assert.Equal(t, time.Minute, r.Reset)
+}
+
+func TestRefill(t *testing.T) {
+	l, c := newTestLimiter()
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		l.Allow("k", limit)
+	}
+
+	c.t = c.t.Add(19 * time.Second)
+
+	r := l.Allow("k", limit)
+	assert.False(t, r.Allowed)
+	assert.InDelta(t, time.Second, r.RetryAfter, float64(time.Millisecond))
+
+	c.t = c.t.Add(time.Second)
+
+	r = l.Allow("k", limit)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+
+	// refills stop at the burst size
+	c.t = c.t.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("k", limit).Allowed)
+	}
+	assert.False(t, l.Allow("k", limit).Allowed)
+}
+
+func TestBucketsPerKey(t *testing.T) {
+	l, _ := newTestLimiter()
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	assert.True(t, l.Allow("a", limit).Allowed)
+	assert.False(t, l.Allow("a", limit).Allowed)
+
+	assert.True(t, l.Allow("b", limit).Allowed)
+	assert.False(t, l.Allow("b", limit).Allowed)
+
+	// a changed limit starts a new bucket
+	assert.True(t, l.Allow("a", Limit{Requests: 2, Period: time.Minute}).Allowed)
+}
+
+func TestSweep(t *testing.T) {
+	l, c := newTestLimiter()
+	limit := Limit{Requests: 2, Period: time.Minute}
+
+	l.Allow("full", limit)
+	l.Allow("used", limit)
+	l.Allow("used", limit)
+
+	c.t = c.t.Add(sweepInterval)
+	l.Allow("new", limit)
+
+	// full buckets are dropped, they would start full anyway
+	assert.NotContains(t, l.buckets, "full")
+	assert.NotContains(t, l.buckets, "used")
+	assert.Contains(t, l.buckets, "new")
+}
+
+func TestQuota(t *testing.T) {
+	c := &clock{t: time.Date(2030, 1, 1, 23, 0, 0, 0, time.UTC)}
+
+	q := NewQuota()
+	q.now = c.now
+
+	for i := 1; i <= 2; i++ {
+		u, ok := q.Use("k", 2)
+		assert.True(t, ok)
+		assert.Equal(t, i, u.Used)
+	}
+
+	u, ok := q.Use("k", 2)
+	assert.False(t, ok)
+	assert.Equal(t, 2, u.Used)
+	assert.Equal(t, time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), u.Reset)
+
+	// other keys and keys without quota are not limited
+	_, ok = q.Use("other", 2)
+	assert.True(t, ok)
+	_, ok = q.Use("unlimited", 0)
+	assert.True(t, ok)
+
+	// counts start over the next day
+	c.t = c.t.Add(time.Hour)
+
+	u, ok = q.Use("k", 2)
+	assert.True(t, ok)
+	assert.Equal(t, 1, u.Used)
+	assert.Equal(t, 1, q.Usage("k", 2).Used)
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Usage is the quota usage of a key for the current day.
+type Usage struct {
+	Used  int       `json:"used"`
+	Limit int       `json:"limit"`
+	Reset time.Time `json:"reset"`
+}
+
+// Quota counts requests per key and UTC day. Counts are kept in memory and
+// start over on restart.
+type Quota struct {
+	mu   sync.Mutex
+	day  time.Time
+	used map[string]int
+	now  func() time.Time
+}
+
+func NewQuota() *Quota {
+	return &Quota{
+		used: make(map[string]int),
+		now:  time.Now,
+	}
+}
+
+// Use counts a request of the key unless the limit was reached. A zero
+// limit means no quota.
+func (q *Quota) Use(key string, limit int) (Usage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover()
+
+	u := q.usage(key, limit)
+	if limit > 0 && u.Used >= limit {
+		return u, false
+	}
+
+	q.used[key]++
+	u.Used++
+
+	return u, true
+}
+
+// Usage returns the usage of the key.
+func (q *Quota) Usage(key string, limit int) Usage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollover()
+
+	return q.usage(key, limit)
+}
+
+func (q *Quota) usage(key string, limit int) Usage {
+	return Usage{
+		Used:  q.used[key],
+		Limit: limit,
+		Reset: q.day.AddDate(0, 0, 1),
+	}
+}
+
+// rollover clears the counts when the day changed.
+func (q *Quota) rollover() {
+	day := q.now().UTC().Truncate(24 * time.Hour)
+	if !day.Equal(q.day) {
+		q.day = day
+		q.used = make(map[string]int)
+	}
+}
@@ -0,0 +1,67 @@
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Wildcard grants every permission.
+const Wildcard = "*"
+
+var ErrInvalidPolicy = errors.New("invalid rbac policy")
+
+// Policy maps role names to the permissions they grant.
+type Policy struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err.Error())
+	}
+
+	var p Policy
+
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err.Error())
+	}
+
+	if len(p.Roles) == 0 {
+		return nil, fmt.Errorf("%w: no roles defined", ErrInvalidPolicy)
+	}
+
+	return &p, nil
+}
+
+// Allowed reports whether any of the roles grants the permission.
+func (p *Policy) Allowed(roles []string, permission string) bool {
+	for _, r := range roles {
+		for _, granted := range p.Roles[r] {
+			if granted == permission || granted == Wildcard {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Granting lists the roles that grant the permission, sorted by name.
+func (p *Policy) Granting(permission string) []string {
+	var roles []string
+
+	for r := range p.Roles {
+		if p.Allowed([]string{r}, permission) {
+			roles = append(roles, r)
+		}
+	}
+
+	sort.Strings(roles)
+
+	return roles
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 
@@ -17,44 +19,108 @@ func readCSV(path string, dest *[]domain.Product) error {
 	defer f.Close()
 
 	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
 		return fmt.Errorf("[storage.readCSV] error: %w", err)
 	}
 
 	for _, record := range records {
-		id, err := strconv.Atoi(record[0])
+		p, err := parseCSVRecord(record)
 		if err != nil {
 			return fmt.Errorf("[storage.readCSV] error: %w", err)
 		}
 
-		quantity, err := strconv.Atoi(record[2])
+		*dest = append(*dest, p)
+	}
+
+	return nil
+}
+
+// parseCSVRecord decodes a product from the columns of a csv file. An empty
+// id is 0.
+func parseCSVRecord(record []string) (domain.Product, error) {
+	if len(record) < 7 {
+		return domain.Product{}, fmt.Errorf("expected at least 7 columns, got %d", len(record))
+	}
+
+	var id int
+	if record[0] != "" {
+		var err error
+		id, err = strconv.Atoi(record[0])
 		if err != nil {
-			return fmt.Errorf("[storage.readCSV] error: %w", err)
+			return domain.Product{}, err
 		}
+	}
 
-		isPublished, err := strconv.ParseBool(record[4])
+	quantity, err := strconv.Atoi(record[2])
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	isPublished, err := strconv.ParseBool(record[4])
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	price, err := strconv.ParseFloat(record[6], 64)
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	// optional columns
+	var variants []domain.Variant
+	if len(record) > 7 && record[7] != "" {
+		err = json.Unmarshal([]byte(record[7]), &variants)
 		if err != nil {
-			return fmt.Errorf("[storage.readCSV] error: %w", err)
+			return domain.Product{}, err
 		}
+	}
 
-		price, err := strconv.ParseFloat(record[6], 64)
+	var attributes map[string]any
+	if len(record) > 8 && record[8] != "" {
+		err = json.Unmarshal([]byte(record[8]), &attributes)
 		if err != nil {
-			return fmt.Errorf("[storage.readCSV] error: %w", err)
+			return domain.Product{}, err
 		}
+	}
 
-		*dest = append(*dest, domain.Product{
-			Id:          id,
-			Name:        record[1],
-			Quantity:    quantity,
-			CodeValue:   record[3],
-			IsPublished: isPublished,
-			Expiration:  record[5],
-			Price:       price,
-		})
+	var media []domain.Media
+	if len(record) > 9 && record[9] != "" {
+		err = json.Unmarshal([]byte(record[9]), &media)
+		if err != nil {
+			return domain.Product{}, err
+		}
 	}
 
-	return nil
+	var reorderPoint, reorderQuantity int
+	if len(record) > 11 {
+		reorderPoint, err = strconv.Atoi(record[10])
+		if err != nil {
+			return domain.Product{}, err
+		}
+
+		reorderQuantity, err = strconv.Atoi(record[11])
+		if err != nil {
+			return domain.Product{}, err
+		}
+	}
+
+	return domain.Product{
+		Id:          id,
+		Name:        record[1],
+		Quantity:    quantity,
+		CodeValue:   record[3],
+		IsPublished: isPublished,
+		Expiration:  record[5],
+		Price:       price,
+		Variants:    variants,
+		Attributes:  attributes,
+		Media:       media,
+
+		ReorderPoint:    reorderPoint,
+		ReorderQuantity: reorderQuantity,
+	}, nil
 }
 
 func writeCSV(path string, data *[]domain.Product) error {
@@ -71,15 +137,12 @@ func writeCSV(path string, data *[]domain.Product) error {
 
 	var records [][]string
 	for _, p := range *data {
-		records = append(records, []string{
-			strconv.Itoa(p.Id),
-			p.Name,
-			strconv.Itoa(p.Quantity),
-			p.CodeValue,
-			strconv.FormatBool(p.IsPublished),
-			p.Expiration,
-			strconv.FormatFloat(p.Price, 'e', 2, 64),
-		})
+		record, err := formatCSVRecord(p)
+		if err != nil {
+			return fmt.Errorf("[storage.writeCSV] error: %w", err)
+		}
+
+		records = append(records, record)
 	}
 
 	writer := csv.NewWriter(f)
@@ -90,3 +153,96 @@ func writeCSV(path string, data *[]domain.Product) error {
 
 	return nil
 }
+
+// formatCSVRecord encodes a product in the columns of a csv file.
+func formatCSVRecord(p domain.Product) ([]string, error) {
+	var variants string
+	if len(p.Variants) > 0 {
+		b, err := json.Marshal(p.Variants)
+		if err != nil {
+			return nil, err
+		}
+		variants = string(b)
+	}
+
+	var attributes string
+	if len(p.Attributes) > 0 {
+		b, err := json.Marshal(p.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		attributes = string(b)
+	}
+
+	var media string
+	if len(p.Media) > 0 {
+		b, err := json.Marshal(p.Media)
+		if err != nil {
+			return nil, err
+		}
+		media = string(b)
+	}
+
+	return []string{
+		strconv.Itoa(p.Id),
+		p.Name,
+		strconv.Itoa(p.Quantity),
+		p.CodeValue,
+		strconv.FormatBool(p.IsPublished),
+		p.Expiration,
+		strconv.FormatFloat(p.Price, 'f', -1, 64),
+		variants,
+		attributes,
+		media,
+		strconv.Itoa(p.ReorderPoint),
+		strconv.Itoa(p.ReorderQuantity),
+	}, nil
+}
+
+// CSVHeader names the columns of the csv files.
+var CSVHeader = []string{
+	"id",
+	"name",
+	"quantity",
+	"code_value",
+	"is_published",
+	"expiration",
+	"price",
+	"variants",
+	"attributes",
+	"media",
+	"reorder_point",
+	"reorder_quantity",
+}
+
+// CSVEncoder writes products one row at a time, with the columns of the
+// csv files.
+type CSVEncoder struct {
+	w *csv.Writer
+}
+
+func NewCSVEncoder(w io.Writer) *CSVEncoder {
+	return &CSVEncoder{
+		w: csv.NewWriter(w),
+	}
+}
+
+// WriteHeader writes the CSVHeader row, which DecodeCSV skips.
+func (e *CSVEncoder) WriteHeader() error {
+	return e.w.Write(CSVHeader)
+}
+
+func (e *CSVEncoder) Encode(p domain.Product) error {
+	record, err := formatCSVRecord(p)
+	if err != nil {
+		return fmt.Errorf("[storage.CSVEncoder] error: %w", err)
+	}
+
+	return e.w.Write(record)
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (e *CSVEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
 )
 
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
 func readJSON(path string, dest any) error {
 	f, err := os.OpenFile(path, os.O_RDONLY, 0444)
 	if err != nil {
@@ -21,16 +26,76 @@ func readJSON(path string, dest any) error {
 	return nil
 }
 
+// writeJSON writes the data to a temporary file, which then replaces the
+// file, so that failed writes leave it whole. Slices are encoded an element
+// at a time rather than marshalled whole.
 func writeJSON(path string, data any) error {
-	jsonData, err := json.Marshal(data)
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
 	if err != nil {
-		return fmt.Errorf("[storage.WriteJSON] %w", err)
+		return fmt.Errorf("[storage.writeJSON] error: %w", err)
 	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
 
-	err = os.WriteFile(path, jsonData, os.ModeAppend)
+	err = encodeJSON(w, data)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("[storage.writeJSON] error: %w", err)
 	}
 
 	return nil
 }
+
+// encodeJSON writes slices, or pointers to them, as a json array with one
+// element per line. Any other data, byte slices and types with their own
+// encoding are encoded as a single value.
+func encodeJSON(w *bufio.Writer, data any) error {
+	enc := json.NewEncoder(w)
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() ||
+		v.Kind() != reflect.Slice && v.Kind() != reflect.Array ||
+		v.Type().Elem().Kind() == reflect.Uint8 ||
+		reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return enc.Encode(data)
+	}
+
+	_, err := w.WriteString("[\n")
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			_, err = w.WriteString(",")
+			if err != nil {
+				return err
+			}
+		}
+
+		// the encoder ends every element with a newline
+		err = enc.Encode(v.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.WriteString("]\n")
+	return err
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+func TestWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "products.json")
+
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0644))
+	require.NoError(t, WriteFile(path, &scanned))
+
+	var ps []domain.Product
+	require.NoError(t, ReadFile(path, &ps))
+	assert.Equal(t, scanned, ps)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	// empty slices and other values are written whole
+	require.NoError(t, WriteFile(path, &[]domain.Product{}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[]`, string(data))
+
+	require.NoError(t, WriteFile(path, map[string]int{"a": 1}))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":1}`, string(data))
+
+	require.NoError(t, WriteFile(path, []byte("hi")))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"aGk="`, string(data))
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteJSONFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "products.json")
+
+	require.NoError(t, WriteFile(path, &scanned))
+
+	// an element that cannot be encoded leaves the file whole
+	err := WriteFile(path, []any{1, make(chan int)})
+	assert.ErrorIs(t, err, ErrWriteFile)
+
+	var ps []domain.Product
+	require.NoError(t, ReadFile(path, &ps))
+	assert.Equal(t, scanned, ps)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+func readNDJSON(path string, dest *[]domain.Product) error {
+	s, err := ScanProducts(path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for s.Scan() {
+		*dest = append(*dest, s.Product())
+	}
+
+	return s.Err()
+}
+
+// writeNDJSON writes the products a line at a time to a temporary file,
+// which then replaces the file, so that failed writes leave it whole.
+func writeNDJSON(path string, data *[]domain.Product) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("[storage.writeNDJSON] error: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	enc := NewNDJSONEncoder(f)
+	for _, p := range *data {
+		err = enc.Encode(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = enc.Flush()
+	if err == nil {
+		err = f.Chmod(0644)
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("[storage.writeNDJSON] error: %w", err)
+	}
+
+	return nil
+}
+
+// NDJSONEncoder writes products as json, one per line.
+type NDJSONEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewNDJSONEncoder(w io.Writer) *NDJSONEncoder {
+	bw := bufio.NewWriter(w)
+
+	return &NDJSONEncoder{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+func (e *NDJSONEncoder) Encode(p domain.Product) error {
+	err := e.enc.Encode(p)
+	if err != nil {
+		return fmt.Errorf("[storage.NDJSONEncoder] error: %w", err)
+	}
+
+	return nil
+}
+
+// Flush writes the buffered lines to the underlying writer.
+func (e *NDJSONEncoder) Flush() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+// ProductScanner reads the products of a file one at a time, like a
+// bufio.Scanner, so that large catalogs are never held in a single buffer.
+// Json files are read element by element, ndjson and csv files line by
+// line.
+type ProductScanner struct {
+	f    *os.File
+	next func() (domain.Product, error)
+	line int
+
+	product domain.Product
+	err     error
+}
+
+// ScanProducts opens the json, ndjson or csv file at path.
+func ScanProducts(path string) (*ProductScanner, error) {
+	ext := filepath.Ext(path)
+
+	flag := os.O_RDONLY
+	if ext == ".csv" {
+		// like ReadFile, missing csv files are created
+		flag |= os.O_CREATE
+	}
+
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: [storage.ScanProducts] %s", ErrReadFile, err.Error())
+	}
+
+	s := &ProductScanner{
+		f: f,
+	}
+
+	r := bufio.NewReader(f)
+
+	switch ext {
+	case ".json":
+		s.next = s.jsonNext(json.NewDecoder(r))
+	case ".ndjson":
+		s.next = s.ndjsonNext(r)
+	case ".csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		s.next = s.csvNext(cr)
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%w: [storage.ScanProducts] file format %s not supported", ErrReadFile, ext)
+	}
+
+	return s, nil
+}
+
+// Scan reads the next product, and reports false at the end of the file or
+// on the first error.
+func (s *ProductScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	p, err := s.next()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.err = fmt.Errorf("%w: [storage.ProductScanner] %s: line %d: %s", ErrReadFile, s.f.Name(), s.line, err.Error())
+		} else {
+			s.err = io.EOF
+		}
+		return false
+	}
+
+	s.product = p
+
+	return true
+}
+
+// Product returns the product read by the last call to Scan.
+func (s *ProductScanner) Product() domain.Product {
+	return s.product
+}
+
+// Err returns the error that stopped the scan, if not the end of the file.
+func (s *ProductScanner) Err() error {
+	if errors.Is(s.err, io.EOF) {
+		return nil
+	}
+
+	return s.err
+}
+
+func (s *ProductScanner) Close() error {
+	return s.f.Close()
+}
+
+// jsonNext reads the elements of a json array. Lines are not tracked, the
+// position in the array is reported instead.
+func (s *ProductScanner) jsonNext(dec *json.Decoder) func() (domain.Product, error) {
+	started := false
+
+	return func() (domain.Product, error) {
+		if !started {
+			started = true
+
+			tok, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				return domain.Product{}, io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return domain.Product{}, err
+			}
+
+			// null holds no product
+			if tok == nil {
+				return domain.Product{}, io.EOF
+			}
+
+			if tok != json.Delim('[') {
+				return domain.Product{}, errors.New("expected a json array")
+			}
+		}
+
+		if !dec.More() {
+			return domain.Product{}, io.EOF
+		}
+
+		s.line++
+
+		var p domain.Product
+		err := dec.Decode(&p)
+
+		return p, err
+	}
+}
+
+// ndjsonNext reads a product per line, skipping blank lines.
+func (s *ProductScanner) ndjsonNext(r *bufio.Reader) func() (domain.Product, error) {
+	return func() (domain.Product, error) {
+		for {
+			line, err := r.ReadBytes('\n')
+			if len(line) == 0 && err != nil {
+				return domain.Product{}, err
+			}
+			if err != nil && !errors.Is(err, io.EOF) {
+				return domain.Product{}, err
+			}
+
+			s.line++
+
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+
+			var p domain.Product
+			err = json.Unmarshal(line, &p)
+
+			return p, err
+		}
+	}
+}
+
+// csvNext reads a product per record, skipping a header row like
+// DecodeCSV.
+func (s *ProductScanner) csvNext(r *csv.Reader) func() (domain.Product, error) {
+	return func() (domain.Product, error) {
+		for {
+			record, err := r.Read()
+			if err != nil {
+				return domain.Product{}, err
+			}
+
+			line, _ := r.FieldPos(0)
+			first := s.line == 0
+			s.line = line
+
+			if first && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "id") {
+				continue
+			}
+
+			return parseCSVRecord(record)
+		}
+	}
+}
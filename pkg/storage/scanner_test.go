@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+var scanned = []domain.Product{
+	{Id: 1, Name: "First", Quantity: 5, CodeValue: "FIRST", Expiration: "20/01/2030", Price: 10.25},
+	{Id: 2, Name: "Second", Quantity: 1, CodeValue: "SECOND", Expiration: "20/01/2030", Price: 20, Attributes: map[string]any{"color": "red"}},
+}
+
+func scan(t *testing.T, path string) ([]domain.Product, error) {
+	t.Helper()
+
+	s, err := ScanProducts(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	var ps []domain.Product
+	for s.Scan() {
+		ps = append(ps, s.Product())
+	}
+
+	return ps, s.Err()
+}
+
+func TestScanProducts(t *testing.T) {
+	dir := t.TempDir()
+
+	// every format written by WriteFile is scanned back
+	for _, ext := range []string{".json", ".ndjson", ".csv"} {
+		path := filepath.Join(dir, "products"+ext)
+		require.NoError(t, WriteFile(path, &scanned), ext)
+
+		ps, err := scan(t, path)
+		assert.NoError(t, err, ext)
+		assert.Equal(t, scanned, ps, ext)
+
+		var read []domain.Product
+		require.NoError(t, ReadFile(path, &read), ext)
+		assert.Equal(t, scanned, read, ext)
+	}
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	// blank lines are skipped, and errors tell their line
+	ps, err := scan(t, write("lines.ndjson", `{"id": 1}`+"\n\n"+`{"id": 2}`+"\n"+`{"id": "three"}`+"\n"+`{"id": 4}`))
+	assert.ErrorIs(t, err, ErrReadFile)
+	assert.ErrorContains(t, err, "line 4")
+	assert.Len(t, ps, 2)
+
+	ps, err = scan(t, write("null.json", "null"))
+	assert.NoError(t, err)
+	assert.Empty(t, ps)
+
+	_, err = scan(t, write("object.json", `{"id": 1}`))
+	assert.ErrorContains(t, err, "expected a json array")
+
+	_, err = scan(t, write("empty.json", ""))
+	assert.ErrorIs(t, err, ErrReadFile)
+
+	// exported csv files start with a header
+	ps, err = scan(t, write("header.csv", "id,name,quantity,code_value,is_published,expiration,price\n3,Third,1,THIRD,true,20/01/2030,1\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, ps, 1) {
+		assert.Equal(t, "THIRD", ps[0].CodeValue)
+	}
+
+	_, err = ScanProducts(write("products.txt", ""))
+	assert.ErrorIs(t, err, ErrReadFile)
+}
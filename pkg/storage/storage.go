@@ -36,6 +36,14 @@ func ReadFile(path string, dest any) error {
 		}
 
 		return nil
+	case ext == ".ndjson":
+		products, ok := dest.(*[]domain.Product)
+		if !ok {
+			return fmt.Errorf("%w: [storage.ReadFile] invalid ndjson receiver type", ErrReadFile)
+		}
+
+		// the scanner errors are already wrapped
+		return readNDJSON(path, products)
 	default:
 		return fmt.Errorf("%w: [storage.ReadFile] file format %s not supported", ErrReadFile, ext)
 	}
@@ -63,6 +71,17 @@ func WriteFile(path string, data any) error {
 			return fmt.Errorf("%w: %s", ErrWriteFile, err.Error())
 		}
 		return nil
+	case ext == ".ndjson":
+		products, ok := data.(*[]domain.Product)
+		if !ok {
+			return fmt.Errorf("%w: [storage.WriteFile] invalid ndjson data type", ErrWriteFile)
+		}
+
+		err := writeNDJSON(path, products)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrWriteFile, err.Error())
+		}
+		return nil
 	default:
 		return fmt.Errorf("[storage.WriteFile] file format %s not supported", ext)
 	}
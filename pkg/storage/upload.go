@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gituhb.com/juajosserand/goweb/internal/domain"
+)
+
+var ErrInvalidUpload = errors.New("unable to decode upload")
+
+// Record is a product decoded from an upload, or the error decoding it.
+// Line is the line of csv uploads, and the position in the array of json
+// ones, from 1.
+type Record struct {
+	Line    int
+	Product domain.Product
+	Err     error
+}
+
+// DecodeCSV decodes the products of a csv upload, with the columns of the
+// csv files. A header row, starting with an id column, is skipped. Lines
+// failing to decode are returned with their error.
+func DecodeCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	var records []Record
+
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, Record{Line: parseErr.Line, Err: parseErr.Err})
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: [storage.DecodeCSV] %s", ErrInvalidUpload, err.Error())
+		}
+
+		if first && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "id") {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		p, err := parseCSVRecord(record)
+		records = append(records, Record{Line: line, Product: p, Err: err})
+	}
+}
+
+// DecodeJSON decodes the products of a json upload, an array of products
+// like the json files. Items failing to decode are returned with their
+// error.
+func DecodeJSON(r io.Reader) ([]Record, error) {
+	var items []json.RawMessage
+
+	err := json.NewDecoder(r).Decode(&items)
+	if err != nil {
+		return nil, fmt.Errorf("%w: [storage.DecodeJSON] %s", ErrInvalidUpload, err.Error())
+	}
+
+	records := make([]Record, len(items))
+	for i, item := range items {
+		records[i].Line = i + 1
+		records[i].Err = json.Unmarshal(item, &records[i].Product)
+	}
+
+	return records, nil
+}
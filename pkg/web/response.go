@@ -23,3 +23,26 @@ func ErrResponse(status int, code string, message string) errResponse {
 		Message: message,
 	}
 }
+
+// FieldError describes an invalid field of a request or response.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type validationErrResponse struct {
+	Status  int          `json:"status"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func ValidationErrResponse(status int, code string, message string, errs []FieldError) validationErrResponse {
+	return validationErrResponse{
+		Status:  status,
+		Code:    code,
+		Message: message,
+		Errors:  errs,
+	}
+}